@@ -68,7 +68,17 @@ func New() *cobra.Command {
 	cmd.Flags().Duration("exec_timeout", 24*time.Hour, "set the task exec command expire time")
 	cmd.Flags().Int("pool_size", runtime.NumCPU()*2, "set the size of the execution work pool.")
 	cmd.Flags().String("mq_url", "inmemory://localhost", "message queue url. [inmemory,amqp]")
-	cmd.Flags().String("redis_url", "", "redis url.")
+	cmd.Flags().String("redis_url", "", "redis url, shares upload and pipeline volume locks between nodes.")
+	cmd.Flags().String("cgroup_parent", utils.ServiceName, "cgroup v2 parent of step cgroups, relative to /sys/fs/cgroup")
+	cmd.Flags().StringSlice("run_as_users", nil, "users that steps are allowed to run as, name or uid, '*' allows any")
+	cmd.Flags().StringSlice("file_allow", nil, "absolute paths file steps may access outside the task workspace, wildcard supported")
+	cmd.Flags().StringSlice("attach_tokens", nil, "name:token pairs allowed to attach to the terminal of running exec steps, empty disables attach")
+	cmd.Flags().String("secret_key", "", "key to encrypt secrets at rest, generated into <root_dir>/secret.key when empty, nodes sharing a database need the same key")
+	cmd.Flags().String("interpreters", "", "interpreter definitions file(yaml or json) extending or overriding the builtin exec types")
+	cmd.Flags().StringSlice("yaegi_allow", []string{"*"}, "packages yaegi steps may import, wildcard supported")
+	cmd.Flags().StringSlice("yaegi_deny", nil, "packages or symbols(path.Name) yaegi steps must not use, wildcard supported")
+	cmd.Flags().Int("yaegi_max_goroutines", 0, "max goroutines of a yaegi step, 0 is unlimited")
+	cmd.Flags().String("yaegi_max_output", "", "max output size of a yaegi step, e.g. 10MiB, empty is unlimited")
 	cmd.Flags().String("self_url", "https://oss.yfdou.com/tools/AutoExecFlow", "self Update URL")
 	_ = viper.BindPFlags(cmd.Flags())
 
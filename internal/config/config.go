@@ -1,10 +1,16 @@
 package config
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,6 +21,7 @@ import (
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/pkg/wildcard"
 )
 
 var App = &SConfig{
@@ -37,6 +44,17 @@ type SConfig struct {
 	DataCenterID  int64         `mapstructure:"DATA_CENTER_ID"`
 	NodeName      string        `mapstructure:"NODE_NAME"`
 	NodeID        int64         `mapstructure:"NODE_ID"`
+	CgroupParent  string        `mapstructure:"CGROUP_PARENT"`
+	RunAsUsers    []string      `mapstructure:"RUN_AS_USERS"`
+	FileAllow     []string      `mapstructure:"FILE_ALLOW"`
+	AttachTokens  []string      `mapstructure:"ATTACH_TOKENS"`
+	Interpreters  string        `mapstructure:"INTERPRETERS"`
+	SecretKey     string        `mapstructure:"SECRET_KEY"`
+
+	YaegiAllow         []string `mapstructure:"YAEGI_ALLOW"`
+	YaegiDeny          []string `mapstructure:"YAEGI_DENY"`
+	YaegiMaxGoroutines int      `mapstructure:"YAEGI_MAX_GOROUTINES"`
+	YaegiMaxOutput     string   `mapstructure:"YAEGI_MAX_OUTPUT"`
 }
 
 func Init() error {
@@ -88,6 +106,9 @@ func (c *SConfig) init() error {
 		"script":    c.ScriptDir(),
 		"log":       c.LogDir(),
 		"workspace": c.WorkSpace(),
+		"volume":    c.VolumeDir(),
+		"cache":     c.CacheDir(),
+		"plugin":    c.PluginDir(),
 	}
 	for name, dir := range dirs {
 		if name == "log" && c.LogOutput != "file" {
@@ -98,6 +119,32 @@ func (c *SConfig) init() error {
 		}
 		logx.Infof("%s dir: %s", name, dir)
 	}
+	return c.initSecretKey()
+}
+
+// initSecretKey 未指定密钥时使用根目录下的secret.key, 不存在则随机生成, 多节点共享数据库时需指定相同的密钥
+func (c *SConfig) initSecretKey() error {
+	if c.SecretKey != "" {
+		return nil
+	}
+	file := filepath.Join(c.RootDir, "secret.key")
+	content, err := os.ReadFile(file)
+	if err == nil && len(bytes.TrimSpace(content)) > 0 {
+		c.SecretKey = string(bytes.TrimSpace(content))
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read secret key: %v", err)
+	}
+	var key = make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return err
+	}
+	c.SecretKey = hex.EncodeToString(key)
+	if err = os.WriteFile(file, []byte(c.SecretKey), 0600); err != nil {
+		return fmt.Errorf("failed to write secret key: %v", err)
+	}
+	logx.Infof("%s file: %s", "secret key", file)
 	return nil
 }
 
@@ -112,3 +159,66 @@ func (c *SConfig) LogDir() string {
 func (c *SConfig) WorkSpace() string {
 	return filepath.Join(c.RootDir, "workspace")
 }
+
+func (c *SConfig) VolumeDir() string {
+	return filepath.Join(c.RootDir, "volumes")
+}
+
+func (c *SConfig) CacheDir() string {
+	return filepath.Join(c.RootDir, "cache")
+}
+
+// PluginDir 插件目录, 其中的可执行文件作为步骤插件启动
+func (c *SConfig) PluginDir() string {
+	return filepath.Join(c.RootDir, "plugins")
+}
+
+// RunAsAllowed 步骤是否允许以指定用户运行, 支持用户名或uid, 白名单为空时不允许
+func (c *SConfig) RunAsAllowed(name string) bool {
+	if slices.Contains(c.RunAsUsers, "*") || slices.Contains(c.RunAsUsers, name) {
+		return true
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		u, err = user.LookupId(name)
+		if err != nil {
+			return false
+		}
+	}
+	return slices.Contains(c.RunAsUsers, u.Username) || slices.Contains(c.RunAsUsers, u.Uid)
+}
+
+// FileAllowed 文件类步骤是否允许访问工作目录外的绝对路径, 允许列表中的目录包含其子路径, 支持通配符*
+func (c *SConfig) FileAllowed(path string) bool {
+	path = filepath.Clean(path)
+	for _, allow := range c.FileAllow {
+		if allow == "" {
+			continue
+		}
+		if wildcard.Match(allow, path) {
+			return true
+		}
+		allow = filepath.Clean(allow)
+		if path == allow || strings.HasPrefix(path, strings.TrimSuffix(allow, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachUser 校验附加步骤终端的令牌, 令牌格式为 用户名:令牌, 返回对应的用户名, 未配置时不允许附加
+func (c *SConfig) AttachUser(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	for _, item := range c.AttachTokens {
+		name, secret, found := strings.Cut(item, ":")
+		if !found || secret == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
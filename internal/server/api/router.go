@@ -10,10 +10,14 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/middleware/zap"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/event"
+	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/interpreter"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/pipeline"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/pipeline/build"
+	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/pipeline/volume"
+	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/plugin"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/pool"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/pty"
+	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/secret"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/task"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/task/step"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/v1/task/workspace"
@@ -81,6 +85,18 @@ func New(relativePath string) (*gin.Engine, error) {
 		apiV1.POST("/pipeline/:pipeline/build/:build", build.ReRun)
 		apiV1.DELETE("/pipeline/:pipeline/build/:build", build.Delete)
 
+		// volume
+		apiV1.GET("/pipeline/:pipeline/volume", volume.List)
+		apiV1.DELETE("/pipeline/:pipeline/volume", volume.Purge)
+		apiV1.DELETE("/pipeline/:pipeline/volume/:volume", volume.Delete)
+
+		// secret
+		apiV1.GET("/secret", secret.List)
+		apiV1.POST("/secret", secret.Post)
+		apiV1.GET("/secret/:secret", secret.Detail)
+		apiV1.POST("/secret/:secret", secret.Update)
+		apiV1.DELETE("/secret/:secret", secret.Delete)
+
 		// task
 		apiV1.GET("/task", task.List)
 		apiV1.POST("/task", task.Post)
@@ -99,11 +115,18 @@ func New(relativePath string) (*gin.Engine, error) {
 		apiV1.GET("/task/:task/step/:step", step.Detail)
 		apiV1.PUT("/task/:task/step/:step", step.Manager)
 		apiV1.GET("/task/:task/step/:step/log", step.Log)
+		apiV1.GET("/task/:task/step/:step/attach", step.Attach)
 
 		// worker pool
 		apiV1.GET("/pool", pool.Detail)
 		apiV1.POST("/pool", pool.Post)
 
+		// interpreter
+		apiV1.GET("/interpreter", interpreter.List)
+
+		// plugin
+		apiV1.GET("/plugin", plugin.List)
+
 		// pty
 		apiV1.GET("/pty", pty.Websocket)
 	}
@@ -0,0 +1,21 @@
+package interpreter
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+)
+
+// List
+// @Summary		列表
+// @Description	获取当前节点支持的脚本类型, 包含命令行, 版本及是否可用
+// @Tags		解释器
+// @Accept		application/json
+// @Produce		application/json
+// @Success		200 {object} types.SBase[types.SInterpreterListRes]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/interpreter [get]
+func List(c *gin.Context) {
+	base.Send(c, base.WithData(service.Interpreter().List()))
+}
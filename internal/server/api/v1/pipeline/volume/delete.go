@@ -0,0 +1,65 @@
+package volume
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Purge
+// @Summary		清理
+// @Description	清空流水线所有持久化卷, 正在被构建使用的卷会返回错误
+// @Tags		持久化卷
+// @Accept		application/json
+// @Produce		application/json
+// @Param		pipeline path string true "流水线名称"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/pipeline/{pipeline}/volume [delete]
+func Purge(c *gin.Context) {
+	pipelineName := c.Param("pipeline")
+	if pipelineName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("pipeline does not exist")))
+		return
+	}
+	if err := service.Pipeline(pipelineName).VolumePurge(""); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithCode[any](types.CodeSuccess))
+}
+
+// Delete
+// @Summary		清理
+// @Description	清空流水线指定持久化卷, 正在被构建使用的卷会返回错误
+// @Tags		持久化卷
+// @Accept		application/json
+// @Produce		application/json
+// @Param		pipeline path string true "流水线名称"
+// @Param		volume path string true "卷名称"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/pipeline/{pipeline}/volume/{volume} [delete]
+func Delete(c *gin.Context) {
+	pipelineName := c.Param("pipeline")
+	if pipelineName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("pipeline does not exist")))
+		return
+	}
+	volumeName := c.Param("volume")
+	if volumeName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("volume does not exist")))
+		return
+	}
+	if err := service.Pipeline(pipelineName).VolumePurge(volumeName); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithCode[any](types.CodeSuccess))
+}
@@ -0,0 +1,36 @@
+package volume
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// List
+// @Summary		列表
+// @Description	获取流水线持久化卷列表及占用情况
+// @Tags		持久化卷
+// @Accept		application/json
+// @Produce		application/json
+// @Param		pipeline path string true "流水线名称"
+// @Success		200 {object} types.SBase[types.SPipelineVolumesRes]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/pipeline/{pipeline}/volume [get]
+func List(c *gin.Context) {
+	pipelineName := c.Param("pipeline")
+	if pipelineName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("pipeline does not exist")))
+		return
+	}
+	res, err := service.Pipeline(pipelineName).VolumeList()
+	if err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithData(res))
+}
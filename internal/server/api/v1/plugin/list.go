@@ -0,0 +1,21 @@
+package plugin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+)
+
+// List
+// @Summary		列表
+// @Description	获取当前节点的步骤插件, 包含处理的步骤类型及运行状态
+// @Tags		插件
+// @Accept		application/json
+// @Produce		application/json
+// @Success		200 {object} types.SBase[types.SPluginListRes]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/plugin [get]
+func List(c *gin.Context) {
+	base.Send(c, base.WithData(service.Plugin().List()))
+}
@@ -0,0 +1,35 @@
+package secret
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Delete
+// @Summary 	删除
+// @Description 删除指定密钥, 被未结束的任务引用时拒绝删除
+// @Tags 		密钥
+// @Accept		application/json
+// @Produce		application/json
+// @Param		secret path string true "密钥名称"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/secret/{secret} [delete]
+func Delete(c *gin.Context) {
+	secretName := c.Param("secret")
+	if secretName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("secret does not exist")))
+		return
+	}
+	if err := service.Secret(secretName).Delete(); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithCode[any](types.CodeSuccess))
+}
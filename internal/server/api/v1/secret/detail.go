@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Detail
+// @Summary 	详情
+// @Description 获取指定密钥信息, 不包含值
+// @Tags 		密钥
+// @Accept		application/json
+// @Produce		application/json
+// @Param		secret path string true "密钥名称"
+// @Success		200 {object} types.SBase[types.SSecretRes]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/secret/{secret} [get]
+func Detail(c *gin.Context) {
+	secretName := c.Param("secret")
+	if secretName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("secret does not exist")))
+		return
+	}
+	res, err := service.Secret(secretName).Detail()
+	if err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithData(res).WithCode(types.CodeSuccess))
+}
@@ -0,0 +1,33 @@
+package secret
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// List
+// @Summary		列表
+// @Description	获取所有密钥列表, 不包含值
+// @Tags		密钥
+// @Accept		application/json
+// @Produce		application/json
+// @Param		page query int false "页码" default(1)
+// @Param		size query int false "分页大小" default(100)
+// @Param		prefix query string false "名称前缀"
+// @Success		200 {object} types.SBase[types.SSecretListRes]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/secret [get]
+func List(c *gin.Context) {
+	var req = &types.SPageReq{
+		Page: 1,
+		Size: 15,
+	}
+	if err := c.ShouldBindQuery(req); err != nil {
+		base.Send(c, base.WithError[any](err))
+		return
+	}
+	base.Send(c, base.WithData(service.SecretList(req)))
+}
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Post
+// @Summary 	创建
+// @Description 创建密钥, 值加密后保存
+// @Tags 		密钥
+// @Accept		application/json
+// @Produce		application/json
+// @Param		content body types.SSecretCreateReq true "密钥内容"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/secret [post]
+func Post(c *gin.Context) {
+	var req = new(types.SSecretCreateReq)
+	if err := c.ShouldBind(req); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+
+	if err := service.Secret(req.Name).Create(req); err != nil {
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+
+	base.Send(c, base.WithCode[any](types.CodeSuccess))
+}
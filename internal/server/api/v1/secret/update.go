@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Update
+// @Summary 	更新
+// @Description 更新指定密钥, 值为空时不修改
+// @Tags 		密钥
+// @Accept		application/json
+// @Produce		application/json
+// @Param		secret path string true "密钥名称"
+// @Param		content body types.SSecretUpdateReq true "更新内容"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/secret/{secret} [post]
+func Update(c *gin.Context) {
+	secretName := c.Param("secret")
+	if secretName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("secret does not exist")))
+		return
+	}
+	var req = new(types.SSecretUpdateReq)
+	if err := c.ShouldBind(req); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	if err := service.Secret(secretName).Update(req); err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(err))
+		return
+	}
+	base.Send(c, base.WithCode[any](types.CodeSuccess))
+}
@@ -0,0 +1,84 @@
+package step
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
+	"github.com/xmapst/AutoExecFlow/internal/service"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+// Attach
+// @Summary		附加终端
+// @Description	附加到运行中exec步骤的终端, 接收实时输出并转发输入, 需要--attach_tokens中配置的令牌, 须连接步骤所在节点
+// @Description	令牌通过Authorization: Bearer <token>传递, 未携带时连接后的首条消息须为令牌
+// @Tags		步骤
+// @Accept		application/json
+// @Produce		application/json
+// @Param		task path string true "任务名称"
+// @Param		step path string true "步骤名称"
+// @Param		Authorization header string false "Bearer <token>"
+// @Success		200 {object} types.SBase[any]
+// @Failure		500 {object} types.SBase[any]
+// @Router		/api/v1/task/{task}/step/{step}/attach [get]
+func Attach(c *gin.Context) {
+	taskName := c.Param("task")
+	stepName := c.Param("step")
+	if taskName == "" || stepName == "" {
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("task or step does not exist")))
+		return
+	}
+	var user string
+	auth := c.GetHeader("Authorization")
+	if auth != "" {
+		var ok bool
+		if user, ok = config.App.AttachUser(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))); !ok {
+			base.Send(c, base.WithCode[any](types.CodeFailed).WithError(errors.New("permission denied")))
+			return
+		}
+	}
+	if !c.IsWebsocket() {
+		base.Send(c, base.WithCode[any](types.CodeFailed).WithError(errors.New("websocket is required")))
+		return
+	}
+	ws, err := base.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		logx.Errorln(err)
+		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(err))
+		return
+	}
+	if auth == "" {
+		// 浏览器无法设置请求头, 首条消息为令牌
+		var ok bool
+		if user, ok = attachUser(ws); !ok {
+			base.CloseWs(ws, "permission denied")
+			return
+		}
+	}
+	user = user + "@" + c.ClientIP()
+	if err = service.Step(taskName, stepName).Attach(ws, user); err != nil {
+		base.CloseWs(ws, err.Error())
+		return
+	}
+	base.CloseWs(ws, "detached")
+}
+
+// attachUser 读取首条消息作为令牌, 10秒内未收到视为失败
+func attachUser(ws *websocket.Conn) (string, bool) {
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer func() {
+		_ = ws.SetReadDeadline(time.Time{})
+	}()
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return "", false
+	}
+	return config.App.AttachUser(strings.TrimSpace(string(data)))
+}
@@ -21,6 +21,9 @@ import (
 // @Produce		application/json
 // @Param		task path string true "任务名称"
 // @Param		step path string true "步骤名称"
+// @Param		stream query string false "按输出流过滤, 逗号分隔" Enums(stdout,stderr,output)
+// @Param		level query string false "按级别过滤, 逗号分隔, trace,debug,info,warn,error,fatal"
+// @Param		color query string false "颜色渲染, ansi返回原始转义序列, html转换为span" Enums(ansi,html)
 // @Success		200 {object} types.SBase[types.SStepLogsRes]
 // @Failure		500 {object} types.SBase[any]
 // @Router		/api/v1/task/{task}/step/{step}/log [get]
@@ -31,6 +34,11 @@ func Log(c *gin.Context) {
 		base.Send(c, base.WithCode[any](types.CodeNoData).WithError(errors.New("task or step does not exist")))
 		return
 	}
+	var req = &types.SStepLogReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		base.Send(c, base.WithError[any](err))
+		return
+	}
 
 	var ws *websocket.Conn
 	if c.IsWebsocket() {
@@ -54,7 +62,7 @@ func Log(c *gin.Context) {
 			}
 		}()
 		defer base.CloseWs(ws, "Server is shutting down")
-		err := service.Step(taskName, stepName).LogStream(ctx, ws)
+		err := service.Step(taskName, stepName).LogStream(ctx, ws, req)
 		if err != nil {
 			_ = ws.WriteJSON(base.WithCode[any](types.CodeFailed).WithError(err))
 		}
@@ -62,6 +70,6 @@ func Log(c *gin.Context) {
 		return
 	}
 
-	code, res, err := service.Step(taskName, stepName).Log()
+	code, res, err := service.Step(taskName, stepName).Log(req)
 	base.Send(c, base.WithData(res).WithCode(code).WithError(err))
 }
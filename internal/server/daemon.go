@@ -26,6 +26,9 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/utils"
 	"github.com/xmapst/AutoExecFlow/internal/worker"
+	"github.com/xmapst/AutoExecFlow/internal/worker/interpreter"
+	"github.com/xmapst/AutoExecFlow/internal/worker/plugin"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 	"github.com/xmapst/AutoExecFlow/pkg/listeners"
 )
 
@@ -73,11 +76,18 @@ func (p *sProgram) init() error {
 		config.App.DataCenterID,
 		config.App.NodeID,
 		config.App.DBUrl,
+		config.App.SecretKey,
 	); err != nil {
 		logx.Errorln(err)
 		return err
 	}
 
+	// 配置redis时流水线卷锁在节点间共享
+	if err := volume.Init(config.App.RedisUrl); err != nil {
+		logx.Errorln(err)
+		return err
+	}
+
 	// 修正当前节点重启前的数据
 	return storage.FixDatabase(config.App.NodeName)
 }
@@ -106,6 +116,16 @@ func (p *sProgram) startWorker() error {
 	// clear old workspace
 	utils.ClearDir(config.App.WorkSpace())
 
+	// 加载解释器定义
+	if err := interpreter.Load(config.App.Interpreters); err != nil {
+		return err
+	}
+
+	// 启动步骤插件
+	if err := plugin.Start(p.ctx, config.App.PluginDir()); err != nil {
+		return err
+	}
+
 	// 启动任务执行器
 	return worker.Start(p.ctx)
 }
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+	"github.com/xmapst/AutoExecFlow/internal/worker/interpreter"
+)
+
+type SInterpreterService struct {
+}
+
+func Interpreter() *SInterpreterService {
+	return &SInterpreterService{}
+}
+
+// List 当前节点支持的脚本类型及可用性
+func (i *SInterpreterService) List() *types.SInterpreterListRes {
+	var res = &types.SInterpreterListRes{
+		Node: config.App.NodeName,
+	}
+	for _, v := range interpreter.List() {
+		res.Interpreters = append(res.Interpreters, &types.SInterpreterRes{
+			Type:      v.Type,
+			Aliases:   v.Aliases,
+			Command:   v.Command,
+			Suffix:    v.Suffix,
+			Encoding:  v.Encoding,
+			Version:   v.Version,
+			Available: v.Available,
+			Error:     v.Error,
+		})
+	}
+	return res
+}
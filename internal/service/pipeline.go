@@ -3,15 +3,20 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin/binding"
 	"github.com/pkg/errors"
 	"github.com/segmentio/ksuid"
 	"github.com/xmapst/logx"
+	"go.uber.org/multierr"
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/storage/models"
 	"github.com/xmapst/AutoExecFlow/internal/types"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 	"github.com/xmapst/AutoExecFlow/pkg/jinja"
 )
 
@@ -52,6 +57,9 @@ func PipelineList(req *types.SPageReq) *types.SPipelineListRes {
 }
 
 func (p *SPipelineService) Delete() error {
+	if err := p.VolumePurge(""); err != nil {
+		return err
+	}
 	return storage.Pipeline(p.name).ClearAll()
 }
 
@@ -61,17 +69,28 @@ func (p *SPipelineService) Detail() (*types.SPipelineRes, error) {
 		logx.Errorln("detail pipeline", p.name, err)
 		return nil, errors.New("pipeline not found")
 	}
-	return &types.SPipelineRes{
+	data := &types.SPipelineRes{
 		Name:    res.Name,
 		Desc:    res.Desc,
 		Disable: *res.Disable,
 		TplType: res.TplType,
 		Content: res.Content,
-	}, nil
+	}
+	for _, v := range storage.Pipeline(p.name).Volume().List() {
+		data.Volumes = append(data.Volumes, &types.SPipelineVolume{
+			Name: v.Name,
+			Path: v.Path,
+		})
+	}
+	return data, nil
 }
 
 func (p *SPipelineService) Create(req *types.SPipelineCreateReq) error {
-	return storage.PipelineCreate(&models.SPipeline{
+	volumes, err := p.reviewVolumes(req.Volumes)
+	if err != nil {
+		return err
+	}
+	err = storage.PipelineCreate(&models.SPipeline{
 		Name: p.name,
 		SPipelineUpdate: models.SPipelineUpdate{
 			Desc:    req.Desc,
@@ -80,15 +99,113 @@ func (p *SPipelineService) Create(req *types.SPipelineCreateReq) error {
 			Content: req.Content,
 		},
 	})
+	if err != nil {
+		return err
+	}
+	return storage.Pipeline(p.name).Volume().Insert(volumes...)
 }
 
 func (p *SPipelineService) Update(req *types.SPipelineUpdateReq) error {
-	return storage.Pipeline(p.name).Update(&models.SPipelineUpdate{
+	volumes, err := p.reviewVolumes(req.Volumes)
+	if err != nil {
+		return err
+	}
+	err = storage.Pipeline(p.name).Update(&models.SPipelineUpdate{
 		Desc:    req.Desc,
 		Disable: req.Disable,
 		TplType: req.TplType,
 		Content: req.Content,
 	})
+	if err != nil {
+		return err
+	}
+	// 卷定义整体替换, 已有卷数据保留在磁盘上, 需要时通过清理接口删除
+	return storage.Pipeline(p.name).Volume().Replace(volumes...)
+}
+
+func (p *SPipelineService) reviewVolumes(req types.SPipelineVolumes) (models.SPipelineVolumes, error) {
+	var names []string
+	var volumes models.SPipelineVolumes
+	for _, v := range req {
+		name := reg.ReplaceAllString(v.Name, "")
+		if name == "" || name != v.Name {
+			return nil, fmt.Errorf("invalid volume name %q", v.Name)
+		}
+		path := utils.PathEscape(v.Path)
+		if path == "" || path == "." {
+			return nil, fmt.Errorf("invalid volume %s path %q", v.Name, v.Path)
+		}
+		names = append(names, name)
+		volumes = append(volumes, &models.SPipelineVolume{
+			Name: name,
+			Path: filepath.ToSlash(path),
+		})
+	}
+	if dup := utils.CheckDuplicate(names); dup != nil {
+		return nil, fmt.Errorf("duplicate volumes %v", dup)
+	}
+	// 卷以符号链接挂载, 路径相同或嵌套时会在另一个卷内创建链接
+	for i, a := range volumes {
+		for _, b := range volumes[i+1:] {
+			if overlaps(a.Path, b.Path) {
+				return nil, fmt.Errorf("volume %s path %s overlaps volume %s path %s", a.Name, a.Path, b.Name, b.Path)
+			}
+		}
+	}
+	return volumes, nil
+}
+
+// overlaps 路径相同或一方是另一方的上级目录
+func overlaps(a, b string) bool {
+	a, b = strings.Trim(a, "/"), strings.Trim(b, "/")
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func (p *SPipelineService) VolumeList() (types.SPipelineVolumesRes, error) {
+	if _, err := storage.Pipeline(p.name).Get(); err != nil {
+		logx.Errorln("pipeline volume list", p.name, err)
+		return nil, errors.New("pipeline not found")
+	}
+	var res types.SPipelineVolumesRes
+	for _, v := range storage.Pipeline(p.name).Volume().List() {
+		size, modTime, err := volume.Usage(p.name, v.Name)
+		if err != nil {
+			logx.Warnln("pipeline volume list", p.name, v.Name, err)
+		}
+		res = append(res, &types.SPipelineVolumeRes{
+			Name:    v.Name,
+			Path:    v.Path,
+			Size:    size,
+			ModTime: modTime,
+			Locked:  volume.IsLocked(p.name, v.Name),
+		})
+	}
+	return res, nil
+}
+
+// VolumePurge 清空指定卷, name为空时清空所有卷
+func (p *SPipelineService) VolumePurge(name string) error {
+	if _, err := storage.Pipeline(p.name).Get(); err != nil {
+		logx.Errorln("pipeline volume purge", p.name, err)
+		return errors.New("pipeline not found")
+	}
+	var err error
+	for _, v := range storage.Pipeline(p.name).Volume().List() {
+		if name != "" && v.Name != name {
+			continue
+		}
+		if _err := volume.Purge(p.name, v.Name); _err != nil {
+			logx.Errorln("pipeline volume purge", p.name, v.Name, _err)
+			err = multierr.Append(err, fmt.Errorf("%s: %w", v.Name, _err))
+		}
+		if name != "" {
+			return err
+		}
+	}
+	if name != "" {
+		return errors.New("volume not found")
+	}
+	return err
 }
 
 func (p *SPipelineService) BuildList(req *types.SPageReq) *types.SPipelineBuildListRes {
@@ -202,7 +319,7 @@ func (p *SPipelineService) buildRun(name string, param map[string]any) error {
 	}()
 	// 自动生成任务名称
 	taskReq.Name = name
-	err = Task(p.name).Create(taskReq)
+	err = Task(name).WithPipeline(p.name).Create(taskReq)
 	if err != nil {
 		logx.Errorln("pipeline build run", p.name, err)
 		return err
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"time"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+	"github.com/xmapst/AutoExecFlow/internal/worker/plugin"
+)
+
+type SPluginService struct {
+}
+
+func Plugin() *SPluginService {
+	return &SPluginService{}
+}
+
+// List 当前节点的步骤插件及其状态
+func (p *SPluginService) List() *types.SPluginListRes {
+	var res = &types.SPluginListRes{
+		Node:    config.App.NodeName,
+		Plugins: []*types.SPluginRes{},
+	}
+	for _, v := range plugin.List() {
+		item := &types.SPluginRes{
+			Name:        v.Name,
+			Path:        v.Path,
+			Version:     v.Version,
+			Description: v.Description,
+			Types:       v.Types,
+			State:       v.State,
+			Pid:         v.Pid,
+			Restarts:    v.Restarts,
+			Error:       v.Error,
+		}
+		if !v.StartedAt.IsZero() {
+			item.StartedAt = v.StartedAt.Format(time.RFC3339)
+		}
+		res.Plugins = append(res.Plugins, item)
+	}
+	return res
+}
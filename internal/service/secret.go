@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/types"
+)
+
+type SSecretService struct {
+	name string
+}
+
+func Secret(name string) *SSecretService {
+	return &SSecretService{
+		name: name,
+	}
+}
+
+func SecretList(req *types.SPageReq) *types.SSecretListRes {
+	secrets, total := storage.SecretList(req.Page, req.Size, req.Prefix)
+	if secrets == nil {
+		return nil
+	}
+	pageTotal := total / req.Size
+	if total%req.Size != 0 {
+		pageTotal += 1
+	}
+	var list = &types.SSecretListRes{
+		Page: types.SPageRes{
+			Current: req.Page,
+			Size:    req.Size,
+			Total:   pageTotal,
+		},
+	}
+	for _, secret := range secrets {
+		list.Secrets = append(list.Secrets, secretRes(secret))
+	}
+	return list
+}
+
+func secretRes(secret *models.SSecret) *types.SSecretRes {
+	return &types.SSecretRes{
+		Name:      secret.Name,
+		Desc:      secret.Desc,
+		CreatedAt: secret.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: secret.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func (s *SSecretService) Detail() (*types.SSecretRes, error) {
+	res, err := storage.Secret(s.name).Get()
+	if err != nil {
+		logx.Errorln("detail secret", s.name, err)
+		return nil, errors.New("secret not found")
+	}
+	return secretRes(res), nil
+}
+
+func (s *SSecretService) Create(req *types.SSecretCreateReq) error {
+	if reg.MatchString(s.name) {
+		return errors.New("secret name contains invalid characters")
+	}
+	return storage.SecretCreate(&models.SSecret{
+		Name: s.name,
+		SSecretUpdate: models.SSecretUpdate{
+			Desc:  req.Desc,
+			Value: req.Value,
+		},
+	})
+}
+
+func (s *SSecretService) Update(req *types.SSecretUpdateReq) error {
+	if _, err := storage.Secret(s.name).Get(); err != nil {
+		return errors.New("secret not found")
+	}
+	return storage.Secret(s.name).Update(&models.SSecretUpdate{
+		Desc:  req.Desc,
+		Value: req.Value,
+	})
+}
+
+func (s *SSecretService) Delete() error {
+	secret := storage.Secret(s.name)
+	if refs := secret.Refs(); len(refs) > 0 {
+		return fmt.Errorf("secret is referenced by unfinished tasks %v", refs)
+	}
+	return secret.Remove()
+}
+
+// reviewSecret 环境变量引用的密钥必须存在, 值在运行时注入
+func reviewSecret(env *types.SEnv) error {
+	if env.Secret == "" {
+		return nil
+	}
+	if _, err := storage.Secret(env.Secret).Get(); err != nil {
+		return fmt.Errorf("env %s: secret %s not found", env.Name, env.Secret)
+	}
+	env.Value = ""
+	return nil
+}
+
+// EnvRes 环境变量转换为响应格式, 引用密钥的变量不返回值
+func EnvRes(env *models.SEnv) *types.SEnv {
+	if env.Secret != "" {
+		return &types.SEnv{Name: env.Name, Secret: env.Secret}
+	}
+	return &types.SEnv{Name: env.Name, Value: env.Value}
+}
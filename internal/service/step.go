@@ -1,16 +1,25 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/segmentio/ksuid"
 	"github.com/xmapst/logx"
 
+	"github.com/xmapst/AutoExecFlow/internal/config"
 	"github.com/xmapst/AutoExecFlow/internal/queues"
 	"github.com/xmapst/AutoExecFlow/internal/server/api/base"
 	"github.com/xmapst/AutoExecFlow/internal/storage"
@@ -18,6 +27,7 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/types"
 	"github.com/xmapst/AutoExecFlow/internal/utils"
 	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/interpreter"
 )
 
 type SStepService struct {
@@ -44,7 +54,12 @@ func (ss *SStepService) Create(globalTimeout time.Duration, step *types.SStepReq
 	if timeout <= 0 || timeout > globalTimeout {
 		timeout = globalTimeout
 	}
-	if err = ss.saveStep(timeout, step); err != nil {
+	resources, err := ss.reviewResources(step.Resources)
+	if err != nil {
+		logx.Errorln("step review resources", ss.taskName, ss.stepName, err)
+		return err
+	}
+	if err = ss.saveStep(timeout, resources, step); err != nil {
 		logx.Errorln("step save", ss.taskName, ss.stepName, err)
 		return err
 	}
@@ -58,17 +73,24 @@ func (ss *SStepService) review(step *types.SStepReq) (time.Duration, error) {
 	}
 	ss.stepName = step.Name
 
-	if step.Type == "" {
-		return 0, errors.New("step type is empty")
-	}
-
 	if step.Content == "" {
 		return 0, errors.New("step content is empty")
 	}
 
+	// 未指定类型时根据shebang选择解释器
+	if step.Type == "" && strings.HasPrefix(step.Content, "#!") {
+		step.Type = interpreter.Auto
+	}
+	if step.Type == "" {
+		return 0, errors.New("step type is empty")
+	}
+
 	// 校验env是否重复
 	var envKeys []string
 	for _, v := range step.Env {
+		if err := reviewSecret(v); err != nil {
+			return 0, err
+		}
 		envKeys = append(envKeys, v.Name)
 	}
 	dup := utils.CheckDuplicate(envKeys)
@@ -76,12 +98,79 @@ func (ss *SStepService) review(step *types.SStepReq) (time.Duration, error) {
 		return 0, fmt.Errorf("duplicate key %v", dup)
 	}
 
+	// 校验运行身份
+	if step.Group != "" && step.User == "" {
+		return 0, errors.New("step group requires user")
+	}
+	if step.User != "" && !config.App.RunAsAllowed(step.User) {
+		return 0, fmt.Errorf("step is not allowed to run as user %s", step.User)
+	}
+
+	// 校验工作目录及标准输入
+	if err := common.CheckLocal("workdir", step.Workdir); err != nil {
+		return 0, err
+	}
+	if err := common.CheckLocal("stdin_file", step.StdinFile); err != nil {
+		return 0, err
+	}
+	if step.Stdin != "" && step.StdinFile != "" {
+		return 0, errors.New("step stdin and stdin_file are mutually exclusive")
+	}
+
+	// 校验成功判定规则
+	for _, code := range step.SkipCodes {
+		if slices.Contains(step.SuccessCodes, code) {
+			return 0, fmt.Errorf("exit code %d is in both success_codes and skip_codes", code)
+		}
+	}
+	if _, err := regexp.Compile(step.FailOnPattern); err != nil {
+		return 0, fmt.Errorf("invalid fail_on_pattern: %w", err)
+	}
+	if _, err := regexp.Compile(step.SucceedOnPattern); err != nil {
+		return 0, fmt.Errorf("invalid succeed_on_pattern: %w", err)
+	}
+
 	step.Depends = utils.RemoveDuplicate(step.Depends)
 	timeout, _ := time.ParseDuration(step.Timeout)
 	return timeout, nil
 }
 
-func (ss *SStepService) saveStep(timeout time.Duration, step *types.SStepReq) (err error) {
+func (ss *SStepService) reviewResources(req *types.SStepResources) (res models.SStepResources, err error) {
+	if req == nil {
+		return
+	}
+	if req.CPU != "" {
+		if strings.HasSuffix(req.CPU, "m") {
+			res.CPU, err = strconv.ParseFloat(strings.TrimSuffix(req.CPU, "m"), 64)
+			res.CPU = res.CPU / 1000
+		} else {
+			res.CPU, err = strconv.ParseFloat(req.CPU, 64)
+		}
+		if err != nil || res.CPU < 0 {
+			return res, fmt.Errorf("invalid cpu %s", req.CPU)
+		}
+	}
+	if req.Memory != "" {
+		var memory uint64
+		memory, err = humanize.ParseBytes(req.Memory)
+		if err != nil {
+			return res, fmt.Errorf("invalid memory %s", req.Memory)
+		}
+		res.Memory = int64(memory)
+	}
+	if req.Pids < 0 {
+		return res, fmt.Errorf("invalid pids %d", req.Pids)
+	}
+	res.Pids = req.Pids
+	// cgroup v2 io.weight 取值范围 [1, 10000]
+	if req.IOWeight != 0 && (req.IOWeight < 1 || req.IOWeight > 10000) {
+		return res, fmt.Errorf("invalid io_weight %d, must be between 1 and 10000", req.IOWeight)
+	}
+	res.IOWeight = req.IOWeight
+	return res, nil
+}
+
+func (ss *SStepService) saveStep(timeout time.Duration, resources models.SStepResources, step *types.SStepReq) (err error) {
 	stepStorage := storage.Task(ss.taskName).Step(step.Name)
 	defer func() {
 		if err != nil {
@@ -98,6 +187,24 @@ func (ss *SStepService) saveStep(timeout time.Duration, step *types.SStepReq) (e
 		Rule:     step.Rule,
 		Timeout:  timeout,
 		Disable:  models.Pointer(step.Disable),
+
+		SStepResources: resources,
+		SStepCredential: models.SStepCredential{
+			User:  step.User,
+			Group: step.Group,
+		},
+		SStepInput: models.SStepInput{
+			Workdir:   step.Workdir,
+			Args:      step.Args,
+			Stdin:     step.Stdin,
+			StdinFile: step.StdinFile,
+		},
+		SStepCriteria: models.SStepCriteria{
+			SuccessCodes:     step.SuccessCodes,
+			SkipCodes:        step.SkipCodes,
+			FailOnPattern:    step.FailOnPattern,
+			SucceedOnPattern: step.SucceedOnPattern,
+		},
 		SStepUpdate: models.SStepUpdate{
 			Message:  "the step is waiting to be scheduled for execution",
 			Code:     models.Pointer(int64(0)),
@@ -113,8 +220,9 @@ func (ss *SStepService) saveStep(timeout time.Duration, step *types.SStepReq) (e
 	var envs models.SEnvs
 	for _, env := range step.Env {
 		envs = append(envs, &models.SEnv{
-			Name:  env.Name,
-			Value: env.Value,
+			Name:   env.Name,
+			Value:  env.Value,
+			Secret: env.Secret,
 		})
 	}
 	if err = stepStorage.Env().Insert(envs...); err != nil {
@@ -138,33 +246,84 @@ func (ss *SStepService) Detail() (types.Code, *types.SStepRes, error) {
 		return types.CodeFailed, nil, errors.New("step not found")
 	}
 	data := &types.SStepRes{
-		Name:    step.Name,
-		Desc:    step.Desc,
-		State:   models.StateMap[*step.State],
-		Code:    *step.Code,
-		Message: step.Message,
-		Timeout: step.Timeout.String(),
-		Disable: *step.Disable,
-		Type:    step.Type,
-		Content: step.Content,
-		Action:  step.Action,
-		Rule:    step.Rule,
+		Name:      step.Name,
+		Desc:      step.Desc,
+		State:     models.StateMap[*step.State],
+		Code:      *step.Code,
+		Message:   step.Message,
+		Timeout:   step.Timeout.String(),
+		Disable:   *step.Disable,
+		Type:      step.Type,
+		Content:   step.Content,
+		Action:    step.Action,
+		Rule:      step.Rule,
+		User:      step.User,
+		Group:     step.Group,
+		Workdir:   step.Workdir,
+		Args:      step.Args,
+		Stdin:     step.Stdin,
+		StdinFile: step.StdinFile,
+
+		SuccessCodes:     step.SuccessCodes,
+		SkipCodes:        step.SkipCodes,
+		FailOnPattern:    step.FailOnPattern,
+		SucceedOnPattern: step.SucceedOnPattern,
+		Decision:         step.Decision,
 		Time: types.STimeRes{
 			Start: step.STimeStr(),
 			End:   step.ETimeStr(),
 		},
 	}
+	data.Resources = ResourcesRes(step.SStepResources)
+	if step.MemPeak != nil || step.CPUTime != nil {
+		data.Usage = new(types.SStepUsageRes)
+		if step.MemPeak != nil {
+			data.Usage.MemoryPeak = humanize.IBytes(uint64(*step.MemPeak))
+		}
+		if step.CPUTime != nil {
+			data.Usage.CPUTime = (time.Duration(*step.CPUTime) * time.Microsecond).String()
+		}
+	}
 	data.Depends = storage.Task(ss.taskName).Step(step.Name).Depend().List()
 	envs := stepStorage.Env().List()
 	for _, env := range envs {
-		data.Env = append(data.Env, &types.SEnv{
-			Name:  env.Name,
-			Value: env.Value,
+		data.Env = append(data.Env, EnvRes(env))
+	}
+	for _, export := range stepStorage.Export().List() {
+		data.Exports = append(data.Exports, export.Name)
+	}
+	outputs := stepStorage.Output().List()
+	for _, output := range outputs {
+		data.Outputs = append(data.Outputs, &types.SEnv{
+			Name:  output.Name,
+			Value: storage.MaskSecrets(output.Value),
 		})
 	}
+	// success_codes或succeed_on_pattern判定成功时退出码可能非零
+	if *step.State == models.StateStopped {
+		return types.CodeSuccess, data, nil
+	}
 	return types.Code(data.Code), data, nil
 }
 
+// ResourcesRes 资源限制转换为请求格式, 未设置时返回nil
+func ResourcesRes(resources models.SStepResources) *types.SStepResources {
+	if resources.IsEmpty() {
+		return nil
+	}
+	res := &types.SStepResources{
+		Pids:     resources.Pids,
+		IOWeight: resources.IOWeight,
+	}
+	if resources.CPU > 0 {
+		res.CPU = strconv.FormatFloat(resources.CPU, 'f', -1, 64)
+	}
+	if resources.Memory > 0 {
+		res.Memory = humanize.IBytes(uint64(resources.Memory))
+	}
+	return res
+}
+
 func (ss *SStepService) Manager(action string, duration string) error {
 	task, err := storage.Task(ss.taskName).Get()
 	if err != nil {
@@ -185,11 +344,78 @@ func (ss *SStepService) Manager(action string, duration string) error {
 	return queues.PublishManager(task.Node, utils.JoinWithInvisibleChar(ss.taskName, ss.stepName, action, duration))
 }
 
+// Attach 附加到运行中步骤的终端, 输出实时转发到ws, ws消息作为步骤输入
+func (ss *SStepService) Attach(ws *websocket.Conn, user string) error {
+	task, err := storage.Task(ss.taskName).Get()
+	if err != nil {
+		logx.Errorln("step attach", ss.taskName, ss.stepName, err)
+		return errors.New("task not found")
+	}
+	step, err := storage.Task(ss.taskName).Step(ss.stepName).Get()
+	if err != nil {
+		logx.Errorln("step attach", ss.taskName, ss.stepName, err)
+		return errors.New("step not found")
+	}
+	if *step.State != models.StateRunning {
+		return errors.New("step is no running")
+	}
+	if task.Node != config.App.NodeName {
+		return fmt.Errorf("step is running on node %s", task.Node)
+	}
+	terminal, err := common.Terminal(ss.taskName, ss.stepName)
+	if err != nil {
+		return err
+	}
+	session, err := terminal.Attach(user, &sWsWriter{ws: ws})
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var readDone = make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			// 与pty接口一致, 首字节为1的二进制消息为终端大小
+			if messageType == websocket.BinaryMessage && len(data) > 0 && data[0] == 1 {
+				ttySize := &types.STTYSize{}
+				if err = json.Unmarshal(bytes.Trim(data[1:], " \n\r\t\x00\x01"), ttySize); err == nil {
+					_ = session.Resize(ttySize.Rows, ttySize.Cols)
+				}
+				continue
+			}
+			if _, err = session.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-readDone:
+	case <-session.Done():
+	}
+	return nil
+}
+
+type sWsWriter struct {
+	ws *websocket.Conn
+}
+
+func (w *sWsWriter) Write(p []byte) (int, error) {
+	if err := w.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 func (ss *SStepService) Delete() error {
 	return storage.Task(ss.taskName).Step(ss.stepName).ClearAll()
 }
 
-func (ss *SStepService) Log() (types.Code, types.SStepLogsRes, error) {
+func (ss *SStepService) Log(req *types.SStepLogReq) (types.Code, types.SStepLogsRes, error) {
 	step, err := storage.Task(ss.taskName).Step(ss.stepName).Get()
 	if err != nil {
 		logx.Errorln("step log", ss.taskName, ss.stepName, err)
@@ -213,12 +439,17 @@ func (ss *SStepService) Log() (types.Code, types.SStepLogsRes, error) {
 			},
 		}, errors.New(step.Message)
 	default:
-		res, _ := ss.log(nil)
+		res, _ := ss.log(nil, req)
 		return ConvertState(*step.State), res, errors.New(step.Message)
 	}
 }
 
-func (ss *SStepService) log(latestLine *int64) (res types.SStepLogsRes, done bool) {
+func (ss *SStepService) log(latestLine *int64, req *types.SStepLogReq) (res types.SStepLogsRes, done bool) {
+	var streams, levels []string
+	if req != nil {
+		streams = splitFilter(req.Stream)
+		levels = splitFilter(req.Level)
+	}
 	logs := storage.Task(ss.taskName).Step(ss.stepName).Log().List(latestLine)
 	for _, v := range logs {
 		if v.Content == common.ConsoleStart {
@@ -228,10 +459,32 @@ func (ss *SStepService) log(latestLine *int64) (res types.SStepLogsRes, done boo
 			done = true
 			continue
 		}
+		if len(streams) > 0 && !slices.Contains(streams, v.Stream) {
+			continue
+		}
+		if len(levels) > 0 && !slices.Contains(levels, v.Level) {
+			continue
+		}
+		content := v.Content
+		if req != nil {
+			switch req.Color {
+			case "ansi":
+				if v.Raw != "" {
+					content = v.Raw
+				}
+			case "html":
+				content = html.EscapeString(content)
+				if v.Raw != "" {
+					content = utils.AnsiToHTML(v.Raw)
+				}
+			}
+		}
 		res = append(res, &types.SStepLogRes{
 			Timestamp: v.Timestamp,
 			Line:      *v.Line,
-			Content:   v.Content,
+			Content:   content,
+			Stream:    v.Stream,
+			Level:     v.Level,
 		})
 	}
 	// 如果查询到有新日志，更新 latestLine 为最后一条日志的行号
@@ -241,9 +494,20 @@ func (ss *SStepService) log(latestLine *int64) (res types.SStepLogsRes, done boo
 	return
 }
 
+// splitFilter 逗号分隔的过滤条件
+func splitFilter(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
 type stateHandlerFn func(ws *websocket.Conn, latest *int64) (bool, error)
 
-func (ss *SStepService) LogStream(ctx context.Context, ws *websocket.Conn) error {
+func (ss *SStepService) LogStream(ctx context.Context, ws *websocket.Conn, req *types.SStepLogReq) error {
 	db := storage.Task(ss.taskName).Step(ss.stepName)
 	step, err := db.Get()
 	if err != nil {
@@ -263,10 +527,10 @@ func (ss *SStepService) LogStream(ctx context.Context, ws *websocket.Conn) error
 		models.StatePending: ss.createOnceHandler(onceMap[models.StatePending], types.CodePending, "step is pending"),
 		models.StatePaused:  ss.createOnceHandler(onceMap[models.StatePaused], types.CodePaused, "step is paused"),
 		models.StateUnknown: ss.createOnceHandler(onceMap[models.StateUnknown], types.CodeNoData, "step status unknown"),
-		models.StateRunning: ss.handleRunningState,
-		models.StateStopped: ss.handleFinalState(types.CodeSuccess),
-		models.StateFailed:  ss.handleFinalState(types.CodeFailed),
-		models.StateSkipped: ss.handleFinalState(types.CodeSkipped),
+		models.StateRunning: ss.handleRunningState(req),
+		models.StateStopped: ss.handleFinalState(types.CodeSuccess, req),
+		models.StateFailed:  ss.handleFinalState(types.CodeFailed, req),
+		models.StateSkipped: ss.handleFinalState(types.CodeSkipped, req),
 	}
 
 	for {
@@ -318,20 +582,22 @@ func (ss *SStepService) createOnceHandler(once *sync.Once, code types.Code, mess
 	}
 }
 
-func (ss *SStepService) handleRunningState(ws *websocket.Conn, latestLine *int64) (bool, error) {
-	res, done := ss.log(latestLine)
-	err := ws.WriteJSON(base.WithData(res).WithCode(types.CodeRunning).WithError(errors.New("in progress")))
-	if err != nil {
-		logx.Errorln("step logstream", ss.taskName, ss.stepName, err)
-		return false, err
-	}
-	if done {
-		return false, nil
+func (ss *SStepService) handleRunningState(req *types.SStepLogReq) stateHandlerFn {
+	return func(ws *websocket.Conn, latestLine *int64) (bool, error) {
+		res, done := ss.log(latestLine, req)
+		err := ws.WriteJSON(base.WithData(res).WithCode(types.CodeRunning).WithError(errors.New("in progress")))
+		if err != nil {
+			logx.Errorln("step logstream", ss.taskName, ss.stepName, err)
+			return false, err
+		}
+		if done {
+			return false, nil
+		}
+		return true, nil
 	}
-	return true, nil
 }
 
-func (ss *SStepService) handleFinalState(code types.Code) stateHandlerFn {
+func (ss *SStepService) handleFinalState(code types.Code, req *types.SStepLogReq) stateHandlerFn {
 	return func(ws *websocket.Conn, latestLine *int64) (bool, error) {
 		db := storage.Task(ss.taskName).Step(ss.stepName)
 		step, err := db.Get()
@@ -339,7 +605,7 @@ func (ss *SStepService) handleFinalState(code types.Code) stateHandlerFn {
 			logx.Errorln("step logstream", ss.taskName, ss.stepName, err)
 			return false, errors.New("step not found")
 		}
-		res, _ := ss.log(latestLine)
+		res, _ := ss.log(latestLine, req)
 		var errMsg error
 		if code == types.CodeFailed {
 			errMsg = fmt.Errorf("exit code: %d", step.Code)
@@ -24,7 +24,8 @@ import (
 var reg = regexp.MustCompile("[^a-zA-Z\\p{Han}0-9\\-_.~]")
 
 type STaskService struct {
-	name string
+	name     string
+	pipeline string
 }
 
 func Task(name string) *STaskService {
@@ -33,6 +34,12 @@ func Task(name string) *STaskService {
 	}
 }
 
+// WithPipeline 标记任务由指定流水线构建
+func (ts *STaskService) WithPipeline(pipeline string) *STaskService {
+	ts.pipeline = pipeline
+	return ts
+}
+
 func TaskList(req *types.SPageReq) *types.STaskListDetailRes {
 	tasks, total := storage.TaskList(req.Page, req.Size, req.Prefix)
 	if tasks == nil {
@@ -142,6 +149,9 @@ func (ts *STaskService) review(task *types.STaskReq) (time.Duration, error) {
 	// 校验env是否重复
 	var envKeys []string
 	for _, v := range task.Env {
+		if err := reviewSecret(v); err != nil {
+			return 0, err
+		}
 		envKeys = append(envKeys, v.Name)
 	}
 	dup := utils.CheckDuplicate(envKeys)
@@ -206,12 +216,13 @@ func (ts *STaskService) uniqStepsName(steps types.SStepsReq) error {
 func (ts *STaskService) saveTask(timeout time.Duration, task *types.STaskReq) (time.Duration, error) {
 	// save task
 	err := storage.TaskCreate(&models.STask{
-		Kind:    task.Kind,
-		Name:    task.Name,
-		Desc:    task.Desc,
-		Node:    task.Node,
-		Timeout: timeout,
-		Disable: models.Pointer(task.Disable),
+		Kind:     task.Kind,
+		Name:     task.Name,
+		Desc:     task.Desc,
+		Node:     task.Node,
+		Pipeline: ts.pipeline,
+		Timeout:  timeout,
+		Disable:  models.Pointer(task.Disable),
 		STaskUpdate: models.STaskUpdate{
 			Message:  "the task is waiting to be scheduled for execution",
 			State:    models.Pointer(models.StatePending),
@@ -227,8 +238,9 @@ func (ts *STaskService) saveTask(timeout time.Duration, task *types.STaskReq) (t
 	var envs models.SEnvs
 	for _, env := range task.Env {
 		envs = append(envs, &models.SEnv{
-			Name:  env.Name,
-			Value: env.Value,
+			Name:   env.Name,
+			Value:  env.Value,
+			Secret: env.Secret,
 		})
 	}
 	if err = storage.Task(task.Name).Env().Insert(envs...); err != nil {
@@ -276,10 +288,7 @@ func (ts *STaskService) Detail() (types.Code, *types.STaskRes, error) {
 		},
 	}
 	for _, env := range db.Env().List() {
-		data.Env = append(data.Env, &types.SEnv{
-			Name:  env.Name,
-			Value: env.Value,
-		})
+		data.Env = append(data.Env, EnvRes(env))
 	}
 
 	// 获取当前进行到那些步骤
@@ -324,10 +333,7 @@ func (ts *STaskService) Dump() (*types.STaskReq, error) {
 		Disable: *task.Disable,
 	}
 	for _, env := range storage.Task(ts.name).Env().List() {
-		res.Env = append(res.Env, &types.SEnv{
-			Name:  env.Name,
-			Value: env.Value,
-		})
+		res.Env = append(res.Env, EnvRes(env))
 	}
 	steps := storage.Task(ts.name).StepList(storage.All)
 	for _, step := range steps {
@@ -337,13 +343,23 @@ func (ts *STaskService) Dump() (*types.STaskReq, error) {
 			Content: step.Content,
 			Timeout: step.Timeout.String(),
 			Disable: *step.Disable,
+			User:    step.User,
+			Group:   step.Group,
+			Workdir: step.Workdir,
+			Args:    step.Args,
+			Stdin:   step.Stdin,
+
+			StdinFile: step.StdinFile,
+			Resources: ResourcesRes(step.SStepResources),
+
+			SuccessCodes:     step.SuccessCodes,
+			SkipCodes:        step.SkipCodes,
+			FailOnPattern:    step.FailOnPattern,
+			SucceedOnPattern: step.SucceedOnPattern,
 		}
 		envs := storage.Task(ts.name).Step(step.Name).Env().List()
 		for _, env := range envs {
-			stepRes.Env = append(stepRes.Env, &types.SEnv{
-				Name:  env.Name,
-				Value: env.Value,
-			})
+			stepRes.Env = append(stepRes.Env, EnvRes(env))
 		}
 		stepRes.Depends = storage.Task(ts.name).Step(step.Name).Depend().List()
 		res.Step = append(res.Step, stepRes)
@@ -90,10 +90,14 @@ func newDB(rawURL string) (*sDatabase, error) {
 		&models.STaskEnv{},
 		&models.SStep{},
 		&models.SStepEnv{},
+		&models.SStepOutput{},
+		&models.SStepExport{},
 		&models.SStepDepend{},
 		&models.SStepLog{},
 		&models.SPipeline{},
 		&models.SPipelineBuild{},
+		&models.SPipelineVolume{},
+		&models.SSecret{},
 	); err != nil {
 		logx.Errorln(err)
 		return nil, err
@@ -227,6 +231,37 @@ func (d *sDatabase) TaskList(page, pageSize int64, str string) (res models.STask
 	return
 }
 
+func (d *sDatabase) Secret(name string) ISecret {
+	return &sSecret{
+		DB:   d.DB,
+		name: name,
+	}
+}
+
+func (d *sDatabase) SecretCreate(secret *models.SSecret) (err error) {
+	if secret.Value, err = encryptSecret(secret.Value); err != nil {
+		return err
+	}
+	defer masker.invalidate()
+	return d.Create(secret).Error
+}
+
+func (d *sDatabase) SecretList(page, pageSize int64, str string) (res models.SSecrets, total int64) {
+	query := d.Model(&models.SSecret{})
+	if str != "" {
+		query = query.Where("name LIKE ?", str+"%")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return
+	}
+	query.Omit("value").
+		Order("id DESC").
+		Scopes(func(db *gorm.DB) *gorm.DB {
+			return models.Paginate(db, page, pageSize)
+		}).Find(&res)
+	return
+}
+
 func (d *sDatabase) Pipeline(name string) IPipeline {
 	return &sPipeline{
 		DB:   d.DB,
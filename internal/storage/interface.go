@@ -33,6 +33,13 @@ type IStorage interface {
 	PipelineCreate(pipeline *models.SPipeline) (err error)
 	// PipelineList 获取流水线,支持分页, 模糊匹配
 	PipelineList(page, pageSize int64, str string) (res models.SPipelines, total int64)
+
+	// Secret 密钥接口
+	Secret(name string) (secret ISecret)
+	// SecretCreate 创建密钥, 值加密后保存
+	SecretCreate(secret *models.SSecret) (err error)
+	// SecretList 获取密钥,支持分页, 前缀匹配, 不包含值
+	SecretList(page, pageSize int64, str string) (res models.SSecrets, total int64)
 }
 
 type IBase interface {
@@ -55,6 +62,8 @@ type ITask interface {
 	State() (state models.State, err error)
 	// Env 环境变量接口
 	Env() (env IEnv)
+	// Pipeline 所属流水线名称, 非流水线构建的任务为空
+	Pipeline() (res string, err error)
 
 	// Timeout 超时时间
 	Timeout() (res time.Duration, err error)
@@ -86,6 +95,10 @@ type IStep interface {
 	State() (state models.State, err error)
 	// Env 环境变量接口
 	Env() (env IEnv)
+	// Output 输出接口
+	Output() (output IEnv)
+	// Export 通过环境变量文件导出的变量接口
+	Export() (export IEnv)
 
 	// TaskName 任务名称
 	TaskName() (taskName string)
@@ -99,12 +112,22 @@ type IStep interface {
 	Action() (res string, err error)
 	// Rule 规则
 	Rule() (res string, err error)
+	// Resources 资源限制
+	Resources() (res *models.SStepResources, err error)
+	// Credential 运行身份
+	Credential() (res *models.SStepCredential, err error)
+	// Input 工作目录, 参数及标准输入
+	Input() (res *models.SStepInput, err error)
+	// Criteria 成功判定规则
+	Criteria() (res *models.SStepCriteria, err error)
 	// Get 根据名称获取指定步骤
 	Get() (res *models.SStep, err error)
 	// Update 更新
 	Update(value *models.SStepUpdate) (err error)
-	// GlobalEnv 全局环境变量接口
+	// GlobalEnv 全局环境变量接口, 读取时合并已结束步骤导出的变量
 	GlobalEnv() (env IEnv)
+	// CheckSecrets 校验任务及步骤环境变量引用的密钥均可读取
+	CheckSecrets() (err error)
 	// Depend 依赖接口
 	Depend() (depend IDepend)
 	// Log 日志接口
@@ -141,6 +164,8 @@ type IPipeline interface {
 
 	// Build 执行相关
 	Build() (build IPipelineBuild)
+	// Volume 持久化卷
+	Volume() (volume IPipelineVolume)
 	// Task 任务接口
 	Task(name string) (task ITask)
 
@@ -156,6 +181,21 @@ type IPipeline interface {
 	Content() (res string, err error)
 }
 
+type ISecret interface {
+	// Name 名称
+	Name() (name string)
+	// Get 获取, 不包含值
+	Get() (res *models.SSecret, err error)
+	// Value 解密后的值
+	Value() (res string, err error)
+	// Update 更新, 值不为空时加密后保存
+	Update(value *models.SSecretUpdate) (err error)
+	// Refs 引用该密钥的未结束任务
+	Refs() (res []string)
+	// Remove 删除
+	Remove() (err error)
+}
+
 type IPipelineBuild interface {
 	// Get 根据名称获取指定构建
 	Get(name string) (res *models.SPipelineBuildRes, err error)
@@ -168,3 +208,14 @@ type IPipelineBuild interface {
 	// ClearAll 清理
 	ClearAll() (err error)
 }
+
+type IPipelineVolume interface {
+	// List 获取所有
+	List() (res models.SPipelineVolumes)
+	// Insert 插入或更新
+	Insert(volumes ...*models.SPipelineVolume) (err error)
+	// Replace 在同一事务中整体替换
+	Replace(volumes ...*models.SPipelineVolume) (err error)
+	// RemoveAll 清理
+	RemoveAll() (err error)
+}
@@ -47,6 +47,7 @@ func (b *SBase) BeforeCreate(tx *gorm.DB) (err error) {
 type SEnvs []*SEnv
 
 type SEnv struct {
-	Name  string `json:"name,omitempty" gorm:"size:256;index:,unique,composite:key;not null;comment:名称"`
-	Value string `json:"value,omitempty" gorm:"size:256;comment:值"`
+	Name   string `json:"name,omitempty" gorm:"size:256;index:,unique,composite:key;not null;comment:名称"`
+	Value  string `json:"value,omitempty" gorm:"size:256;comment:值"`
+	Secret string `json:"secret,omitempty" gorm:"size:256;comment:引用的密钥名称, 运行时注入值"`
 }
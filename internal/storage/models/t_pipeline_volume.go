@@ -0,0 +1,14 @@
+package models
+
+type SPipelineVolume struct {
+	SBase
+	PipelineName string `json:"pipeline_name,omitempty" gorm:"size:256;uniqueIndex:idx_pipeline_volume_name;not null;comment:流水线名称"`
+	Name         string `json:"name,omitempty" gorm:"size:256;uniqueIndex:idx_pipeline_volume_name;not null;comment:名称"`
+	Path         string `json:"path,omitempty" gorm:"size:1024;not null;comment:挂载路径"`
+}
+
+func (p *SPipelineVolume) TableName() string {
+	return "t_pipeline_volume"
+}
+
+type SPipelineVolumes []*SPipelineVolume
@@ -0,0 +1,18 @@
+package models
+
+type SSecret struct {
+	SBase
+	Name string `json:"name,omitempty" gorm:"size:256;uniqueIndex;not null;comment:名称"`
+	SSecretUpdate
+}
+
+func (s *SSecret) TableName() string {
+	return "t_secret"
+}
+
+type SSecretUpdate struct {
+	Desc  string `json:"desc,omitempty" gorm:"comment:描述"`
+	Value string `json:"value,omitempty" gorm:"type:text;comment:加密后的值"`
+}
+
+type SSecrets []*SSecret
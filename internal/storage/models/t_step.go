@@ -15,9 +15,55 @@ type SStep struct {
 	Rule     string        `json:"rule,omitempty" gorm:"comment:规则"`
 	Timeout  time.Duration `json:"timeout,omitempty" gorm:"not null;default:86400000000000;comment:超时时间"`
 	Disable  *bool         `json:"disable,omitempty" gorm:"not null;default:false;comment:禁用"`
+	SStepResources
+	SStepCredential
+	SStepInput
+	SStepCriteria
 	SStepUpdate
 }
 
+// SStepCriteria 成功判定规则, 未设置时以退出码0为成功
+type SStepCriteria struct {
+	SuccessCodes     []int64 `json:"success_codes,omitempty" gorm:"serializer:json;comment:成功退出码"`
+	SkipCodes        []int64 `json:"skip_codes,omitempty" gorm:"serializer:json;comment:跳过退出码"`
+	FailOnPattern    string  `json:"fail_on_pattern,omitempty" gorm:"comment:匹配则失败的输出正则"`
+	SucceedOnPattern string  `json:"succeed_on_pattern,omitempty" gorm:"comment:匹配则成功的输出正则"`
+}
+
+// SStepInput 工作目录, 参数及标准输入, 路径均相对于工作空间
+type SStepInput struct {
+	Workdir   string   `json:"workdir,omitempty" gorm:"size:1024;comment:工作目录"`
+	Args      []string `json:"args,omitempty" gorm:"serializer:json;comment:参数"`
+	Stdin     string   `json:"stdin,omitempty" gorm:"comment:标准输入"`
+	StdinFile string   `json:"stdin_file,omitempty" gorm:"size:1024;comment:标准输入文件"`
+}
+
+func (i *SStepInput) IsEmpty() bool {
+	return i == nil || (i.Workdir == "" && len(i.Args) == 0 && i.Stdin == "" && i.StdinFile == "")
+}
+
+// SStepCredential 运行身份, 仅Unix下生效
+type SStepCredential struct {
+	User  string `json:"user,omitempty" gorm:"column:run_user;size:256;comment:运行用户"`
+	Group string `json:"group,omitempty" gorm:"column:run_group;size:256;comment:运行用户组"`
+}
+
+func (c *SStepCredential) IsEmpty() bool {
+	return c == nil || (c.User == "" && c.Group == "")
+}
+
+// SStepResources 资源限制, 仅Linux cgroup v2下生效
+type SStepResources struct {
+	CPU      float64 `json:"cpu,omitempty" gorm:"column:res_cpu;not null;default:0;comment:CPU核数限制"`
+	Memory   int64   `json:"memory,omitempty" gorm:"column:res_memory;not null;default:0;comment:内存限制(字节)"`
+	Pids     int64   `json:"pids,omitempty" gorm:"column:res_pids;not null;default:0;comment:进程数限制"`
+	IOWeight int64   `json:"io_weight,omitempty" gorm:"column:res_io_weight;not null;default:0;comment:IO权重"`
+}
+
+func (r *SStepResources) IsEmpty() bool {
+	return r == nil || (r.CPU <= 0 && r.Memory <= 0 && r.Pids <= 0 && r.IOWeight <= 0)
+}
+
 func (s *SStep) TableName() string {
 	return "t_step"
 }
@@ -29,6 +75,9 @@ type SStepUpdate struct {
 	Code     *int64     `json:"code,omitempty" gorm:"index;not null;default:0;comment:退出码"`
 	STime    *time.Time `json:"s_time,omitempty" gorm:"comment:开始时间"`
 	ETime    *time.Time `json:"e_time,omitempty" gorm:"comment:结束时间"`
+	MemPeak  *int64     `json:"mem_peak,omitempty" gorm:"comment:内存峰值(字节)"`
+	CPUTime  *int64     `json:"cpu_time,omitempty" gorm:"comment:CPU时间(微秒)"`
+	Decision string     `json:"decision,omitempty" gorm:"size:64;comment:决定结果的规则"`
 }
 
 func (s *SStepUpdate) STimeStr() string {
@@ -7,8 +7,17 @@ type SStepLog struct {
 	Timestamp int64  `json:"timestamp,omitempty" gorm:"not null;comment:时间戳"`
 	Line      *int64 `json:"line,omitempty" gorm:"not null;comment:行号"`
 	Content   string `json:"content,omitempty" gorm:"comment:内容"`
+	Stream    string `json:"stream,omitempty" gorm:"size:16;index;default:null;comment:输出流"`
+	Level     string `json:"level,omitempty" gorm:"size:16;index;default:null;comment:日志级别"`
+	Raw       string `json:"raw,omitempty" gorm:"default:null;comment:包含ANSI转义的原始内容"`
 }
 
+const (
+	StreamStdout = "stdout" // 标准输出
+	StreamStderr = "stderr" // 标准错误
+	StreamOutput = "output" // pty等合并的输出
+)
+
 func (l *SStepLog) TableName() string {
 	return "t_step_log"
 }
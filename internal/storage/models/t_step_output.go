@@ -0,0 +1,22 @@
+package models
+
+type SStepOutput struct {
+	SBase
+	TaskName string `json:"task_name,omitempty" gorm:"size:256;index:,unique,composite:key;not null;comment:任务名称"`
+	StepName string `json:"step_name,omitempty" gorm:"size:256;index:,unique,composite:key;not null;comment:步骤名称"`
+	Name     string `json:"name,omitempty" gorm:"size:256;index:,unique,composite:key;not null;comment:名称"`
+	Value    string `json:"value,omitempty" gorm:"type:text;comment:值"`
+}
+
+func (s *SStepOutput) TableName() string {
+	return "t_step_output"
+}
+
+// SStepExport 步骤通过环境变量文件导出的变量, 之后启动的步骤可见
+type SStepExport struct {
+	SStepOutput
+}
+
+func (s *SStepExport) TableName() string {
+	return "t_step_export"
+}
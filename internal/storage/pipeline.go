@@ -25,6 +25,10 @@ func (p *sPipeline) ClearAll() error {
 	for _, task := range list {
 		_ = p.Task(task.TaskName).ClearAll()
 	}
+	// clear volume
+	if err := p.Volume().RemoveAll(); err != nil {
+		return err
+	}
 	// clear build
 	return p.Build().ClearAll()
 }
@@ -42,6 +46,13 @@ func (p *sPipeline) Build() IPipelineBuild {
 	}
 }
 
+func (p *sPipeline) Volume() IPipelineVolume {
+	return &sPipelineVolume{
+		pName: p.name,
+		DB:    p.DB,
+	}
+}
+
 func (p *sPipeline) Task(name string) ITask {
 	return &sTask{
 		DB:    p.DB,
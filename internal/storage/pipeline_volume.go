@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+type sPipelineVolume struct {
+	*gorm.DB
+	pName string
+}
+
+func (v *sPipelineVolume) List() (res models.SPipelineVolumes) {
+	v.Model(&models.SPipelineVolume{}).
+		Where(map[string]interface{}{
+			"pipeline_name": v.pName,
+		}).
+		Order("id ASC").
+		Find(&res)
+	return
+}
+
+func (v *sPipelineVolume) Insert(volumes ...*models.SPipelineVolume) (err error) {
+	if len(volumes) == 0 {
+		return
+	}
+	for _, volume := range volumes {
+		volume.PipelineName = v.pName
+	}
+	return v.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "pipeline_name"},
+			{Name: "name"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"path"}),
+	}).Create(volumes).Error
+}
+
+func (v *sPipelineVolume) RemoveAll() (err error) {
+	return v.Where(map[string]interface{}{
+		"pipeline_name": v.pName,
+	}).Delete(&models.SPipelineVolume{}).Error
+}
+
+func (v *sPipelineVolume) Replace(volumes ...*models.SPipelineVolume) (err error) {
+	return v.Transaction(func(tx *gorm.DB) error {
+		volume := &sPipelineVolume{DB: tx, pName: v.pName}
+		if err := volume.RemoveAll(); err != nil {
+			return err
+		}
+		return volume.Insert(volumes...)
+	})
+}
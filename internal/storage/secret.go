@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xmapst/logx"
+	"gorm.io/gorm"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/pkg/cryptox/x19sing"
+)
+
+type sSecret struct {
+	*gorm.DB
+	name string
+}
+
+func (s *sSecret) Name() string {
+	return s.name
+}
+
+func (s *sSecret) Get() (res *models.SSecret, err error) {
+	res = new(models.SSecret)
+	err = s.Model(&models.SSecret{}).
+		Omit("value").
+		Where(map[string]interface{}{
+			"name": s.name,
+		}).
+		First(res).
+		Error
+	return
+}
+
+func (s *sSecret) Value() (res string, err error) {
+	var values []string
+	err = s.Model(&models.SSecret{}).
+		Where(map[string]interface{}{
+			"name": s.name,
+		}).
+		Pluck("value", &values).
+		Error
+	if err != nil {
+		return
+	}
+	if len(values) == 0 {
+		return "", errors.Errorf("secret %s not found", s.name)
+	}
+	return decryptSecret(values[0])
+}
+
+func (s *sSecret) Update(value *models.SSecretUpdate) (err error) {
+	if value == nil {
+		return
+	}
+	var update = *value
+	if update.Value, err = encryptSecret(value.Value); err != nil {
+		return err
+	}
+	defer masker.invalidate()
+	return s.Model(&models.SSecret{}).
+		Where(map[string]interface{}{
+			"name": s.name,
+		}).
+		Updates(&update).
+		Error
+}
+
+func (s *sSecret) Refs() (res []string) {
+	active := s.Model(&models.STask{}).
+		Select("name").
+		Where("state IN ?", []models.State{models.StatePending, models.StateRunning, models.StatePaused})
+	var taskEnvs, stepEnvs []string
+	s.Model(&models.STaskEnv{}).
+		Where("secret = ? AND task_name IN (?)", s.name, active).
+		Distinct().
+		Pluck("task_name", &taskEnvs)
+	s.Model(&models.SStepEnv{}).
+		Where("secret = ? AND task_name IN (?)", s.name, active).
+		Distinct().
+		Pluck("task_name", &stepEnvs)
+	res = append(taskEnvs, stepEnvs...)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+func (s *sSecret) Remove() (err error) {
+	defer masker.invalidate()
+	return s.Where(map[string]interface{}{
+		"name": s.name,
+	}).Delete(&models.SSecret{}).Error
+}
+
+// 加密前在值前加入随机字节与校验和, 相同的值加密结果不同, 密钥错误时解密失败
+const (
+	secretNonceSize = 8
+	secretSumSize   = 4
+)
+
+var secretCipher *x19sing.Cipher
+
+// setSecretKey 使用服务端密钥的sha256派生32位加密密钥
+func setSecretKey(key string) (err error) {
+	if key == "" {
+		return errors.New("secret key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	secretCipher, err = x19sing.New(hex.EncodeToString(sum[:])[:x19sing.KeyLength])
+	return
+}
+
+func encryptSecret(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	prefix := make([]byte, secretNonceSize, secretNonceSize+secretSumSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+	prefix = binary.BigEndian.AppendUint32(prefix, crc32.ChecksumIEEE([]byte(value)))
+	return secretCipher.Encrypt(string(prefix) + value)
+}
+
+func decryptSecret(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	res, err := secretCipher.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	const size = secretNonceSize + secretSumSize
+	if len(res) < size || binary.BigEndian.Uint32([]byte(res[secretNonceSize:size])) != crc32.ChecksumIEEE([]byte(res[size:])) {
+		return "", errors.New("invalid secret, the server key may have changed")
+	}
+	return res[size:], nil
+}
+
+// resolveSecrets 引用密钥的环境变量在读取时注入解密后的值, 返回首个无法读取的密钥错误
+func resolveSecrets(db *gorm.DB, envs ...*models.SEnv) (err error) {
+	for _, env := range envs {
+		if env.Secret == "" {
+			continue
+		}
+		value, _err := (&sSecret{DB: db, name: env.Secret}).Value()
+		if _err != nil {
+			logx.Errorln("resolve secret", env.Name, env.Secret, _err)
+			if err == nil {
+				err = errors.Wrapf(_err, "env %s", env.Name)
+			}
+			continue
+		}
+		env.Value = value
+	}
+	return err
+}
+
+// masker 日志中的密钥值替换为***, 密钥变更或超过缓存时间后重新加载
+var masker = &sMasker{}
+
+const (
+	maskTTL       = 10 * time.Second
+	maskMinLength = 4 // 过短的值不替换, 避免日志中大量误替换
+	maskText      = "***"
+)
+
+type sMasker struct {
+	mu       sync.RWMutex
+	at       time.Time
+	replacer *strings.Replacer
+}
+
+func (m *sMasker) invalidate() {
+	m.mu.Lock()
+	m.at = time.Time{}
+	m.mu.Unlock()
+}
+
+func (m *sMasker) mask(db *gorm.DB, s string) string {
+	if s == "" {
+		return s
+	}
+	m.mu.RLock()
+	replacer, at := m.replacer, m.at
+	m.mu.RUnlock()
+	if time.Since(at) > maskTTL {
+		replacer = m.load(db)
+	}
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+func (m *sMasker) load(db *gorm.DB) *strings.Replacer {
+	var values []string
+	db.Model(&models.SSecret{}).Pluck("value", &values)
+
+	var variants []string
+	for _, value := range values {
+		plain, err := decryptSecret(value)
+		if err != nil {
+			continue
+		}
+		// 多行的值逐行替换, 日志按行写入
+		for _, v := range append(strings.Split(plain, "\n"), plain) {
+			v = strings.TrimSpace(v)
+			if len(v) < maskMinLength {
+				continue
+			}
+			variants = append(variants,
+				v,
+				base64.StdEncoding.EncodeToString([]byte(v)),
+				base64.RawStdEncoding.EncodeToString([]byte(v)),
+				base64.URLEncoding.EncodeToString([]byte(v)),
+				base64.RawURLEncoding.EncodeToString([]byte(v)),
+				url.QueryEscape(v),
+				url.PathEscape(v),
+			)
+		}
+	}
+	// 长的优先替换, 相同的值相邻后去重
+	slices.SortFunc(variants, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	variants = slices.Compact(variants)
+
+	var replacer *strings.Replacer
+	if len(variants) > 0 {
+		var oldnew = make([]string, 0, len(variants)*2)
+		for _, v := range variants {
+			oldnew = append(oldnew, v, maskText)
+		}
+		replacer = strings.NewReplacer(oldnew...)
+	}
+	m.mu.Lock()
+	m.replacer, m.at = replacer, time.Now()
+	m.mu.Unlock()
+	return replacer
+}
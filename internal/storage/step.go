@@ -15,6 +15,8 @@ type sStep struct {
 	sName string
 
 	env    IEnv
+	output IEnv
+	export IEnv
 	depend IDepend
 	log    ILog
 }
@@ -30,6 +32,12 @@ func (s *sStep) ClearAll() error {
 	if err := s.Env().RemoveAll(); err != nil {
 		return err
 	}
+	if err := s.Output().RemoveAll(); err != nil {
+		return err
+	}
+	if err := s.Export().RemoveAll(); err != nil {
+		return err
+	}
 	if err := s.Depend().RemoveAll(); err != nil {
 		return err
 	}
@@ -80,6 +88,30 @@ func (s *sStep) Env() IEnv {
 	return s.env
 }
 
+func (s *sStep) Output() IEnv {
+	if s.output == nil {
+		s.output = &sStepOutput{
+			DB:    s.DB,
+			table: (&models.SStepOutput{}).TableName(),
+			tName: s.tName,
+			sName: s.sName,
+		}
+	}
+	return s.output
+}
+
+func (s *sStep) Export() IEnv {
+	if s.export == nil {
+		s.export = &sStepOutput{
+			DB:    s.DB,
+			table: (&models.SStepExport{}).TableName(),
+			tName: s.tName,
+			sName: s.sName,
+		}
+	}
+	return s.export
+}
+
 func (s *sStep) TaskName() string {
 	return s.tName
 }
@@ -144,6 +176,58 @@ func (s *sStep) Rule() (res string, err error) {
 	return
 }
 
+func (s *sStep) Resources() (res *models.SStepResources, err error) {
+	res = new(models.SStepResources)
+	err = s.Model(&models.SStep{}).
+		Select("res_cpu, res_memory, res_pids, res_io_weight").
+		Where(map[string]interface{}{
+			"task_name": s.tName,
+			"name":      s.sName,
+		}).
+		Scan(res).
+		Error
+	return
+}
+
+func (s *sStep) Credential() (res *models.SStepCredential, err error) {
+	res = new(models.SStepCredential)
+	err = s.Model(&models.SStep{}).
+		Select("run_user, run_group").
+		Where(map[string]interface{}{
+			"task_name": s.tName,
+			"name":      s.sName,
+		}).
+		Scan(res).
+		Error
+	return
+}
+
+func (s *sStep) Input() (res *models.SStepInput, err error) {
+	res = new(models.SStepInput)
+	err = s.Model(&models.SStep{}).
+		Select("workdir, args, stdin, stdin_file").
+		Where(map[string]interface{}{
+			"task_name": s.tName,
+			"name":      s.sName,
+		}).
+		Scan(res).
+		Error
+	return
+}
+
+func (s *sStep) Criteria() (res *models.SStepCriteria, err error) {
+	res = new(models.SStepCriteria)
+	err = s.Model(&models.SStep{}).
+		Select("success_codes, skip_codes, fail_on_pattern, succeed_on_pattern").
+		Where(map[string]interface{}{
+			"task_name": s.tName,
+			"name":      s.sName,
+		}).
+		Scan(res).
+		Error
+	return
+}
+
 func (s *sStep) Get() (res *models.SStep, err error) {
 	res = new(models.SStep)
 	err = s.Model(&models.SStep{}).
@@ -171,14 +255,42 @@ func (s *sStep) Update(value *models.SStepUpdate) (err error) {
 
 func (s *sStep) GlobalEnv() IEnv {
 	if s.genv == nil {
-		s.genv = &sTaskEnv{
-			DB:    s.DB,
-			tName: s.tName,
+		s.genv = &sRuntimeEnv{
+			sTaskEnv: &sTaskEnv{
+				DB:    s.DB,
+				tName: s.tName,
+			},
+			sName: s.sName,
 		}
 	}
 	return s.genv
 }
 
+func (s *sStep) CheckSecrets() (err error) {
+	var envs []*models.SEnv
+	var taskEnvs []*models.STaskEnv
+	if err = s.Model(&models.STaskEnv{}).
+		Where("task_name = ? AND secret != ''", s.tName).
+		Find(&taskEnvs).
+		Error; err != nil {
+		return
+	}
+	for _, env := range taskEnvs {
+		envs = append(envs, &env.SEnv)
+	}
+	var stepEnvs []*models.SStepEnv
+	if err = s.Model(&models.SStepEnv{}).
+		Where("task_name = ? AND step_name = ? AND secret != ''", s.tName, s.sName).
+		Find(&stepEnvs).
+		Error; err != nil {
+		return
+	}
+	for _, env := range stepEnvs {
+		envs = append(envs, &env.SEnv)
+	}
+	return resolveSecrets(s.DB, envs...)
+}
+
 func (s *sStep) Depend() IDepend {
 	if s.depend == nil {
 		s.depend = &sStepDepend{
@@ -22,6 +22,8 @@ func (e *sStepEnv) List() (res models.SEnvs) {
 		}).
 		Order("id ASC").
 		Find(&res)
+	// 无法读取的密钥在执行前由CheckSecrets拦截, 此处仅记录日志
+	_ = resolveSecrets(e.DB, res...)
 	return
 }
 
@@ -43,7 +45,7 @@ func (e *sStepEnv) Insert(envs ...*models.SEnv) (err error) {
 			{Name: "step_name"},
 			{Name: "name"},
 		},
-		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		DoUpdates: clause.AssignmentColumns([]string{"value", "secret"}),
 	}).Create(_envs).Error
 	if err != nil {
 		return err
@@ -66,16 +68,23 @@ func (e *sStepEnv) Get(name string) (res string, err error) {
 	if name == "" {
 		return "", errors.New("name is empty")
 	}
+	var env = new(models.SEnv)
 	err = e.Model(&models.SStepEnv{}).
-		Select("value").
+		Select("value", "secret").
 		Where(map[string]interface{}{
 			"task_name": e.tName,
 			"step_name": e.sName,
 			"name":      name,
 		}).
-		Scan(&res).
+		Scan(env).
 		Error
-	return
+	if err != nil {
+		return
+	}
+	if err = resolveSecrets(e.DB, env); err != nil {
+		return "", err
+	}
+	return env.Value, nil
 }
 
 func (e *sStepEnv) Remove(name string) (err error) {
@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
 )
 
 type sStepLog struct {
@@ -39,6 +40,11 @@ func (l *sStepLog) Insert(log *models.SStepLog) error {
 	defer l.lock.Unlock()
 	log.TaskName = l.tName
 	log.StepName = l.sName
+	log.Content = masker.mask(l.DB, log.Content)
+	log.Raw = masker.mask(l.DB, log.Raw)
+	if log.Level == "" {
+		log.Level = utils.LogLevel(log.Content)
+	}
 	return l.Transaction(func(tx *gorm.DB) error {
 		var count int64
 		if err := l.Model(&models.SStepLog{}).
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+// sStepOutput 步骤输出, 导出的环境变量结构相同, 以表名区分
+type sStepOutput struct {
+	*gorm.DB
+	table string
+	tName string
+	sName string
+}
+
+func (o *sStepOutput) List() (res models.SEnvs) {
+	o.Table(o.table).
+		Select("name, value").
+		Where(map[string]interface{}{
+			"task_name": o.tName,
+			"step_name": o.sName,
+		}).
+		Order("id ASC").
+		Find(&res)
+	return
+}
+
+func (o *sStepOutput) Insert(envs ...*models.SEnv) (err error) {
+	if len(envs) == 0 {
+		return
+	}
+	var outputs []models.SStepOutput
+	for _, env := range envs {
+		outputs = append(outputs, models.SStepOutput{
+			TaskName: o.tName,
+			StepName: o.sName,
+			Name:     env.Name,
+			Value:    env.Value,
+		})
+	}
+	return o.Table(o.table).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "task_name"},
+			{Name: "step_name"},
+			{Name: "name"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(outputs).Error
+}
+
+func (o *sStepOutput) Update(env *models.SEnv) (err error) {
+	return o.Table(o.table).
+		Where(map[string]interface{}{
+			"task_name": o.tName,
+			"step_name": o.sName,
+			"name":      env.Name,
+		}).
+		Update("value", env.Value).Error
+}
+
+func (o *sStepOutput) Get(name string) (res string, err error) {
+	if name == "" {
+		return "", errors.New("name is empty")
+	}
+	err = o.Table(o.table).
+		Select("value").
+		Where(map[string]interface{}{
+			"task_name": o.tName,
+			"step_name": o.sName,
+			"name":      name,
+		}).
+		Scan(&res).
+		Error
+	return
+}
+
+func (o *sStepOutput) Remove(name string) (err error) {
+	if name == "" {
+		return errors.New("name is empty")
+	}
+	return o.Table(o.table).Where(map[string]interface{}{
+		"task_name": o.tName,
+		"step_name": o.sName,
+		"name":      name,
+	}).Delete(&models.SStepOutput{}).Error
+}
+
+func (o *sStepOutput) RemoveAll() (err error) {
+	return o.Table(o.table).Where(map[string]interface{}{
+		"task_name": o.tName,
+		"step_name": o.sName,
+	}).Delete(&models.SStepOutput{}).Error
+}
@@ -16,7 +16,10 @@ const (
 	TypeSqlserver = "sqlserver"
 )
 
-func New(dataCenterID, nodeID int64, rawURL string) error {
+func New(dataCenterID, nodeID int64, rawURL, secretKey string) error {
+	if err := setSecretKey(secretKey); err != nil {
+		return err
+	}
 	db, err := newDB(rawURL)
 	if err != nil {
 		return err
@@ -74,3 +77,20 @@ func PipelineCreate(pipeline *models.SPipeline) (err error) {
 func PipelineList(page, pageSize int64, str string) (res []*models.SPipeline, total int64) {
 	return storage.PipelineList(page, pageSize, str)
 }
+
+func Secret(name string) ISecret {
+	return storage.Secret(name)
+}
+
+func SecretCreate(secret *models.SSecret) (err error) {
+	return storage.SecretCreate(secret)
+}
+
+func SecretList(page, pageSize int64, str string) (res models.SSecrets, total int64) {
+	return storage.SecretList(page, pageSize, str)
+}
+
+// MaskSecrets 将内容中出现的密钥值替换为***
+func MaskSecrets(s string) string {
+	return masker.mask(storage.GetDB(), s)
+}
@@ -88,6 +88,17 @@ func (t *sTask) Env() IEnv {
 	return t.env
 }
 
+func (t *sTask) Pipeline() (res string, err error) {
+	err = t.Model(&models.STask{}).
+		Select("pipeline").
+		Where(map[string]interface{}{
+			"name": t.tName,
+		}).
+		Scan(&res).
+		Error
+	return
+}
+
 func (t *sTask) Timeout() (res time.Duration, err error) {
 	err = t.Model(&models.STask{}).
 		Select("timeout").
@@ -125,7 +136,6 @@ func (t *sTask) Update(value *models.STaskUpdate) (err error) {
 func (t *sTask) Step(name string) IStep {
 	return &sStep{
 		DB:    t.DB,
-		genv:  t.Env(),
 		tName: t.tName,
 		sName: name,
 	}
@@ -20,6 +20,8 @@ func (e *sTaskEnv) List() (res models.SEnvs) {
 		}).
 		Order("id ASC").
 		Find(&res)
+	// 无法读取的密钥在执行前由CheckSecrets拦截, 此处仅记录日志
+	_ = resolveSecrets(e.DB, res...)
 	return
 }
 
@@ -39,7 +41,7 @@ func (e *sTaskEnv) Insert(envs ...*models.SEnv) (err error) {
 			{Name: "task_name"},
 			{Name: "name"},
 		},
-		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		DoUpdates: clause.AssignmentColumns([]string{"value", "secret"}),
 	}).Create(_envs).Error
 	if err != nil {
 		return err
@@ -61,15 +63,22 @@ func (e *sTaskEnv) Get(name string) (res string, err error) {
 	if name == "" {
 		return "", errors.New("name is empty")
 	}
+	var env = new(models.SEnv)
 	err = e.Model(&models.STaskEnv{}).
-		Select("value").
+		Select("value", "secret").
 		Where(map[string]interface{}{
 			"task_name": e.tName,
 			"name":      name,
 		}).
-		Scan(&res).
+		Scan(env).
 		Error
-	return
+	if err != nil {
+		return
+	}
+	if err = resolveSecrets(e.DB, env); err != nil {
+		return "", err
+	}
+	return env.Value, nil
 }
 
 func (e *sTaskEnv) Remove(name string) (err error) {
@@ -87,3 +96,56 @@ func (e *sTaskEnv) RemoveAll() (err error) {
 		"task_name": e.tName,
 	}).Delete(&models.STaskEnv{}).Error
 }
+
+// sRuntimeEnv 步骤运行时的全局环境变量, 依次合并任务环境变量, 已结束步骤导出的变量及所依赖步骤的输出, 同名时后者优先
+type sRuntimeEnv struct {
+	*sTaskEnv
+	sName string
+}
+
+// runtime 导出的变量在前, 直接依赖步骤的输出在后, 各自按写入顺序
+func (e *sRuntimeEnv) runtime(name string) (res models.SEnvs) {
+	var exports, outputs models.SEnvs
+	query := e.Table((&models.SStepExport{}).TableName()).
+		Select("name, value").
+		Where("task_name = ?", e.tName)
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	query.Order("id ASC").Find(&exports)
+
+	depends := e.Model(&models.SStepDepend{}).
+		Select("name").
+		Where("task_name = ? AND step_name = ?", e.tName, e.sName)
+	query = e.Model(&models.SStepOutput{}).
+		Select("name, value").
+		Where("task_name = ? AND step_name IN (?)", e.tName, depends)
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	query.Order("id ASC").Find(&outputs)
+	return append(exports, outputs...)
+}
+
+func (e *sRuntimeEnv) List() (res models.SEnvs) {
+	var index = make(map[string]int)
+	for _, env := range append(e.sTaskEnv.List(), e.runtime("")...) {
+		if i, ok := index[env.Name]; ok {
+			res[i] = env
+			continue
+		}
+		index[env.Name] = len(res)
+		res = append(res, env)
+	}
+	return
+}
+
+func (e *sRuntimeEnv) Get(name string) (res string, err error) {
+	if name == "" {
+		return "", errors.New("name is empty")
+	}
+	if envs := e.runtime(name); len(envs) > 0 {
+		return envs[len(envs)-1].Value, nil
+	}
+	return e.sTaskEnv.Get(name)
+}
@@ -115,6 +115,7 @@ type STimeRes struct {
 type SEnvs []*SEnv
 
 type SEnv struct {
-	Name  string `json:"name" yaml:"name" binding:"required"`
-	Value string `json:"value" yaml:"value"`
+	Name   string `json:"name" yaml:"name" binding:"required"`
+	Value  string `json:"value" yaml:"value"`
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty" example:"db-password"` // 引用的密钥名称, 值在运行时注入
 }
@@ -0,0 +1,17 @@
+package types
+
+type SInterpreterListRes struct {
+	Node         string             `json:"node" yaml:"node"`
+	Interpreters []*SInterpreterRes `json:"interpreters" yaml:"interpreters"`
+}
+
+type SInterpreterRes struct {
+	Type      string   `json:"type" yaml:"type"`
+	Aliases   []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Command   []string `json:"command" yaml:"command"`
+	Suffix    string   `json:"suffix" yaml:"suffix"`
+	Encoding  string   `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	Version   string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Available bool     `json:"available" yaml:"available"`
+	Error     string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
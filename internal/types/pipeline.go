@@ -8,28 +8,48 @@ type SPipelineListRes struct {
 type SPipelinesRes []*SPipelineRes
 
 type SPipelineRes struct {
-	Name    string `json:"name" yaml:"name"`
-	Desc    string `json:"desc,omitempty" yaml:"desc,omitempty"`
-	Disable bool   `json:"disable,omitempty" yaml:"disable,omitempty"`
-	TplType string `json:"tplType" yaml:"tplType"`
-	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	Name    string           `json:"name" yaml:"name"`
+	Desc    string           `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Disable bool             `json:"disable,omitempty" yaml:"disable,omitempty"`
+	TplType string           `json:"tplType" yaml:"tplType"`
+	Content string           `json:"content,omitempty" yaml:"content,omitempty"`
+	Volumes SPipelineVolumes `json:"volumes,omitempty" yaml:"volumes,omitempty"`
 }
 
 type SPipelineCreateReq struct {
-	Name    string `json:"name" yaml:"name" binding:"required"`
-	Desc    string `json:"desc,omitempty" yaml:"desc,omitempty"`
-	Disable *bool  `json:"disable" yaml:"disable"`
-	TplType string `json:"tplType" yaml:"tplType" binding:"required" example:"jinja2"`
-	Content string `json:"content" yaml:"content" binding:"required"`
+	Name    string           `json:"name" yaml:"name" binding:"required"`
+	Desc    string           `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Disable *bool            `json:"disable" yaml:"disable"`
+	TplType string           `json:"tplType" yaml:"tplType" binding:"required" example:"jinja2"`
+	Content string           `json:"content" yaml:"content" binding:"required"`
+	Volumes SPipelineVolumes `json:"volumes,omitempty" yaml:"volumes,omitempty"`
 }
 
 type SPipelineUpdateReq struct {
-	Desc    string `json:"desc,omitempty" yaml:"desc,omitempty"`
-	Disable *bool  `json:"disable" yaml:"disable"`
-	TplType string `json:"tplType" yaml:"tplType" binding:"required" example:"jinja2"`
-	Content string `json:"content" yaml:"content" binding:"required"`
+	Desc    string           `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Disable *bool            `json:"disable" yaml:"disable"`
+	TplType string           `json:"tplType" yaml:"tplType" binding:"required" example:"jinja2"`
+	Content string           `json:"content" yaml:"content" binding:"required"`
+	Volumes SPipelineVolumes `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+}
+
+type SPipelineVolumes []*SPipelineVolume
+
+type SPipelineVolume struct {
+	Name string `json:"name" yaml:"name" binding:"required"`
+	Path string `json:"path" yaml:"path" binding:"required" example:".cache/go-build"`
 }
 
+type SPipelineVolumeRes struct {
+	Name    string `json:"name" yaml:"name"`
+	Path    string `json:"path" yaml:"path"`
+	Size    int64  `json:"size" yaml:"size"`
+	ModTime int64  `json:"modTime" yaml:"modTime"`
+	Locked  bool   `json:"locked" yaml:"locked"`
+}
+
+type SPipelineVolumesRes []*SPipelineVolumeRes
+
 type SPipelineBuildRes struct {
 	PipelineName string   `json:"pipelineName" yaml:"pipelineName"`
 	TaskName     string   `json:"taskName" yaml:"taskName"`
@@ -0,0 +1,19 @@
+package types
+
+type SPluginListRes struct {
+	Node    string        `json:"node" yaml:"node"`
+	Plugins []*SPluginRes `json:"plugins" yaml:"plugins"`
+}
+
+type SPluginRes struct {
+	Name        string   `json:"name" yaml:"name"`
+	Path        string   `json:"path" yaml:"path"`
+	Version     string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Types       []string `json:"types,omitempty" yaml:"types,omitempty"`
+	State       string   `json:"state" yaml:"state" example:"running"`
+	Pid         int      `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Restarts    int      `json:"restarts" yaml:"restarts"`
+	Error       string   `json:"error,omitempty" yaml:"error,omitempty"`
+	StartedAt   string   `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+}
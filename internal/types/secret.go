@@ -0,0 +1,27 @@
+package types
+
+type SSecretListRes struct {
+	Page    SPageRes    `json:"page" yaml:"page"`
+	Secrets SSecretsRes `json:"secrets" yaml:"secrets"`
+}
+
+type SSecretsRes []*SSecretRes
+
+// SSecretRes 密钥信息, 不返回值
+type SSecretRes struct {
+	Name      string `json:"name" yaml:"name"`
+	Desc      string `json:"desc,omitempty" yaml:"desc,omitempty"`
+	CreatedAt string `json:"createdAt" yaml:"createdAt"`
+	UpdatedAt string `json:"updatedAt" yaml:"updatedAt"`
+}
+
+type SSecretCreateReq struct {
+	Name  string `json:"name" yaml:"name" binding:"required" example:"db-password"`
+	Desc  string `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Value string `json:"value" yaml:"value" binding:"required"`
+}
+
+type SSecretUpdateReq struct {
+	Desc  string `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"` // 为空时不修改
+}
@@ -10,11 +10,40 @@ type SStepRes struct {
 	Depends []string `json:"depends,omitempty" yaml:"depends,omitempty"`
 	Message string   `json:"message" yaml:"message"`
 	Env     SEnvs    `json:"env,omitempty" yaml:"env,omitempty"`
+	Outputs SEnvs    `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	Exports []string `json:"exports,omitempty" yaml:"exports,omitempty"`
 	Type    string   `json:"type,omitempty" yaml:"type,omitempty"`
 	Content string   `json:"content,omitempty" yaml:"content,omitempty"`
 	Action  string   `json:"action,omitempty" yaml:"action,omitempty"`
 	Rule    string   `json:"rule,omitempty" yaml:"rule,omitempty"`
 	Time    STimeRes `json:"time,omitempty" yaml:"time,omitempty"`
+	User    string   `json:"user,omitempty" yaml:"user,omitempty"`
+	Group   string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Workdir string   `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+	Stdin   string   `json:"stdin,omitempty" yaml:"stdin,omitempty"`
+
+	StdinFile string          `json:"stdin_file,omitempty" yaml:"stdin_file,omitempty"`
+	Resources *SStepResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Usage     *SStepUsageRes  `json:"usage,omitempty" yaml:"usage,omitempty"`
+
+	SuccessCodes     []int64 `json:"success_codes,omitempty" yaml:"success_codes,omitempty"`
+	SkipCodes        []int64 `json:"skip_codes,omitempty" yaml:"skip_codes,omitempty"`
+	FailOnPattern    string  `json:"fail_on_pattern,omitempty" yaml:"fail_on_pattern,omitempty"`
+	SucceedOnPattern string  `json:"succeed_on_pattern,omitempty" yaml:"succeed_on_pattern,omitempty"`
+	Decision         string  `json:"decision,omitempty" yaml:"decision,omitempty"`
+}
+
+type SStepResources struct {
+	CPU      string `json:"cpu,omitempty" form:"cpu" yaml:"cpu,omitempty" example:"500m"`
+	Memory   string `json:"memory,omitempty" form:"memory" yaml:"memory,omitempty" example:"512MiB"`
+	Pids     int64  `json:"pids,omitempty" form:"pids" yaml:"pids,omitempty"`
+	IOWeight int64  `json:"io_weight,omitempty" form:"io_weight" yaml:"io_weight,omitempty"`
+}
+
+type SStepUsageRes struct {
+	MemoryPeak string `json:"memory_peak,omitempty" yaml:"memory_peak,omitempty"`
+	CPUTime    string `json:"cpu_time,omitempty" yaml:"cpu_time,omitempty"`
 }
 
 type SStepsRes []*SStepRes
@@ -26,18 +55,40 @@ type SStepReq struct {
 	Disable bool     `json:"disable,omitempty" form:"disable" yaml:"disable,omitempty"`
 	Depends []string `json:"depends,omitempty" form:"depends" yaml:"depends,omitempty"`
 	Env     SEnvs    `json:"env,omitempty" form:"env" yaml:"env,omitempty"`
-	Type    string   `json:"type,omitempty" form:"type" yaml:"type,omitempty" binding:"required"`
+	Type    string   `json:"type,omitempty" form:"type" yaml:"type,omitempty"`
 	Content string   `json:"content,omitempty" form:"content" yaml:"content,omitempty" binding:"required"`
 	Action  string   `json:"action,omitempty" form:"action" yaml:"action,omitempty"`
 	Rule    string   `json:"rule,omitempty" form:"rule" yaml:"rule,omitempty"`
+	User    string   `json:"user,omitempty" form:"user" yaml:"user,omitempty" example:"nobody"`
+	Group   string   `json:"group,omitempty" form:"group" yaml:"group,omitempty" example:"nogroup"`
+	Workdir string   `json:"workdir,omitempty" form:"workdir" yaml:"workdir,omitempty" example:"src/app"`
+	Args    []string `json:"args,omitempty" form:"args" yaml:"args,omitempty"`
+	Stdin   string   `json:"stdin,omitempty" form:"stdin" yaml:"stdin,omitempty"`
+
+	StdinFile string          `json:"stdin_file,omitempty" form:"stdin_file" yaml:"stdin_file,omitempty" example:"input.txt"` // 工作空间内作为标准输入的文件, 与stdin互斥
+	Resources *SStepResources `json:"resources,omitempty" form:"resources" yaml:"resources,omitempty"`
+
+	// 成功判定规则, 未设置时以退出码0为成功
+	SuccessCodes     []int64 `json:"success_codes,omitempty" form:"success_codes" yaml:"success_codes,omitempty" example:"0,1"`
+	SkipCodes        []int64 `json:"skip_codes,omitempty" form:"skip_codes" yaml:"skip_codes,omitempty" example:"3"`
+	FailOnPattern    string  `json:"fail_on_pattern,omitempty" form:"fail_on_pattern" yaml:"fail_on_pattern,omitempty" example:"^ERROR:"`
+	SucceedOnPattern string  `json:"succeed_on_pattern,omitempty" form:"succeed_on_pattern" yaml:"succeed_on_pattern,omitempty"`
 }
 
 type SStepsReq []*SStepReq
 
+type SStepLogReq struct {
+	Stream string `json:"stream,omitempty" form:"stream" yaml:"stream,omitempty" example:"stderr"`  // 按输出流过滤, 逗号分隔
+	Level  string `json:"level,omitempty" form:"level" yaml:"level,omitempty" example:"warn,error"` // 按级别过滤, 逗号分隔
+	Color  string `json:"color,omitempty" form:"color" yaml:"color,omitempty" example:"html"`       // 颜色渲染, ansi或html
+}
+
 type SStepLogRes struct {
 	Timestamp int64  `json:"timestamp" yaml:"timestamp"`
 	Line      int64  `json:"line" yaml:"line"`
 	Content   string `json:"content" yaml:"content"`
+	Stream    string `json:"stream,omitempty" yaml:"stream,omitempty"`
+	Level     string `json:"level,omitempty" yaml:"level,omitempty"`
 }
 
 type SStepLogsRes []*SStepLogRes
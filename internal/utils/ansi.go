@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ansiRegexp 匹配ANSI转义序列, 包括CSI与OSC
+var ansiRegexp = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+var ansiPalette = [16]string{
+	"#000000", "#cd3131", "#0dbc79", "#e5e510", "#2472c8", "#bc3fbc", "#11a8cd", "#e5e5e5",
+	"#666666", "#f14c4c", "#23d18b", "#f5f543", "#3b8eea", "#d670d6", "#29b8db", "#ffffff",
+}
+
+// HasAnsi 是否包含ANSI转义序列
+func HasAnsi(s string) bool {
+	return strings.Contains(s, "\x1b")
+}
+
+// StripAnsi 去除ANSI转义序列
+func StripAnsi(s string) string {
+	if !HasAnsi(s) {
+		return s
+	}
+	return ansiRegexp.ReplaceAllString(s, "")
+}
+
+type sAnsiStyle struct {
+	fg, bg                  string
+	bold, italic, underline bool
+}
+
+func (s sAnsiStyle) css() string {
+	var css []string
+	if s.fg != "" {
+		css = append(css, "color:"+s.fg)
+	}
+	if s.bg != "" {
+		css = append(css, "background-color:"+s.bg)
+	}
+	if s.bold {
+		css = append(css, "font-weight:bold")
+	}
+	if s.italic {
+		css = append(css, "font-style:italic")
+	}
+	if s.underline {
+		css = append(css, "text-decoration:underline")
+	}
+	return strings.Join(css, ";")
+}
+
+// AnsiToHTML 将SGR颜色与样式转为带style的span, 其他转义序列去除, 文本按html转义
+func AnsiToHTML(s string) string {
+	var sb strings.Builder
+	var style sAnsiStyle
+	var open bool
+	var last int
+	for _, loc := range ansiRegexp.FindAllStringIndex(s, -1) {
+		sb.WriteString(html.EscapeString(s[last:loc[0]]))
+		last = loc[1]
+		seq := s[loc[0]:loc[1]]
+		if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "m") {
+			continue
+		}
+		style = style.apply(seq[2 : len(seq)-1])
+		if open {
+			sb.WriteString("</span>")
+			open = false
+		}
+		if css := style.css(); css != "" {
+			sb.WriteString(`<span style="` + css + `">`)
+			open = true
+		}
+	}
+	sb.WriteString(html.EscapeString(s[last:]))
+	if open {
+		sb.WriteString("</span>")
+	}
+	return sb.String()
+}
+
+// apply 应用SGR参数, 支持16色、256色与真彩色
+func (s sAnsiStyle) apply(params string) sAnsiStyle {
+	if params == "" {
+		return sAnsiStyle{}
+	}
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 0:
+			s = sAnsiStyle{}
+		case code == 1:
+			s.bold = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 22:
+			s.bold = false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code >= 30 && code <= 37:
+			s.fg = ansiPalette[code-30]
+		case code >= 90 && code <= 97:
+			s.fg = ansiPalette[code-90+8]
+		case code == 39:
+			s.fg = ""
+		case code >= 40 && code <= 47:
+			s.bg = ansiPalette[code-40]
+		case code >= 100 && code <= 107:
+			s.bg = ansiPalette[code-100+8]
+		case code == 49:
+			s.bg = ""
+		case code == 38 || code == 48:
+			color, n := ansiExtendedColor(codes[i+1:])
+			i += n
+			if code == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+	return s
+}
+
+// ansiExtendedColor 解析 5;n 或 2;r;g;b, 返回颜色与消耗的参数个数
+func ansiExtendedColor(codes []string) (string, int) {
+	if len(codes) == 0 {
+		return "", 0
+	}
+	num := func(i int) int {
+		v, _ := strconv.Atoi(codes[i])
+		return max(0, min(255, v))
+	}
+	switch codes[0] {
+	case "5":
+		if len(codes) < 2 {
+			return "", len(codes)
+		}
+		n := num(1)
+		switch {
+		case n < 16:
+			return ansiPalette[n], 2
+		case n < 232:
+			levels := [6]int{0, 95, 135, 175, 215, 255}
+			n -= 16
+			return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6]), 2
+		default:
+			gray := 8 + (n-232)*10
+			return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray), 2
+		}
+	case "2":
+		if len(codes) < 4 {
+			return "", len(codes)
+		}
+		return fmt.Sprintf("#%02x%02x%02x", num(1), num(2), num(3)), 4
+	}
+	return "", 1
+}
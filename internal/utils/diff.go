@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// DiffLines 逐行比较, 输出变化的行, 删除的行以-开头, 新增的行以+开头
+func DiffLines(a, b []string) []string {
+	n, m := len(a), len(b)
+	if n*m > 1<<22 {
+		return []string{fmt.Sprintf("@@ %d lines -> %d lines, too large to diff @@", n, m)}
+	}
+	// lcs[i][j] 为a[i:]与b[j:]的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var res []string
+	var hunk bool
+	for i, j := 0, 0; i < n || j < m; {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			i, j, hunk = i+1, j+1, false
+			continue
+		case !hunk:
+			res = append(res, fmt.Sprintf("@@ -%d +%d @@", i+1, j+1))
+			hunk = true
+		}
+		if j >= m || (i < n && lcs[i+1][j] >= lcs[i][j+1]) {
+			res = append(res, "-"+a[i])
+			i++
+		} else {
+			res = append(res, "+"+b[j])
+			j++
+		}
+	}
+	return res
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+const logTimestamp = `^(?:\[?[\dTZ:.,/+\-]+\]?\s+){0,3}`
+
+// logLevelRegexps 常见日志格式中的级别, 依次为json, logfmt, 方括号, 冒号, 大写单词与klog
+var logLevelRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)"(?:level|lvl|severity|loglevel)"\s*:\s*"([a-z]+)"`),
+	regexp.MustCompile(`(?i)(?:^|\s)(?:level|lvl|severity)=["']?([a-z]+)`),
+	regexp.MustCompile(`(?i)` + logTimestamp + `[\[<(]([a-z]+)[\]>)]`),
+	regexp.MustCompile(`(?i)` + logTimestamp + `([a-z]+):`),
+	regexp.MustCompile(logTimestamp + `(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|FATAL|CRITICAL|PANIC)\s`),
+	regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`),
+}
+
+var logLevels = map[string]string{
+	"trace":    "trace",
+	"debug":    "debug",
+	"dbg":      "debug",
+	"info":     "info",
+	"notice":   "info",
+	"i":        "info",
+	"warn":     "warn",
+	"warning":  "warn",
+	"w":        "warn",
+	"error":    "error",
+	"err":      "error",
+	"e":        "error",
+	"fatal":    "fatal",
+	"critical": "fatal",
+	"crit":     "fatal",
+	"panic":    "fatal",
+	"f":        "fatal",
+}
+
+// LogLevel 从常见日志格式中解析级别, 统一为trace, debug, info, warn, error, fatal, 无法识别时为空
+func LogLevel(line string) string {
+	for _, re := range logLevelRegexps {
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if level, ok := logLevels[strings.ToLower(match[1])]; ok {
+			return level
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// approvals 运行中等待人工确认的步骤
+var approvals sync.Map
+
+// WaitApproval 等待步骤被确认, 由步骤管理的approve操作放行
+func WaitApproval(ctx context.Context, taskName, stepName string) error {
+	key := taskName + "/" + stepName
+	ch := make(chan struct{})
+	approvals.Store(key, ch)
+	defer approvals.CompareAndDelete(key, ch)
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-ch:
+		return nil
+	}
+}
+
+// Approve 放行等待确认的步骤
+func Approve(taskName, stepName string) error {
+	value, ok := approvals.LoadAndDelete(taskName + "/" + stepName)
+	if !ok {
+		return errors.New("step is not waiting for approval")
+	}
+	close(value.(chan struct{}))
+	return nil
+}
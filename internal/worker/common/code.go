@@ -4,6 +4,7 @@ const (
 	CodeSuccess   int64 = 0
 	CodeFailed    int64 = -1
 	CodeSkipped   int64 = -2
+	CodeOOMKilled int64 = -996
 	CodeKilled    int64 = -997
 	CodeTimeout   int64 = -998
 	CodeSystemErr int64 = -999
@@ -7,4 +7,5 @@ import (
 var (
 	ErrTimeOut = errors.New("forced termination by timeout")
 	ErrManual  = errors.New("artificial force termination")
+	ErrOOM     = errors.New("killed by the out-of-memory killer")
 )
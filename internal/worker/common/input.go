@@ -0,0 +1,103 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckLocal 校验路径为工作空间内的相对路径
+func CheckLocal(kind, name string) error {
+	if name == "" || filepath.IsLocal(name) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s %s, must be a relative path inside the workspace", kind, name)
+}
+
+// Workdir 步骤工作目录, 未指定时为工作空间, 解析符号链接后需位于工作空间或挂载的卷目录内
+func Workdir(workspace, dir string, roots ...string) (string, error) {
+	if dir == "" {
+		return workspace, nil
+	}
+	if err := CheckLocal("workdir", dir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(workspace, dir)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("workdir %s is not a directory", dir)
+	}
+	if _, err = contained("workdir", dir, path, append([]string{workspace}, roots...)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Stdin 步骤标准输入, 文本或工作空间内的文件, 文件与工作目录的限制相同, 均未指定时返回nil
+func Stdin(workspace, text, file string, roots ...string) (io.ReadCloser, error) {
+	if file == "" {
+		if text == "" {
+			return nil, nil
+		}
+		return io.NopCloser(strings.NewReader(text)), nil
+	}
+	real, err := File("stdin_file", workspace, file, roots...)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(real)
+}
+
+// File 工作空间内的文件, 解析符号链接后需位于工作空间或挂载的卷目录内, 返回实际路径
+func File(kind, workspace, name string, roots ...string) (string, error) {
+	if err := CheckLocal(kind, name); err != nil {
+		return "", err
+	}
+	return contained(kind, name, filepath.Join(workspace, name), append([]string{workspace}, roots...))
+}
+
+// Path 工作空间内的路径, 可以不存在, 已存在的部分解析符号链接后需位于工作空间或挂载的卷目录内, 返回拼接后的路径
+func Path(kind, workspace, name string, roots ...string) (string, error) {
+	if err := CheckLocal(kind, name); err != nil {
+		return "", err
+	}
+	path := filepath.Join(workspace, name)
+	// 从最深的已存在的上级目录开始校验
+	existing := path
+	for existing != filepath.Clean(workspace) {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	if _, err := contained(kind, name, existing, append([]string{workspace}, roots...)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// contained 解析符号链接后的实际路径需位于任一根目录内
+func contained(kind, name, path string, roots []string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, real); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("%s %s is outside the workspace", kind, name)
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	workspace, volume, outside := t.TempDir(), t.TempDir(), t.TempDir()
+	for name, target := range map[string]string{"out": outside, "vol": volume} {
+		if err := os.Symlink(target, filepath.Join(workspace, name)); err != nil {
+			t.Skip(err)
+		}
+	}
+	tests := []struct {
+		name string
+		err  bool
+	}{
+		{name: "file.txt"},
+		{name: "new/dir/file.txt"},
+		{name: "vol/file.txt"},
+		{name: "out", err: true},
+		{name: "out/new/file.txt", err: true},
+		{name: "../file.txt", err: true},
+		{name: "/etc/hostname", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Path("path", workspace, tt.name, volume)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if err == nil && path != filepath.Join(workspace, tt.name) {
+				t.Errorf("path = %s", path)
+			}
+		})
+	}
+}
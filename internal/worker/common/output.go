@@ -0,0 +1,36 @@
+package common
+
+import (
+	"bufio"
+	"io"
+)
+
+// MaxLineSize 单行输出的最大长度, 超出部分截断
+const MaxLineSize = 1024 * 1024
+
+// ReadLines 按行读取直到EOF, 超长的行截断后继续读取, 避免写入方因管道写满而阻塞
+func ReadLines(reader io.Reader, fn func(line string)) {
+	r := bufio.NewReaderSize(reader, 64*1024)
+	var line []byte
+	var truncated bool
+	for {
+		part, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		room := MaxLineSize - len(line)
+		if len(part) > room {
+			part, truncated = part[:room], true
+		}
+		line = append(line, part...)
+		if isPrefix {
+			continue
+		}
+		text := string(line)
+		if truncated {
+			text += " ...(truncated)"
+		}
+		fn(text)
+		line, truncated = line[:0], false
+	}
+}
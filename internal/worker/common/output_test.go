@@ -0,0 +1,34 @@
+package common
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("x", MaxLineSize+10)
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "lines", input: "a\r\nb\n\nc", want: []string{"a", "b", "", "c"}},
+		{
+			name:  "long line truncated",
+			input: "a\n" + long + "\nb\n",
+			want:  []string{"a", long[:MaxLineSize] + " ...(truncated)", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			ReadLines(strings.NewReader(tt.input), func(line string) {
+				got = append(got, line)
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lines = %d %.20q, want %d %.20q", len(got), got, len(tt.want), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ITerminal 运行中可附加的步骤终端
+type ITerminal interface {
+	// Attach 附加到终端, output接收实时输出, 返回的会话用于输入与分离
+	Attach(user string, output io.Writer) (ISession, error)
+}
+
+// ISession 附加会话, 写入的内容转发到步骤的标准输入
+type ISession interface {
+	io.Writer
+	Resize(rows, cols uint16) error
+	// Done 步骤结束时关闭
+	Done() <-chan struct{}
+	// Close 分离会话
+	Close() error
+}
+
+// terminals 运行中可附加的步骤终端
+var terminals sync.Map
+
+// RegisterTerminal 注册步骤终端, 返回注销函数
+func RegisterTerminal(taskName, stepName string, t ITerminal) func() {
+	key := taskName + "/" + stepName
+	terminals.Store(key, t)
+	return func() {
+		terminals.CompareAndDelete(key, t)
+	}
+}
+
+// Terminal 获取运行中步骤的终端
+func Terminal(taskName, stepName string) (ITerminal, error) {
+	value, ok := terminals.Load(taskName + "/" + stepName)
+	if !ok {
+		return nil, errors.New("step has no attachable terminal")
+	}
+	return value.(ITerminal), nil
+}
@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+// 决定步骤结果的规则
+const (
+	decisionError            = "error"
+	decisionExitCode         = "exit_code"
+	decisionSuccessCodes     = "success_codes"
+	decisionSkipCodes        = "skip_codes"
+	decisionFailOnPattern    = "fail_on_pattern"
+	decisionSucceedOnPattern = "succeed_on_pattern"
+)
+
+// aborted 超时, 强杀, OOM及系统错误, 结果不由判定规则决定
+func aborted(code int64) bool {
+	switch code {
+	case common.CodeKilled, common.CodeTimeout, common.CodeSystemErr, common.CodeOOMKilled:
+		return true
+	default:
+		return false
+	}
+}
+
+// sCriteria 按退出码及输出判定步骤结果
+type sCriteria struct {
+	*models.SStepCriteria
+	fail    *regexp.Regexp
+	succeed *regexp.Regexp
+
+	mu          sync.Mutex
+	failLine    string
+	succeedLine string
+}
+
+func newCriteria(stg storage.IStep) (*sCriteria, error) {
+	criteria, err := stg.Criteria()
+	if err != nil {
+		return nil, err
+	}
+	c := &sCriteria{SStepCriteria: criteria}
+	if criteria.FailOnPattern != "" {
+		if c.fail, err = regexp.Compile(criteria.FailOnPattern); err != nil {
+			return nil, fmt.Errorf("invalid fail_on_pattern: %w", err)
+		}
+	}
+	if criteria.SucceedOnPattern != "" {
+		if c.succeed, err = regexp.Compile(criteria.SucceedOnPattern); err != nil {
+			return nil, fmt.Errorf("invalid succeed_on_pattern: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// wrap 包装步骤存储, 执行器写入日志时匹配输出
+func (c *sCriteria) wrap(stg storage.IStep) storage.IStep {
+	if c.fail == nil && c.succeed == nil {
+		return stg
+	}
+	return &sMatchStep{
+		IStep: stg,
+		log: &sMatchLog{
+			ILog:     stg.Log(),
+			criteria: c,
+		},
+	}
+}
+
+// match 以原始内容匹配, 记录首个匹配的行脱敏后的内容
+func (c *sCriteria) match(raw, masked string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail != nil && c.failLine == "" && c.fail.MatchString(raw) {
+		c.failLine = masked
+	}
+	if c.succeed != nil && c.succeedLine == "" && c.succeed.MatchString(raw) {
+		c.succeedLine = masked
+	}
+}
+
+// decide 判定结果, 优先级: fail_on_pattern > succeed_on_pattern > skip_codes > success_codes > 退出码0
+func (c *sCriteria) decide(code int64) (state models.State, decision, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.failLine != "":
+		return models.StateFailed, decisionFailOnPattern, fmt.Sprintf("output matched fail_on_pattern: %s", c.failLine)
+	case c.succeedLine != "":
+		return models.StateStopped, decisionSucceedOnPattern, fmt.Sprintf("output matched succeed_on_pattern: %s", c.succeedLine)
+	case slices.Contains(c.SkipCodes, code):
+		return models.StateSkipped, decisionSkipCodes, fmt.Sprintf("skipped due to exit code: %d", code)
+	case len(c.SuccessCodes) > 0:
+		if slices.Contains(c.SuccessCodes, code) {
+			return models.StateStopped, decisionSuccessCodes, "execution succeed"
+		}
+		return models.StateFailed, decisionSuccessCodes, fmt.Sprintf("execution failed with code: %d", code)
+	case code != 0:
+		return models.StateFailed, decisionExitCode, fmt.Sprintf("execution failed with code: %d", code)
+	default:
+		return models.StateStopped, decisionExitCode, "execution succeed"
+	}
+}
+
+type sMatchStep struct {
+	storage.IStep
+	log *sMatchLog
+}
+
+func (s *sMatchStep) Log() storage.ILog {
+	return s.log
+}
+
+type sMatchLog struct {
+	storage.ILog
+	criteria *sCriteria
+}
+
+func (l *sMatchLog) Insert(log *models.SStepLog) error {
+	raw := log.Content
+	err := l.ILog.Insert(log)
+	l.criteria.match(raw, log.Content)
+	return err
+}
+
+func (l *sMatchLog) Write(contents ...string) {
+	if err := l.Insert(&models.SStepLog{
+		Timestamp: time.Now().UnixNano(),
+		Content:   strings.Join(contents, " "),
+	}); err != nil {
+		logx.Warnln(err)
+	}
+}
+
+func (l *sMatchLog) Writef(format string, args ...interface{}) {
+	l.Write(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+func newTestCriteria(criteria models.SStepCriteria) *sCriteria {
+	c := &sCriteria{SStepCriteria: &criteria}
+	if criteria.FailOnPattern != "" {
+		c.fail = regexp.MustCompile(criteria.FailOnPattern)
+	}
+	if criteria.SucceedOnPattern != "" {
+		c.succeed = regexp.MustCompile(criteria.SucceedOnPattern)
+	}
+	return c
+}
+
+func TestCriteriaDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria models.SStepCriteria
+		lines    []string
+		code     int64
+		state    models.State
+		decision string
+	}{
+		{name: "default success", code: 0, state: models.StateStopped, decision: decisionExitCode},
+		{name: "default failure", code: 2, state: models.StateFailed, decision: decisionExitCode},
+		{name: "runner failure", code: common.CodeFailed, state: models.StateFailed, decision: decisionExitCode},
+		{
+			name:     "success codes match",
+			criteria: models.SStepCriteria{SuccessCodes: []int64{0, 1, 7}},
+			code:     7, state: models.StateStopped, decision: decisionSuccessCodes,
+		},
+		{
+			name:     "success codes exclude zero",
+			criteria: models.SStepCriteria{SuccessCodes: []int64{3}},
+			code:     0, state: models.StateFailed, decision: decisionSuccessCodes,
+		},
+		{
+			name:     "skip codes",
+			criteria: models.SStepCriteria{SuccessCodes: []int64{0}, SkipCodes: []int64{5}},
+			code:     5, state: models.StateSkipped, decision: decisionSkipCodes,
+		},
+		{
+			name:     "fail pattern with exit 0",
+			criteria: models.SStepCriteria{FailOnPattern: `^ERROR:`},
+			lines:    []string{"ok", "ERROR: bad"},
+			code:     0, state: models.StateFailed, decision: decisionFailOnPattern,
+		},
+		{
+			name:     "fail pattern wins over succeed pattern",
+			criteria: models.SStepCriteria{FailOnPattern: `ERROR`, SucceedOnPattern: `DONE`},
+			lines:    []string{"DONE", "ERROR"},
+			code:     0, state: models.StateFailed, decision: decisionFailOnPattern,
+		},
+		{
+			name:     "succeed pattern with runner failure",
+			criteria: models.SStepCriteria{SucceedOnPattern: `DONE$`},
+			lines:    []string{"all DONE"},
+			code:     common.CodeFailed, state: models.StateStopped, decision: decisionSucceedOnPattern,
+		},
+		{
+			name:     "succeed pattern wins over skip codes",
+			criteria: models.SStepCriteria{SkipCodes: []int64{1}, SucceedOnPattern: `DONE`},
+			lines:    []string{"DONE"},
+			code:     1, state: models.StateStopped, decision: decisionSucceedOnPattern,
+		},
+		{
+			name:     "pattern not matched",
+			criteria: models.SStepCriteria{FailOnPattern: `^ERROR:`},
+			lines:    []string{"no ERROR: here"},
+			code:     0, state: models.StateStopped, decision: decisionExitCode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCriteria(tt.criteria)
+			for _, line := range tt.lines {
+				c.match(line, line)
+			}
+			state, decision, message := c.decide(tt.code)
+			if state != tt.state || decision != tt.decision {
+				t.Fatalf("decide(%d) = %v, %s, want %v, %s", tt.code, state, decision, tt.state, tt.decision)
+			}
+			if message == "" {
+				t.Fatal("empty message")
+			}
+		})
+	}
+}
+
+func TestCriteriaMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    string
+		succeed string
+		lines   [][2]string
+		failed  string
+		success string
+	}{
+		{
+			name:   "first match is kept",
+			fail:   `ERROR`,
+			lines:  [][2]string{{"ERROR one", "ERROR one"}, {"ERROR two", "ERROR two"}},
+			failed: "ERROR one",
+		},
+		{
+			name:    "raw content is matched, masked content is recorded",
+			succeed: `token=s3cr3t`,
+			lines:   [][2]string{{"token=s3cr3t", "token=***"}},
+			success: "token=***",
+		},
+		{
+			name:    "both patterns on one line",
+			fail:    `WARN`,
+			succeed: `DONE`,
+			lines:   [][2]string{{"DONE with WARN", "DONE with WARN"}},
+			failed:  "DONE with WARN",
+			success: "DONE with WARN",
+		},
+		{
+			name:  "no patterns",
+			lines: [][2]string{{"ERROR", "ERROR"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCriteria(models.SStepCriteria{FailOnPattern: tt.fail, SucceedOnPattern: tt.succeed})
+			for _, line := range tt.lines {
+				c.match(line[0], line[1])
+			}
+			if c.failLine != tt.failed || c.succeedLine != tt.success {
+				t.Fatalf("match = %q, %q, want %q, %q", c.failLine, c.succeedLine, tt.failed, tt.success)
+			}
+		})
+	}
+}
+
+func TestAborted(t *testing.T) {
+	for _, code := range []int64{common.CodeKilled, common.CodeTimeout, common.CodeSystemErr, common.CodeOOMKilled} {
+		if !aborted(code) {
+			t.Fatalf("aborted(%d) = false", code)
+		}
+	}
+	for _, code := range []int64{common.CodeSuccess, common.CodeFailed, 1, 255} {
+		if aborted(code) {
+			t.Fatalf("aborted(%d) = true", code)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package interpreter
+
+// portable 各系统通用的内置解释器
+func portable() []*SInterpreter {
+	var python = map[string]string{"PYTHONUNBUFFERED": "1"}
+	return []*SInterpreter{
+		{Name: "python", Aliases: []string{"python2", "py", "py2"}, Command: []string{"python2"}, Suffix: ".py", Env: python, Check: []string{"python2", "--version"}},
+		{Name: "python3", Aliases: []string{"py3"}, Command: []string{"python3"}, Suffix: ".py", Env: python, Check: []string{"python3", "--version"}},
+		{Name: "python-versioned", Pattern: `^python[23]\.\d+$`, Command: []string{placeholderType}, Suffix: ".py", Env: python, Check: []string{placeholderType, "--version"}},
+		{Name: "node", Aliases: []string{"nodejs"}, Command: []string{"node"}, Suffix: ".js", Check: []string{"node", "--version"}},
+		{Name: "perl", Command: []string{"perl"}, Suffix: ".pl", Check: []string{"perl", "-e", `print "$^V\n"`}},
+		{Name: "ruby", Command: []string{"ruby"}, Suffix: ".rb", Check: []string{"ruby", "--version"}},
+		{Name: "pwsh", Command: []string{"pwsh", "-NoLogo", "-NonInteractive", "-NoProfile", "-File"}, Suffix: ".ps1", Check: []string{"pwsh", "--version"}},
+	}
+}
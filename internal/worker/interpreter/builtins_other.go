@@ -0,0 +1,22 @@
+//go:build !windows
+
+package interpreter
+
+func builtins() []*SInterpreter {
+	var list = []*SInterpreter{
+		// -o pipefail 管道中最后一个返回非零退出状态码的命令的退出状态码将作为该管道命令的返回值，若所有命令的退出状态码都为零则返回零
+		{Name: "bash", Command: []string{"bash", "-o", "pipefail"}, Suffix: ".bash"},
+		{Name: "sh", Aliases: []string{"shell"}, Command: []string{"sh"}, Suffix: ".sh"},
+		{Name: "ash", Command: []string{"ash"}, Suffix: ".ash"},
+		{Name: "dash", Command: []string{"dash"}, Suffix: ".dash"},
+		{Name: "ksh", Command: []string{"ksh"}, Suffix: ".ksh"},
+		{Name: "csh", Command: []string{"csh"}, Suffix: ".csh"},
+		{Name: "tcsh", Command: []string{"tcsh"}, Suffix: ".tcsh"},
+		{Name: "zsh", Command: []string{"zsh"}, Suffix: ".zsh"},
+	}
+	list = append(list, portable()...)
+	for _, it := range list {
+		_ = it.init()
+	}
+	return list
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package interpreter
+
+func builtins() []*SInterpreter {
+	var list = []*SInterpreter{
+		{Name: "cmd", Aliases: []string{"bat"}, Command: []string{"cmd", "/D", "/E:ON", "/V:OFF", "/Q", "/S", "/C"}, Suffix: ".bat", Encoding: "gbk"},
+		// 解决用户不写exit时, powershell进程外获取不到退出码
+		{
+			Name:     "powershell",
+			Aliases:  []string{"ps", "ps1"},
+			Command:  []string{"powershell", "-NoLogo", "-NonInteractive", "-Command", "$ErrorActionPreference='Continue';" + placeholderScript + " " + placeholderArgs + ";exit $LASTEXITCODE"},
+			Suffix:   ".ps1",
+			Encoding: "gbk",
+		},
+	}
+	list = append(list, portable()...)
+	for _, it := range list {
+		_ = it.init()
+	}
+	return list
+}
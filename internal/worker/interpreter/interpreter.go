@@ -0,0 +1,383 @@
+package interpreter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	placeholderScript = "{{script}}" // 脚本路径
+	placeholderType   = "{{type}}"   // 步骤类型, 用于pattern匹配的版本化解释器
+	placeholderArgs   = "{{args}}"   // 步骤参数, 未包含时追加在末尾
+
+	// Auto 根据内容首行的shebang选择解释器
+	Auto = "auto"
+)
+
+// SInterpreter 解释器定义, 步骤类型匹配name, aliases或pattern时使用
+type SInterpreter struct {
+	Name     string            `json:"name" yaml:"name"`
+	Aliases  []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Pattern  string            `json:"pattern,omitempty" yaml:"pattern,omitempty"`   // 正则, 匹配版本化的类型, 如 ^python3\.\d+$
+	Command  []string          `json:"command" yaml:"command"`                       // 命令行, {{script}}为脚本路径, 未包含时追加在末尾
+	Suffix   string            `json:"suffix" yaml:"suffix"`                         // 脚本文件后缀
+	Encoding string            `json:"encoding,omitempty" yaml:"encoding,omitempty"` // 脚本与输出的编码, 如gbk, 默认utf-8
+	Env      map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Check    []string          `json:"check,omitempty" yaml:"check,omitempty"`       // 可用性检查命令, 默认检查命令是否在PATH中
+	Shebang  []string          `json:"shebang,omitempty" yaml:"shebang,omitempty"`   // shebang中匹配的程序名, 默认为name与aliases
+	Disabled bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"` // 禁用同名的内置解释器
+
+	pattern *regexp.Regexp
+	enc     encoding.Encoding
+}
+
+type sConfig struct {
+	Interpreters []*SInterpreter `json:"interpreters" yaml:"interpreters"`
+}
+
+type sCheck struct {
+	at      time.Time
+	version string
+	err     error
+}
+
+var (
+	mu       sync.RWMutex
+	registry = builtins()
+
+	checks sync.Map // 可用性检查结果缓存, key为命令行
+)
+
+const checkTTL = time.Minute
+
+// Load 加载内置解释器, file不为空时按yaml或json读取, 同名的定义覆盖内置定义
+func Load(file string) error {
+	var list = builtins()
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var conf sConfig
+		if err = yaml.Unmarshal(content, &conf); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, it := range conf.Interpreters {
+			if it.Name == "" {
+				return fmt.Errorf("%s: interpreter name is required", file)
+			}
+			list = slices.DeleteFunc(list, func(v *SInterpreter) bool {
+				return v.Name == it.Name
+			})
+			if !it.Disabled {
+				list = append(list, it)
+			}
+		}
+	}
+	for _, it := range list {
+		if err := it.init(); err != nil {
+			return fmt.Errorf("interpreter %s: %w", it.Name, err)
+		}
+	}
+	mu.Lock()
+	registry = list
+	mu.Unlock()
+	return nil
+}
+
+func (it *SInterpreter) init() error {
+	if len(it.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+	if it.Pattern != "" {
+		var err error
+		if it.pattern, err = regexp.Compile(it.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if it.Encoding != "" && !strings.EqualFold(it.Encoding, "utf-8") && !strings.EqualFold(it.Encoding, "utf8") {
+		var err error
+		if it.enc, err = htmlindex.Get(it.Encoding); err != nil {
+			return fmt.Errorf("invalid encoding %s", it.Encoding)
+		}
+	}
+	return nil
+}
+
+// Lookup 按步骤类型查找解释器, 返回的副本中{{type}}已替换
+func Lookup(typ string) (*SInterpreter, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, it := range registry {
+		if it.Name == typ || slices.Contains(it.Aliases, typ) {
+			return it.resolve(typ), nil
+		}
+	}
+	for _, it := range registry {
+		if it.pattern != nil && it.pattern.MatchString(typ) {
+			return it.resolve(typ), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
+
+// Detect 按内容首行的shebang查找解释器, 未注册但shebang指向的程序存在时直接执行脚本
+func Detect(content string) (*SInterpreter, error) {
+	line, _, _ := strings.Cut(content, "\n")
+	line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+	if !strings.HasPrefix(line, "#!") {
+		return nil, fmt.Errorf("type %s requires a shebang line", Auto)
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid shebang %s", line)
+	}
+	name := filepath.Base(fields[0])
+	if name == "env" {
+		// #!/usr/bin/env [-S] [NAME=VALUE] program
+		name = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			name = filepath.Base(field)
+			break
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid shebang %s", line)
+		}
+	}
+
+	mu.RLock()
+	for _, it := range registry {
+		shebang := it.Shebang
+		if len(shebang) == 0 {
+			shebang = append([]string{it.Name}, it.Aliases...)
+		}
+		if slices.Contains(shebang, name) {
+			mu.RUnlock()
+			return it.resolve(name), nil
+		}
+	}
+	for _, it := range registry {
+		if it.pattern != nil && it.pattern.MatchString(name) {
+			mu.RUnlock()
+			return it.resolve(name), nil
+		}
+	}
+	mu.RUnlock()
+
+	// 由系统按shebang执行
+	if _, err := os.Stat(fields[0]); err != nil {
+		return nil, fmt.Errorf("unsupported shebang %s", line)
+	}
+	it := &SInterpreter{Name: name, Command: []string{placeholderScript}}
+	return it, nil
+}
+
+func (it *SInterpreter) resolve(typ string) *SInterpreter {
+	var res = *it
+	res.Name = typ
+	res.Command = replace(it.Command, typ)
+	res.Check = replace(it.Check, typ)
+	return &res
+}
+
+func replace(args []string, typ string) []string {
+	if args == nil {
+		return nil
+	}
+	var res = make([]string, len(args))
+	for i, arg := range args {
+		res[i] = strings.ReplaceAll(arg, placeholderType, typ)
+	}
+	return res
+}
+
+// Args 命令行参数, 脚本路径替换{{script}}, 步骤参数替换{{args}}, 未包含时依次追加在末尾
+func (it *SInterpreter) Args(script string, extra ...string) []string {
+	var args = make([]string, 0, len(it.Command)+len(extra)+1)
+	var foundScript, foundArgs bool
+	for _, arg := range it.Command {
+		if arg == placeholderArgs {
+			foundArgs = true
+			args = append(args, extra...)
+			continue
+		}
+		if strings.Contains(arg, placeholderArgs) {
+			// 嵌入在命令字符串中时以单引号转义, 如powershell -Command
+			foundArgs = true
+			var quoted = make([]string, 0, len(extra))
+			for _, v := range extra {
+				quoted = append(quoted, "'"+strings.ReplaceAll(v, "'", "''")+"'")
+			}
+			arg = strings.ReplaceAll(arg, placeholderArgs, strings.Join(quoted, " "))
+		}
+		if strings.Contains(arg, placeholderScript) {
+			foundScript = true
+			arg = strings.ReplaceAll(arg, placeholderScript, script)
+		}
+		args = append(args, arg)
+	}
+	if !foundScript {
+		args = append(args, script)
+	}
+	if !foundArgs {
+		args = append(args, extra...)
+	}
+	return args
+}
+
+// Environ 解释器的环境变量, 格式为NAME=VALUE
+func (it *SInterpreter) Environ() []string {
+	var envs = make([]string, 0, len(it.Env))
+	for name, value := range it.Env {
+		envs = append(envs, name+"="+value)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// Encode 将脚本内容转换为解释器的编码
+func (it *SInterpreter) Encode(content string) string {
+	if it.enc == nil {
+		return content
+	}
+	res, err := it.enc.NewEncoder().String(content)
+	if err != nil {
+		return content
+	}
+	return res
+}
+
+// Decode 将解释器的输出转换为utf-8
+func (it *SInterpreter) Decode(line string) string {
+	if it.enc == nil {
+		return line
+	}
+	res, err := it.enc.NewDecoder().String(line)
+	if err != nil {
+		return line
+	}
+	return res
+}
+
+// HasEncoding 是否指定了非utf-8编码
+func (it *SInterpreter) HasEncoding() bool {
+	return it.enc != nil
+}
+
+// Available 检查解释器是否可用, 返回检查命令输出的首行作为版本, 结果缓存一分钟
+func (it *SInterpreter) Available() (string, error) {
+	key := strings.Join(it.Command, "\x00") + "\x01" + strings.Join(it.Check, "\x00")
+	if v, ok := checks.Load(key); ok && time.Since(v.(*sCheck).at) < checkTTL {
+		return v.(*sCheck).version, v.(*sCheck).err
+	}
+	var res = &sCheck{at: time.Now()}
+	res.version, res.err = it.check()
+	checks.Store(key, res)
+	return res.version, res.err
+}
+
+func (it *SInterpreter) check() (string, error) {
+	if it.Command[0] == placeholderScript {
+		return "", nil
+	}
+	if _, err := exec.LookPath(it.Command[0]); err != nil {
+		return "", err
+	}
+	if len(it.Check) == 0 {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, it.Check[0], it.Check[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", strings.Join(it.Check, " "), err)
+	}
+	line, _ := bufio.NewReader(bytes.NewReader(output)).ReadString('\n')
+	return strings.TrimSpace(line), nil
+}
+
+// SStatus 解释器在当前节点的状态
+type SStatus struct {
+	Type      string
+	Aliases   []string
+	Command   []string
+	Suffix    string
+	Encoding  string
+	Version   string
+	Available bool
+	Error     string
+}
+
+// List 列出当前节点的解释器, pattern类型按PATH中匹配的程序展开
+func List() []*SStatus {
+	mu.RLock()
+	var list = slices.Clone(registry)
+	mu.RUnlock()
+
+	var res []*SStatus
+	for _, it := range list {
+		if it.pattern == nil {
+			res = append(res, it.status(it.Name))
+			continue
+		}
+		for _, name := range it.discover() {
+			res = append(res, it.resolve(name).status(name))
+		}
+	}
+	return res
+}
+
+func (it *SInterpreter) status(typ string) *SStatus {
+	version, err := it.Available()
+	res := &SStatus{
+		Type:      typ,
+		Aliases:   it.Aliases,
+		Command:   it.Command,
+		Suffix:    it.Suffix,
+		Encoding:  it.Encoding,
+		Version:   version,
+		Available: err == nil,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// discover 在PATH中查找匹配pattern的程序
+func (it *SInterpreter) discover() []string {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".exe")
+			if entry.IsDir() || !it.pattern.MatchString(name) || slices.Contains(names, name) {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,341 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmapst/logx"
+
+	sdk "github.com/xmapst/AutoExecFlow/pkg/plugin"
+)
+
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateExited   = "exited"
+
+	scanInterval   = 30 * time.Second
+	connectTimeout = 30 * time.Second
+	maxBackoff     = time.Minute
+)
+
+// sPlugin 插件目录中的一个可执行文件, 由supervise启动并在退出后重启
+type sPlugin struct {
+	name    string
+	path    string
+	socket  string
+	modTime time.Time
+	cancel  context.CancelFunc
+
+	mu        sync.RWMutex
+	client    *sdk.SClient
+	info      *sdk.SInfo
+	state     string
+	pid       int
+	restarts  int
+	err       error
+	startedAt time.Time
+}
+
+// SStatus 插件在当前节点的状态
+type SStatus struct {
+	Name        string
+	Path        string
+	Version     string
+	Description string
+	Types       []string
+	State       string
+	Pid         int
+	Restarts    int
+	Error       string
+	StartedAt   time.Time
+}
+
+var (
+	mu      sync.RWMutex
+	plugins = map[string]*sPlugin{} // key为插件路径
+)
+
+// Start 启动插件目录中的插件, 并定期扫描目录发现新增, 删除或更新的插件
+func Start(ctx context.Context, dir string) error {
+	sockets := filepath.Join(dir, ".sock")
+	if err := os.MkdirAll(sockets, os.ModePerm); err != nil {
+		return err
+	}
+	scan(ctx, dir, sockets)
+	go func() {
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				scan(ctx, dir, sockets)
+			}
+		}
+	}()
+	return nil
+}
+
+func scan(ctx context.Context, dir, sockets string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logx.Errorln("plugin scan", dir, err)
+		return
+	}
+	var found = make(map[string]time.Time)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !executable(info) {
+			continue
+		}
+		found[filepath.Join(dir, entry.Name())] = info.ModTime()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for path, p := range plugins {
+		if modTime, ok := found[path]; ok && modTime.Equal(p.modTime) {
+			continue
+		}
+		// 删除或更新的插件先停止, 更新的插件随后重新启动
+		logx.Infoln("plugin stop", p.name, path)
+		p.cancel()
+		delete(plugins, path)
+	}
+	for path, modTime := range found {
+		if _, ok := plugins[path]; ok {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		p := &sPlugin{
+			name:    name,
+			path:    path,
+			socket:  filepath.Join(sockets, name+".sock"),
+			modTime: modTime,
+			state:   StateStarting,
+		}
+		var pctx context.Context
+		pctx, p.cancel = context.WithCancel(ctx)
+		plugins[path] = p
+		logx.Infoln("plugin found", name, path)
+		go p.supervise(pctx)
+	}
+}
+
+func executable(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+	}
+	return info.Mode().Perm()&0111 != 0
+}
+
+// supervise 运行插件, 异常退出后按指数退避重启
+func (p *sPlugin) supervise(ctx context.Context) {
+	var backoff = time.Second
+	for {
+		started := time.Now()
+		err := p.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logx.Errorln("plugin exited", p.name, err)
+		if time.Since(started) > maxBackoff {
+			backoff = time.Second
+		}
+		p.mu.Lock()
+		p.state, p.err, p.restarts = StateExited, err, p.restarts+1
+		p.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (p *sPlugin) run(ctx context.Context) error {
+	if err := os.Remove(p.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Dir = filepath.Dir(p.path)
+	cmd.Env = append(os.Environ(), sdk.EnvSocket+"="+p.socket)
+	cmd.Stdout = &sLogWriter{name: p.name}
+	cmd.Stderr = cmd.Stdout
+	// 先发送中断信号, 插件未及时退出再强制结束
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = 10 * time.Second
+
+	p.mu.Lock()
+	p.state = StateStarting
+	p.mu.Unlock()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	client, info, err := p.connect(ctx, exited)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-exited
+		return err
+	}
+	defer client.Close()
+	logx.Infoln("plugin running", p.name, info.Version, info.Types)
+
+	p.mu.Lock()
+	p.client, p.info, p.state, p.err = client, info, StateRunning, nil
+	p.pid, p.startedAt = cmd.Process.Pid, time.Now()
+	p.mu.Unlock()
+
+	err = <-exited
+	p.mu.Lock()
+	p.client, p.pid = nil, 0
+	p.mu.Unlock()
+	if err == nil {
+		err = errors.New("exit status 0")
+	}
+	return err
+}
+
+// connect 等待插件监听socket并获取插件信息
+func (p *sPlugin) connect(ctx context.Context, exited chan error) (*sdk.SClient, *sdk.SInfo, error) {
+	client, err := sdk.Dial(p.socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err = <-exited:
+			_ = client.Close()
+			exited <- err
+			return nil, nil, fmt.Errorf("exited before serving: %v", err)
+		case <-ctx.Done():
+			_ = client.Close()
+			return nil, nil, fmt.Errorf("not serving on %s: %w", p.socket, ctx.Err())
+		case <-ticker.C:
+		}
+		if _, err = os.Stat(p.socket); err != nil {
+			continue
+		}
+		info, err := client.Info(ctx)
+		if err != nil {
+			continue
+		}
+		if len(info.Types) == 0 {
+			_ = client.Close()
+			return nil, nil, errors.New("no step types declared")
+		}
+		return client, info, nil
+	}
+}
+
+// Lookup 查找处理步骤类型的插件, 多个插件声明同一类型时优先运行中的插件, 再按名称排序取第一个,
+// 重启中的插件仍按上次声明的类型匹配, 此时client为nil
+func Lookup(typ string) (name string, client *sdk.SClient, ok bool) {
+	mu.RLock()
+	var list = make([]*sPlugin, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p)
+	}
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	for _, p := range list {
+		p.mu.RLock()
+		c, info := p.client, p.info
+		p.mu.RUnlock()
+		if info == nil || !slices.ContainsFunc(info.Types, func(t string) bool {
+			return strings.EqualFold(t, typ)
+		}) {
+			continue
+		}
+		if c != nil {
+			return p.name, c, true
+		}
+		if !ok {
+			name, ok = p.name, true
+		}
+	}
+	return name, nil, ok
+}
+
+// List 当前节点的插件状态
+func List() []*SStatus {
+	mu.RLock()
+	var res = make([]*SStatus, 0, len(plugins))
+	for _, p := range plugins {
+		res = append(res, p.status())
+	}
+	mu.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func (p *sPlugin) status() *SStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res := &SStatus{
+		Name:     p.name,
+		Path:     p.path,
+		State:    p.state,
+		Pid:      p.pid,
+		Restarts: p.restarts,
+	}
+	if p.info != nil {
+		res.Version, res.Description, res.Types = p.info.Version, p.info.Description, p.info.Types
+	}
+	if p.state == StateRunning {
+		res.StartedAt = p.startedAt
+	}
+	if p.err != nil {
+		res.Error = p.err.Error()
+	}
+	return res
+}
+
+// sLogWriter 插件的标准输出与标准错误写入服务日志
+type sLogWriter struct {
+	name string
+}
+
+func (w *sLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		logx.Infoln("plugin", w.name, strings.TrimRight(line, "\r"))
+	}
+	return len(p), nil
+}
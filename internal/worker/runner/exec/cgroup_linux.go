@@ -0,0 +1,244 @@
+//go:build linux
+
+package exec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// sCgroup 步骤独占的cgroup v2, 用于资源限制, 统计及清理所有子孙进程
+type sCgroup struct {
+	path string
+	dir  *os.File
+}
+
+// newCgroup cgroup v2可用时为步骤创建独立cgroup, 仅写入设置了的资源限制
+func (c *SCmd) newCgroup() (*sCgroup, error) {
+	resources, err := c.storage.Resources()
+	if err != nil {
+		return nil, err
+	}
+	if !utils.FileOrPathExist(filepath.Join(cgroupRoot, "cgroup.controllers")) {
+		if !resources.IsEmpty() {
+			c.storage.Log().Write("cgroup v2 is not available, resource limits are ignored")
+		}
+		return nil, nil
+	}
+	cg, err := c.createCgroup(resources)
+	if err != nil && resources.IsEmpty() {
+		// 未设置资源限制时cgroup仅用于统计及清理子孙进程, 创建失败(如无权限)不影响执行
+		logx.Debugln("create cgroup", c.storage.TaskName(), c.storage.Name(), err)
+		return nil, nil
+	}
+	return cg, err
+}
+
+func (c *SCmd) createCgroup(resources *models.SStepResources) (*sCgroup, error) {
+	parent := filepath.Join(cgroupRoot, utils.PathEscape(config.App.CgroupParent))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+	var controllers []string
+	if resources.CPU > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if resources.Memory > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if resources.Pids > 0 {
+		controllers = append(controllers, "pids")
+	}
+	if resources.IOWeight > 0 {
+		controllers = append(controllers, "io")
+	}
+	if err := cgroupEnableControllers(parent, controllers); err != nil {
+		return nil, err
+	}
+
+	cg := &sCgroup{
+		path: filepath.Join(parent, fmt.Sprintf("%s-%s-%s", c.storage.TaskName(), c.storage.Name(), ksuid.New().String())),
+	}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+	if err := cg.limit(resources); err != nil {
+		cg.remove()
+		return nil, err
+	}
+	var err error
+	cg.dir, err = os.Open(cg.path)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	return cg, nil
+}
+
+// cgroupEnableControllers 从根开始逐级开启子树控制器
+func cgroupEnableControllers(parent string, controllers []string) error {
+	rel, err := filepath.Rel(cgroupRoot, parent)
+	if err != nil {
+		return err
+	}
+	var value []string
+	for _, controller := range controllers {
+		value = append(value, "+"+controller)
+	}
+	dir := cgroupRoot
+	for _, elem := range append([]string{""}, strings.Split(rel, string(filepath.Separator))...) {
+		dir = filepath.Join(dir, elem)
+		if err = os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(strings.Join(value, " ")), 0644); err != nil {
+			logx.Debugln("enable cgroup controllers", dir, err)
+		}
+	}
+	enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(enabled))
+	for _, controller := range controllers {
+		var found bool
+		for _, field := range fields {
+			if field == controller {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("cgroup controller %s is not available in %s", controller, parent)
+		}
+	}
+	return nil
+}
+
+func (cg *sCgroup) write(name, value string) error {
+	return os.WriteFile(filepath.Join(cg.path, name), []byte(value), 0644)
+}
+
+func (cg *sCgroup) limit(resources *models.SStepResources) error {
+	if resources.CPU > 0 {
+		const period = 100000
+		if err := cg.write("cpu.max", fmt.Sprintf("%d %d", int64(resources.CPU*period), period)); err != nil {
+			return err
+		}
+	}
+	if resources.Memory > 0 {
+		if err := cg.write("memory.max", strconv.FormatInt(resources.Memory, 10)); err != nil {
+			return err
+		}
+		// 禁止使用swap, 避免达到上限后长时间卡顿而不是被OOM
+		_ = cg.write("memory.swap.max", "0")
+	}
+	if resources.Pids > 0 {
+		if err := cg.write("pids.max", strconv.FormatInt(resources.Pids, 10)); err != nil {
+			return err
+		}
+	}
+	if resources.IOWeight > 0 {
+		if err := cg.write("io.weight", fmt.Sprintf("default %d", resources.IOWeight)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attach 进程创建时直接放入cgroup, 其所有子孙进程都会继承
+func (cg *sCgroup) attach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.dir.Fd())
+}
+
+// kill 杀死cgroup内所有进程
+func (cg *sCgroup) kill() error {
+	if err := cg.write("cgroup.kill", "1"); err == nil {
+		return nil
+	}
+	// 内核低于5.14不支持cgroup.kill
+	procs, err := os.ReadFile(filepath.Join(cg.path, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for _, field := range strings.Fields(string(procs)) {
+		pid, _err := strconv.Atoi(field)
+		if _err != nil {
+			continue
+		}
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	}
+	return nil
+}
+
+func (cg *sCgroup) stat(name, key string) (int64, bool) {
+	file, err := os.Open(filepath.Join(cg.path, name))
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, err := strconv.ParseInt(fields[1], 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+// usage 内存峰值(字节)及CPU时间(微秒)
+func (cg *sCgroup) usage() (memPeak *int64, cpuTime *int64) {
+	if data, err := os.ReadFile(filepath.Join(cg.path, "memory.peak")); err == nil {
+		if value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			memPeak = &value
+		}
+	}
+	if value, ok := cg.stat("cpu.stat", "usage_usec"); ok {
+		cpuTime = &value
+	}
+	return
+}
+
+func (cg *sCgroup) oomKilled() bool {
+	value, ok := cg.stat("memory.events", "oom_kill")
+	return ok && value > 0
+}
+
+func (cg *sCgroup) remove() {
+	if cg.dir != nil {
+		_ = cg.dir.Close()
+	}
+	// 进程退出需要时间, cgroup非空时无法删除
+	for i := 0; i < 50; i++ {
+		err := os.Remove(cg.path)
+		if err == nil || os.IsNotExist(err) {
+			return
+		}
+		if !errors.Is(err, syscall.EBUSY) {
+			logx.Warnln("remove cgroup", cg.path, err)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	logx.Warnln("remove cgroup", cg.path, "timeout")
+}
@@ -0,0 +1,36 @@
+//go:build !linux && !windows
+
+package exec
+
+import (
+	"os/exec"
+)
+
+type sCgroup struct{}
+
+func (c *SCmd) newCgroup() (*sCgroup, error) {
+	resources, err := c.storage.Resources()
+	if err != nil {
+		return nil, err
+	}
+	if !resources.IsEmpty() {
+		c.storage.Log().Write("resource limits are only supported on linux with cgroup v2, ignored")
+	}
+	return nil, nil
+}
+
+func (cg *sCgroup) attach(*exec.Cmd) {}
+
+func (cg *sCgroup) kill() error {
+	return nil
+}
+
+func (cg *sCgroup) usage() (memPeak *int64, cpuTime *int64) {
+	return nil, nil
+}
+
+func (cg *sCgroup) oomKilled() bool {
+	return false
+}
+
+func (cg *sCgroup) remove() {}
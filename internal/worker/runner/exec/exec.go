@@ -8,16 +8,25 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/segmentio/ksuid"
 	"github.com/xmapst/logx"
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
 	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/interpreter"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 )
 
+var envNameReg = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type SCmd struct {
 	storage storage.IStep
 
@@ -26,7 +35,12 @@ type SCmd struct {
 	envPath string
 
 	shell      string
+	interp     *interpreter.SInterpreter
+	pipe       bool // 标准输出与标准错误分别通过管道读取, 不使用pty
 	workspace  string
+	workdir    string
+	args       []string
+	stdin      io.ReadCloser
 	scriptName string
 	timeout    time.Duration
 }
@@ -39,39 +53,67 @@ func New(storage storage.IStep,
 		workspace: workspace,
 		shell:     shell,
 	}
-	c.ctx, c.cancel = context.WithCancel(context.Background())
-	c.scriptName = filepath.Join(scriptDir, ksuid.New().String())
-	c.scriptName = c.scriptName + c.scriptSuffix()
-	if err := os.MkdirAll(scriptDir, os.ModePerm); err != nil {
-		return nil, err
+	// 类型为 <shell>@pipe 时使用管道模式
+	if name, mode, found := strings.Cut(shell, "@"); found {
+		if mode != "pipe" {
+			return nil, fmt.Errorf("unsupported exec mode %s", mode)
+		}
+		c.shell, c.pipe = name, true
 	}
 	content, err := storage.Content()
 	if err != nil {
 		return nil, err
 	}
-	if c.shell == "cmd" || c.shell == "powershell" {
-		content = c.utf8ToGb2312(content)
+	// 类型为auto时根据shebang选择解释器
+	if c.shell == interpreter.Auto {
+		c.interp, err = interpreter.Detect(content)
+	} else {
+		c.interp, err = interpreter.Lookup(c.shell)
 	}
-	if err = os.WriteFile(c.scriptName, []byte(content), os.ModePerm); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	return c, nil
-}
-
-func (c *SCmd) scriptSuffix() string {
-	switch c.shell {
-	case "python", "python2", "python3", "py", "py2", "py3":
-		return ".py"
+	if _, err = c.interp.Available(); err != nil {
+		return nil, fmt.Errorf("interpreter %s is not available: %w", c.interp.Name, err)
+	}
+	input, err := storage.Input()
+	if err != nil {
+		return nil, err
+	}
+	roots := volume.Roots(storage.TaskName())
+	if c.workdir, err = common.Workdir(workspace, input.Workdir, roots...); err != nil {
+		return nil, err
+	}
+	c.args = input.Args
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.scriptName = filepath.Join(scriptDir, ksuid.New().String()) + c.interp.Suffix
+	if err = os.MkdirAll(scriptDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(c.scriptName, []byte(c.interp.Encode(content)), os.ModePerm); err != nil {
+		return nil, err
+	}
+	// 步骤写入该文件的变量在结束后导出为任务级环境变量
+	c.envPath = strings.TrimSuffix(c.scriptName, c.interp.Suffix) + ".env"
+	if err = os.WriteFile(c.envPath, nil, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if c.stdin, err = common.Stdin(workspace, input.Stdin, input.StdinFile, roots...); err != nil {
+		return nil, err
 	}
-	return c.selfScriptSuffix()
+	return c, nil
 }
 
 func (c *SCmd) Clear() error {
+	if c.stdin != nil {
+		_ = c.stdin.Close()
+	}
+	_ = os.Remove(c.envPath)
 	return os.Remove(c.scriptName)
 }
 
-func (c *SCmd) envs() []string {
-	var envs []string
+func (c *SCmd) envs(extra ...string) []string {
+	var envs = extra
 	taskEnv := c.storage.GlobalEnv().List()
 	for _, env := range taskEnv {
 		envs = append(envs, fmt.Sprintf("%s=%s", env.Name, env.Value))
@@ -86,17 +128,53 @@ func (c *SCmd) envs() []string {
 		fmt.Sprintf("TASK_NAME=%s", c.storage.TaskName()),
 		fmt.Sprintf("TASK_STEP_NAME=%s", c.storage.Name()),
 		fmt.Sprintf("TASK_WORKSPACE=%s", c.workspace),
+		fmt.Sprintf("TASK_ENV=%s", c.envPath),
 	)...)
 }
 
-func (c *SCmd) parseEnvFileFromFile() {
+// exportEnv 解析环境变量文件, 按步骤保存, 之后启动的步骤合并到全局环境变量中
+func (c *SCmd) exportEnv() {
+	envs, err := c.parseEnvFileFromFile()
+	if err != nil {
+		c.storage.Log().Write("export env failed:", err.Error())
+		return
+	}
+	if len(envs) == 0 {
+		return
+	}
+	if err = c.storage.Export().Insert(envs...); err != nil {
+		c.storage.Log().Write("export env failed:", err.Error())
+		return
+	}
+	var names []string
+	for _, env := range envs {
+		names = append(names, env.Name)
+	}
+	c.storage.Log().Write("exported env", strings.Join(names, ", "))
+}
+
+func (c *SCmd) parseEnvFileFromFile() (envs models.SEnvs, err error) {
 	// 打开源文件
 	file, err := os.Open(c.envPath)
 	if err != nil {
-		logx.Warnln(err)
-		return
+		return nil, err
 	}
 	defer file.Close()
+	// 同名变量以最后一次写入为准
+	var index = make(map[string]int)
+	var add = func(name, value string) error {
+		name = strings.TrimSpace(name)
+		if !envNameReg.MatchString(name) {
+			return fmt.Errorf("invalid env name '%v'", name)
+		}
+		if i, ok := index[name]; ok {
+			envs[i].Value = value
+			return nil
+		}
+		index[name] = len(envs)
+		envs = append(envs, &models.SEnv{Name: name, Value: value})
+		return nil
+	}
 	s := bufio.NewScanner(file)
 	firstLine := true
 	for s.Scan() {
@@ -113,9 +191,13 @@ func (c *SCmd) parseEnvFileFromFile() {
 		// 处理单行和多行环境变量
 		singleLineEnv := strings.Index(line, "=")
 		multiLineEnv := strings.Index(line, "<<")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 		if singleLineEnv != -1 && (multiLineEnv == -1 || singleLineEnv < multiLineEnv) {
-			// TODO: write to storage
-			logx.Debugf("parsed env: %v=%v", line[:singleLineEnv], line[singleLineEnv+1:])
+			if err = add(line[:singleLineEnv], line[singleLineEnv+1:]); err != nil {
+				return nil, err
+			}
 		} else if multiLineEnv != -1 {
 			multiLineEnvContent := ""
 			multiLineEnvDelimiter := line[multiLineEnv+2:]
@@ -132,21 +214,20 @@ func (c *SCmd) parseEnvFileFromFile() {
 				multiLineEnvContent += content
 			}
 			if !delimiterFound {
-				logx.Errorf("invalid format delimiter '%v' not found before end of file", multiLineEnvDelimiter)
-				return
+				return nil, fmt.Errorf("invalid format delimiter '%v' not found before end of file", multiLineEnvDelimiter)
+			}
+			if err = add(line[:multiLineEnv], multiLineEnvContent); err != nil {
+				return nil, err
 			}
-			// TODO: write to storage
-			logx.Debugf("parsed env: %v=%v", line[:multiLineEnv], multiLineEnvContent)
 		} else {
-			logx.Errorf("invalid format '%v', expected a line with '=' or '<<'", line)
-			return
+			return nil, fmt.Errorf("invalid format '%v', expected a line with '=' or '<<'", line)
 		}
 	}
 
 	if err = s.Err(); err != nil {
-		logx.Errorf("error reading file: %v", err)
-		return
+		return nil, fmt.Errorf("error reading file: %v", err)
 	}
+	return envs, nil
 }
 
 func (c *SCmd) newCmd(ctx context.Context) (*exec.Cmd, error) {
@@ -157,33 +238,72 @@ func (c *SCmd) newCmd(ctx context.Context) (*exec.Cmd, error) {
 	if timeout > 0 {
 		c.ctx, c.cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
 	}
-	var cmd *exec.Cmd
-	switch c.shell {
-	case "python", "python2", "py2", "py":
-		cmd = exec.CommandContext(c.ctx, "python2", c.scriptName)
-	case "python3", "py3":
-		cmd = exec.CommandContext(c.ctx, "python3", c.scriptName)
-	default:
-		cmd = c.selfCmd()
-	}
-	cmd.Dir = c.workspace
-	cmd.Env = c.envs()
+	args := c.interp.Args(c.scriptName, c.args...)
+	cmd := exec.CommandContext(c.ctx, args[0], args[1:]...)
+	cmd.Dir = c.workdir
+	if c.stdin != nil {
+		cmd.Stdin = c.stdin
+	}
+	cmd.Env = c.envs(c.interp.Environ()...)
 	return cmd, nil
 }
 
-func (c *SCmd) copyOutput(reader io.ReadCloser) {
+// pipeOutput 标准输出与标准错误分别写入日志, 返回等待输出读取完成的函数
+func (c *SCmd) pipeOutput(cmd *exec.Cmd) func() {
+	var wg sync.WaitGroup
+	var writers []*io.PipeWriter
+	for stream, out := range map[string]*io.Writer{
+		models.StreamStdout: &cmd.Stdout,
+		models.StreamStderr: &cmd.Stderr,
+	} {
+		r, w := io.Pipe()
+		*out = w
+		writers = append(writers, w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.copyOutput(r, stream)
+		}()
+	}
+	// 后台子进程持有输出时, 进程退出后最多等待的时长
+	cmd.WaitDelay = 5 * time.Second
+	return func() {
+		for _, w := range writers {
+			_ = w.Close()
+		}
+		wg.Wait()
+	}
+}
+
+// transform 解释器指定了编码且输出不是合法utf-8时转码
+func (c *SCmd) transform(line string) string {
+	if c.interp.HasEncoding() && !utf8.ValidString(line) {
+		return c.interp.Decode(line)
+	}
+	return line
+}
+
+func (c *SCmd) copyOutput(reader io.ReadCloser, stream string) {
 	defer func() {
 		_ = reader.Close()
 	}()
-	// 按行读取输出写入到日志中
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
+	// 按行读取输出写入到日志中, 去除ANSI转义序列, 原始内容保存在Raw中, 超长的行截断
+	common.ReadLines(reader, func(text string) {
+		raw := c.transform(strings.TrimSpace(text))
+		line := strings.TrimSpace(utils.StripAnsi(raw))
 		if line == "" {
-			continue
+			return
 		}
-		line = c.transform(line)
-		c.storage.Log().Write(line)
-	}
+		log := &models.SStepLog{
+			Timestamp: time.Now().UnixNano(),
+			Content:   line,
+			Stream:    stream,
+		}
+		if raw != line {
+			log.Raw = raw
+		}
+		if err := c.storage.Log().Insert(log); err != nil {
+			logx.Warnln(err)
+		}
+	})
 }
@@ -11,75 +11,15 @@ import (
 	"runtime/debug"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/creack/pty"
-	"github.com/dlclark/regexp2"
 	"github.com/xmapst/logx"
 	"golang.org/x/term"
 
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
 	"github.com/xmapst/AutoExecFlow/internal/worker/common"
 )
 
-func (c *SCmd) selfScriptSuffix() string {
-	switch c.shell {
-	case "ash":
-		return ".ash"
-	case "bash":
-		return ".bash"
-	case "csh":
-		return ".csh"
-	case "dash":
-		return ".dash"
-	case "ksh":
-		return ".ksh"
-	case "shell", "sh":
-		return ".sh"
-	case "tcsh":
-		return ".tcsh"
-	case "zsh":
-		return ".zsh"
-	default:
-		return ".bash"
-	}
-}
-
-func (c *SCmd) selfCmd() *exec.Cmd {
-	var cmd *exec.Cmd
-	switch c.shell {
-	case "ash":
-		cmd = exec.CommandContext(c.ctx, "ash", "-c", c.scriptName)
-	case "csh":
-		cmd = exec.CommandContext(c.ctx, "csh", "-c", c.scriptName)
-	case "dash":
-		cmd = exec.CommandContext(c.ctx, "dash", "-c", c.scriptName)
-	case "ksh":
-		cmd = exec.CommandContext(c.ctx, "ksh", "-c", c.scriptName)
-	case "shell", "sh":
-		// 严格模式
-		// c.exec = exec.CommandContext(c.ctx, "sh", "-e", c.absFilePath)
-		cmd = exec.CommandContext(c.ctx, "sh", c.scriptName)
-	case "tcsh":
-		cmd = exec.CommandContext(c.ctx, "tcsh", "-c", c.scriptName)
-	case "zsh":
-		cmd = exec.CommandContext(c.ctx, "zsh", "-c", c.scriptName)
-	default:
-		// 严格模式
-		// c.exec = exec.CommandContext(c.ctx, "bash", "--noprofile", "--norc", "-e", "-o", "pipefail", c.absFilePath)
-		// -o pipefail 管道中最后一个返回非零退出状态码的命令的退出状态码将作为该管道命令的返回值，若所有命令的退出状态码都为零则返回零
-		cmd = exec.CommandContext(c.ctx, "bash", "-o", "pipefail", c.scriptName)
-	}
-	return cmd
-}
-
-func (c *SCmd) utf8ToGb2312(s string) string {
-	return s
-}
-
-func (c *SCmd) transform(line string) string {
-	return line
-}
-
 func (c *SCmd) Run(ctx context.Context) (exit int64, err error) {
 	defer func() {
 		c.cancel()
@@ -99,43 +39,39 @@ func (c *SCmd) Run(ctx context.Context) (exit int64, err error) {
 		c.storage.Log().Write(err.Error())
 		return common.CodeSystemErr, err
 	}
-	ppty, tty, err := pty.Open()
-	if err != nil {
-		c.storage.Log().Write(err.Error())
-		return common.CodeSystemErr, err
-	}
-
-	defer func() {
-		if ppty != nil {
-			_ = ppty.Close()
-		}
-		if tty != nil {
-			_ = tty.Close()
-		}
-	}()
-
-	if term.IsTerminal(int(tty.Fd())) {
-		_, err = term.MakeRaw(int(tty.Fd()))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid: true,
+	}
+	var finishOutput func()
+	if c.pipe {
+		finishOutput = c.pipeOutput(cmd)
+	} else {
+		var closePty func()
+		finishOutput, closePty, err = c.ptyOutput(cmd)
 		if err != nil {
-			_ = ppty.Close()
-			_ = tty.Close()
 			c.storage.Log().Write(err.Error())
 			return common.CodeSystemErr, err
 		}
+		defer closePty()
 	}
-	cmd.Stdin = tty
-	cmd.Stdout = tty
-	cmd.Stderr = tty
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setsid:  true,
-		Setctty: true,
+	if err = c.runAs(cmd); err != nil {
+		c.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	cg, err := c.newCgroup()
+	if err != nil {
+		c.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	if cg != nil {
+		defer cg.remove()
+		cg.attach(cmd)
+		// 取消时杀死cgroup内所有子孙进程
+		cmd.Cancel = func() error {
+			_ = cg.kill()
+			return cmd.Process.Kill()
+		}
 	}
-	r, w := io.Pipe()
-	go c.copyOutput(r)
-	writer := &ptyWriter{Out: w}
-	logctx, finishLog := context.WithCancel(context.Background())
-	go c.copyPtyOutput(writer, ppty, finishLog)
-	go c.writeKeepAlive(ppty)
 	err = cmd.Run()
 	if cmd.ProcessState != nil {
 		exit = int64(cmd.ProcessState.ExitCode())
@@ -147,12 +83,20 @@ func (c *SCmd) Run(ctx context.Context) (exit int64, err error) {
 	if err != nil && exit == 0 {
 		exit = common.CodeFailed
 	}
-	writer.AutoStop = true
-	if _, _err := tty.Write([]byte("\x04")); _err != nil {
-		logx.Debugln("Failed to write EOT")
+	var oomKilled bool
+	if cg != nil {
+		_ = cg.kill()
+		oomKilled = cg.oomKilled()
+		memPeak, cpuTime := cg.usage()
+		if _err := c.storage.Update(&models.SStepUpdate{
+			MemPeak: memPeak,
+			CPUTime: cpuTime,
+		}); _err != nil {
+			logx.Warnln(_err)
+		}
 	}
-
-	<-logctx.Done()
+	finishOutput()
+	c.exportEnv()
 	if c.ctx.Err() != nil {
 		switch {
 		case errors.Is(context.Cause(c.ctx), common.ErrTimeOut):
@@ -162,10 +106,66 @@ func (c *SCmd) Run(ctx context.Context) (exit int64, err error) {
 			err = common.ErrManual
 			exit = common.CodeKilled
 		}
+		return
+	}
+	if oomKilled {
+		c.storage.Log().Write(common.ErrOOM.Error())
+		err = common.ErrOOM
+		exit = common.CodeOOMKilled
 	}
 	return
 }
 
+// ptyOutput 标准输入输出连接到pty, 运行中可通过接口附加终端进行交互,
+// 返回等待输出读取完成的函数与关闭pty的函数
+func (c *SCmd) ptyOutput(cmd *exec.Cmd) (finish func(), closePty func(), err error) {
+	ppty, tty, err := pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	var cooked *term.State
+	if term.IsTerminal(int(tty.Fd())) {
+		cooked, err = term.MakeRaw(int(tty.Fd()))
+		if err != nil {
+			_ = ppty.Close()
+			_ = tty.Close()
+			return nil, nil, err
+		}
+	}
+	// 指定了标准输入时从其读取, 控制终端改为标准输出
+	if cmd.Stdin == nil {
+		cmd.Stdin = tty
+	} else {
+		cmd.SysProcAttr.Ctty = 1
+	}
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	cmd.SysProcAttr.Setctty = true
+
+	r, w := io.Pipe()
+	go c.copyOutput(r, models.StreamOutput)
+	writer := &ptyWriter{Out: w}
+	terminal := newTerminal(c.storage, ppty, tty, cooked)
+	unregister := common.RegisterTerminal(c.storage.TaskName(), c.storage.Name(), terminal)
+	logctx, finishLog := context.WithCancel(context.Background())
+	go c.copyPtyOutput(io.MultiWriter(terminal, writer), ppty, finishLog)
+
+	finish = func() {
+		writer.AutoStop = true
+		if _, _err := tty.Write([]byte("\x04")); _err != nil {
+			logx.Debugln("Failed to write EOT")
+		}
+		<-logctx.Done()
+	}
+	closePty = func() {
+		unregister()
+		terminal.close()
+		_ = ppty.Close()
+		_ = tty.Close()
+	}
+	return finish, closePty, nil
+}
+
 func (c *SCmd) copyPtyOutput(writer io.Writer, ppty io.Reader, finishLog context.CancelFunc) {
 	defer func() {
 		finishLog()
@@ -175,24 +175,12 @@ func (c *SCmd) copyPtyOutput(writer io.Writer, ppty io.Reader, finishLog context
 	}
 }
 
-func (c *SCmd) writeKeepAlive(ppty io.Writer) {
-	n := 1
-	var err error
-	for n == 1 && err == nil {
-		n, err = ppty.Write([]byte{4})
-		<-time.After(time.Second)
-	}
-}
-
 type ptyWriter struct {
 	Out       io.Writer
 	AutoStop  bool
 	dirtyLine bool
 }
 
-// 定义正则表达式，用来匹配 ANSI 转义序列
-var ansiRegexp = regexp2.MustCompile("\\x1b\\[[0-9;]*[a-zA-Z]", regexp2.RE2)
-
 func (w *ptyWriter) Write(buf []byte) (int, error) {
 	if w.AutoStop && len(buf) > 0 && buf[len(buf)-1] == 4 {
 		n, err := w.Out.Write(buf[:len(buf)-1])
@@ -206,10 +194,8 @@ func (w *ptyWriter) Write(buf []byte) (int, error) {
 		return n, io.EOF
 	}
 
-	cleaned, err := ansiRegexp.Replace(string(buf), "", -1, -1)
-	if err != nil {
-		return 0, err
-	}
+	var err error
+	var cleaned = string(buf)
 	var lineStart int
 	for i, b := range cleaned {
 		if b == '\r' || b == '\n' {
@@ -228,6 +214,12 @@ func (w *ptyWriter) Write(buf []byte) (int, error) {
 			lineStart = i + 1
 		}
 	}
+	// 未换行的内容(如交互提示)同样写入, 与后续输出拼接为一行
+	if lineStart < len(cleaned) {
+		if _, err = w.Out.Write([]byte(cleaned[lineStart:])); err != nil {
+			return 0, err
+		}
+	}
 	w.dirtyLine = strings.LastIndex(string(buf), "\n") < len(buf)-1
 	return len(buf), nil
 }
@@ -0,0 +1,141 @@
+//go:build !windows
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+// runAs 以指定的用户和组运行, 并将步骤工作目录及脚本文件的属主修改为该用户
+func (c *SCmd) runAs(cmd *exec.Cmd) error {
+	credential, err := c.storage.Credential()
+	if err != nil {
+		return err
+	}
+	if credential.IsEmpty() {
+		return nil
+	}
+	if credential.User == "" {
+		return fmt.Errorf("group %s requires user", credential.Group)
+	}
+	// 白名单可能在任务提交后被修改, 执行前再次校验
+	if !config.App.RunAsAllowed(credential.User) {
+		return fmt.Errorf("step is not allowed to run as user %s", credential.User)
+	}
+	u, err := c.lookupUser(credential.User)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid %s", u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid gid %s", u.Gid)
+	}
+	var groups = []string{u.Gid}
+	if ids, _err := u.GroupIds(); _err == nil {
+		groups = append(groups, ids...)
+	}
+	if credential.Group != "" {
+		var g *user.Group
+		g, err = c.lookupGroup(credential.Group)
+		if err != nil {
+			return err
+		}
+		// 只允许切换到用户所属的组
+		if !slices.Contains(groups, g.Gid) {
+			return fmt.Errorf("user %s is not a member of group %s", u.Username, credential.Group)
+		}
+		gid, err = strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid gid %s", g.Gid)
+		}
+	}
+	var supplementary []uint32
+	for _, v := range utils.RemoveDuplicate(groups) {
+		id, _err := strconv.ParseUint(v, 10, 32)
+		if _err != nil {
+			continue
+		}
+		supplementary = append(supplementary, uint32(id))
+	}
+
+	if err = c.chown(int(uid), int(gid)); err != nil {
+		return err
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    uint32(uid),
+		Gid:    uint32(gid),
+		Groups: supplementary,
+	}
+	// 追加在newCmd生成的环境变量之后, 同名时以最后一个为准
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("HOME=%s", u.HomeDir),
+		fmt.Sprintf("USER=%s", u.Username),
+		fmt.Sprintf("LOGNAME=%s", u.Username),
+	)
+	return nil
+}
+
+// lookupUser 根据用户名或uid查找用户, 系统中不存在的数字uid以同名组运行
+func (c *SCmd) lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, _err := strconv.ParseUint(name, 10, 32); _err != nil {
+		return nil, err
+	}
+	u, err = user.LookupId(name)
+	if err == nil {
+		return u, nil
+	}
+	return &user.User{
+		Uid:      name,
+		Gid:      name,
+		Username: name,
+		HomeDir:  c.workspace,
+	}, nil
+}
+
+// lookupGroup 根据组名或gid查找组
+func (c *SCmd) lookupGroup(name string) (*user.Group, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g, nil
+	}
+	if _, _err := strconv.ParseUint(name, 10, 32); _err != nil {
+		return nil, err
+	}
+	g, err = user.LookupGroupId(name)
+	if err == nil {
+		return g, nil
+	}
+	return &user.Group{Gid: name, Name: name}, nil
+}
+
+// chown 修改步骤工作目录(不递归), 脚本及环境变量文件的属主,
+// 其他步骤写入的文件保持原属主, 不跟随符号链接
+func (c *SCmd) chown(uid, gid int) error {
+	if err := os.Lchown(c.workdir, uid, gid); err != nil {
+		return fmt.Errorf("chown workdir: %w", err)
+	}
+	for _, name := range []string{c.scriptName, c.envPath} {
+		if err := os.Lchown(name, uid, gid); err != nil {
+			return fmt.Errorf("chown %s: %w", filepath.Base(name), err)
+		}
+	}
+	return nil
+}
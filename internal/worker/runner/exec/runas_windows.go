@@ -0,0 +1,17 @@
+package exec
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func (c *SCmd) runAs(*exec.Cmd) error {
+	credential, err := c.storage.Credential()
+	if err != nil {
+		return err
+	}
+	if !credential.IsEmpty() {
+		return errors.New("run as user/group is not supported on windows")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+//go:build !windows
+
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/creack/pty"
+	"github.com/xmapst/logx"
+	"golang.org/x/term"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+const historySize = 4096
+
+// sTerminal 可附加的步骤pty, 有会话附加时切换为规范模式, 以便回显与按行输入
+type sTerminal struct {
+	storage storage.IStep
+	ppty    *os.File
+	tty     *os.File
+	cooked  *term.State // 切换为raw模式前的状态
+
+	mu       sync.Mutex
+	history  []byte // 最近的输出, 附加时先发送, 以便看到附加前的提示
+	sessions map[*sSession]struct{}
+	closed   bool
+}
+
+func newTerminal(storage storage.IStep, ppty, tty *os.File, cooked *term.State) *sTerminal {
+	return &sTerminal{
+		storage:  storage,
+		ppty:     ppty,
+		tty:      tty,
+		cooked:   cooked,
+		sessions: make(map[*sSession]struct{}),
+	}
+}
+
+func (t *sTerminal) Attach(user string, output io.Writer) (common.ISession, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, errors.New("step has finished")
+	}
+	if len(t.sessions) == 0 {
+		// 丢弃附加前积压的输入
+		if err := flushInput(int(t.tty.Fd())); err != nil {
+			logx.Warnln(err)
+		}
+		if t.cooked != nil {
+			if err := term.Restore(int(t.tty.Fd()), t.cooked); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s := &sSession{
+		terminal: t,
+		user:     user,
+		output:   output,
+		ch:       make(chan []byte, 256),
+		done:     make(chan struct{}),
+	}
+	if len(t.history) > 0 {
+		s.ch <- bytes.Clone(t.history)
+	}
+	t.sessions[s] = struct{}{}
+	go s.pump()
+	t.storage.Log().Writef("%s attached to terminal", user)
+	return s, nil
+}
+
+// Write 将脱敏后的pty输出广播到已附加的会话, 会话来不及处理时丢弃, 不阻塞步骤输出
+func (t *sTerminal) Write(p []byte) (int, error) {
+	masked := []byte(storage.MaskSecrets(string(p)))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.history = append(t.history, masked...)
+	if len(t.history) > historySize {
+		t.history = t.history[len(t.history)-historySize:]
+	}
+	for s := range t.sessions {
+		select {
+		case s.ch <- bytes.Clone(masked):
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (t *sTerminal) detach(s *sSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.sessions[s]; !ok {
+		return
+	}
+	delete(t.sessions, s)
+	close(s.ch)
+	if len(t.sessions) == 0 && !t.closed && t.cooked != nil {
+		if _, err := term.MakeRaw(int(t.tty.Fd())); err != nil {
+			logx.Warnln(err)
+		}
+	}
+	t.storage.Log().Writef("%s detached from terminal", s.user)
+}
+
+// close 步骤结束, 分离所有会话
+func (t *sTerminal) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for s := range t.sessions {
+		delete(t.sessions, s)
+		close(s.ch)
+		t.storage.Log().Writef("%s detached from terminal, step finished", s.user)
+	}
+}
+
+type sSession struct {
+	terminal *sTerminal
+	user     string
+	output   io.Writer
+	ch       chan []byte
+	done     chan struct{}
+}
+
+func (s *sSession) pump() {
+	defer close(s.done)
+	for p := range s.ch {
+		if _, err := s.output.Write(p); err != nil {
+			s.terminal.detach(s)
+			break
+		}
+	}
+	// 分离后继续读取直到通道关闭
+	for range s.ch {
+	}
+}
+
+func (s *sSession) Write(p []byte) (int, error) {
+	select {
+	case <-s.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	return s.terminal.ppty.Write(p)
+}
+
+func (s *sSession) Resize(rows, cols uint16) error {
+	return pty.Setsize(s.terminal.ppty, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+func (s *sSession) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *sSession) Close() error {
+	s.terminal.detach(s)
+	<-s.done
+	return nil
+}
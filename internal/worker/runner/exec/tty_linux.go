@@ -0,0 +1,10 @@
+//go:build linux
+
+package exec
+
+import "golang.org/x/sys/unix"
+
+// flushInput 丢弃终端中未读取的输入
+func flushInput(fd int) error {
+	return unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH)
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !windows
+
+package exec
+
+func flushInput(int) error {
+	return nil
+}
@@ -0,0 +1,463 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+const (
+	formatTar   = "tar"
+	formatTarGz = "tar.gz"
+	formatZip   = "zip"
+	formatGz    = "gz"
+)
+
+// detectFormat 按扩展名判断归档格式
+func detectFormat(format, name string) (string, error) {
+	if format == "" {
+		lower := strings.ToLower(name)
+		switch {
+		case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+			format = formatTarGz
+		case strings.HasSuffix(lower, ".tar"):
+			format = formatTar
+		case strings.HasSuffix(lower, ".zip"):
+			format = formatZip
+		case strings.HasSuffix(lower, ".gz"):
+			format = formatGz
+		}
+	}
+	switch strings.ToLower(format) {
+	case formatTar:
+		return formatTar, nil
+	case formatTarGz, "tgz":
+		return formatTarGz, nil
+	case formatZip:
+		return formatZip, nil
+	case formatGz, "gzip":
+		return formatGz, nil
+	default:
+		return "", fmt.Errorf("unknown archive format of %s, set format to tar, tar.gz, zip or gz", name)
+	}
+}
+
+type sArchive struct {
+	f      *SFile
+	sPaths `yaml:",inline"`
+
+	Dest   string `json:"dest" yaml:"dest"`
+	Format string `json:"format" yaml:"format"` // 默认按dest扩展名判断
+}
+
+// run 每个源以其名称作为归档中的顶层路径
+func (a *sArchive) run(ctx context.Context) error {
+	sources, err := a.f.globs(a.list())
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return errors.New("no file matched")
+	}
+	dest, err := a.f.path(a.Dest)
+	if err != nil {
+		return err
+	}
+	format, err := detectFormat(a.Format, dest)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if source == dest || strings.HasPrefix(dest, source+string(filepath.Separator)) {
+			return fmt.Errorf("cannot archive %s into itself", a.f.display(source))
+		}
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(dest)); err != nil {
+		return err
+	}
+
+	a.f.storage.Log().Writef("create %s archive %s", format, a.f.display(dest))
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case formatGz:
+		err = a.gzip(sources, file)
+	case formatZip:
+		err = a.zip(ctx, sources, file)
+	default:
+		err = a.tar(ctx, sources, file, format == formatTarGz)
+	}
+	if _err := file.Close(); err == nil {
+		err = _err
+	}
+	if err != nil {
+		_ = os.Remove(dest)
+	}
+	return err
+}
+
+func (a *sArchive) gzip(sources []string, w io.Writer) error {
+	if len(sources) != 1 {
+		return errors.New("gz format only supports a single file")
+	}
+	in, err := os.Open(sources[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gw := gzip.NewWriter(w)
+	gw.Name = filepath.Base(sources[0])
+	if _, err = io.Copy(gw, in); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func (a *sArchive) tar(ctx context.Context, sources []string, w io.Writer, compress bool) error {
+	if compress {
+		gw := gzip.NewWriter(w)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	for _, source := range sources {
+		err := a.each(ctx, source, func(path, name string, info fs.FileInfo) error {
+			var link string
+			if info.Mode()&os.ModeSymlink != 0 {
+				var err error
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err = tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			return copyFrom(tw, path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (a *sArchive) zip(ctx context.Context, sources []string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, source := range sources {
+		err := a.each(ctx, source, func(path, name string, info fs.FileInfo) error {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			switch {
+			case info.IsDir():
+				header.Name += "/"
+			case info.Mode()&os.ModeSymlink != 0:
+				// 符号链接以链接目标作为内容
+				link, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				fw, err := zw.CreateHeader(header)
+				if err != nil {
+					return err
+				}
+				_, err = fw.Write([]byte(link))
+				return err
+			default:
+				header.Method = zip.Deflate
+			}
+			fw, err := zw.CreateHeader(header)
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			return copyFrom(fw, path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// each 遍历源路径, name为归档中使用/分隔的相对路径
+func (a *sArchive) each(ctx context.Context, source string, fn func(path, name string, info fs.FileInfo) error) error {
+	parent := filepath.Dir(source)
+	return filepath.Walk(source, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func copyFrom(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+type sExtract struct {
+	f *SFile
+
+	Src             string `json:"src" yaml:"src"`
+	Dest            string `json:"dest" yaml:"dest"`     // 默认为归档所在目录
+	Format          string `json:"format" yaml:"format"` // 默认按src扩展名判断
+	StripComponents int    `json:"strip_components" yaml:"strip_components"`
+}
+
+func (e *sExtract) run(ctx context.Context) error {
+	src, err := e.f.path(e.Src)
+	if err != nil {
+		return err
+	}
+	format, err := detectFormat(e.Format, src)
+	if err != nil {
+		return err
+	}
+	var dest = filepath.Dir(src)
+	if e.Dest != "" {
+		if dest, err = e.f.path(e.Dest); err != nil {
+			return err
+		}
+	}
+	e.f.storage.Log().Writef("extract %s archive %s to %s", format, e.f.display(src), e.f.display(dest))
+	switch format {
+	case formatZip:
+		return e.zip(ctx, src, dest)
+	case formatGz:
+		return e.gzip(src, dest)
+	default:
+		file, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		var r io.Reader = file
+		if format == formatTarGz {
+			gr, err := gzip.NewReader(file)
+			if err != nil {
+				return err
+			}
+			defer gr.Close()
+			r = gr
+		}
+		return e.tar(ctx, r, dest)
+	}
+}
+
+// target 计算条目的解压路径, 拒绝越出目标目录的条目, 返回空表示跳过
+func (e *sExtract) target(dest, name string) (string, error) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	if len(parts) <= e.StripComponents {
+		return "", nil
+	}
+	rel := filepath.FromSlash(strings.Join(parts[e.StripComponents:], "/"))
+	target := filepath.Join(dest, rel)
+	if target != filepath.Clean(dest) && !strings.HasPrefix(target, filepath.Clean(dest)+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
+
+// checkLink 链接目标不能指向目标目录外
+func checkLink(dest, target, link string) error {
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("illegal link in archive: %s -> %s", target, link)
+	}
+	resolved := filepath.Join(filepath.Dir(target), link)
+	if resolved != filepath.Clean(dest) && !strings.HasPrefix(resolved, filepath.Clean(dest)+string(filepath.Separator)) {
+		return fmt.Errorf("illegal link in archive: %s -> %s", target, link)
+	}
+	return nil
+}
+
+func (e *sExtract) tar(ctx context.Context, r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := e.target(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = writeFile(target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err = checkLink(dest, target, header.Linkname); err != nil {
+				return err
+			}
+			if err = utils.EnsureDirExist(filepath.Dir(target)); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err = os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			link, err := e.target(dest, header.Linkname)
+			if err != nil || link == "" {
+				return fmt.Errorf("illegal link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			_ = os.Remove(target)
+			if err = os.Link(link, target); err != nil {
+				return err
+			}
+		default:
+			e.f.storage.Log().Writef("skip unsupported entry %s", header.Name)
+		}
+	}
+}
+
+func (e *sExtract) zip(ctx context.Context, src, dest string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, file := range zr.File {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		target, err := e.target(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		mode := file.Mode()
+		if mode.IsDir() {
+			if err = os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		if mode&os.ModeSymlink != 0 {
+			var link []byte
+			link, err = io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+			if err = checkLink(dest, target, string(link)); err != nil {
+				return err
+			}
+			if err = utils.EnsureDirExist(filepath.Dir(target)); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err = os.Symlink(string(link), target); err != nil {
+				return err
+			}
+			continue
+		}
+		err = writeFile(target, rc, mode.Perm())
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gzip 解压单个文件, dest为已存在的目录时解压到目录下
+func (e *sExtract) gzip(src, dest string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+	name := gr.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	}
+	target := dest
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		target = filepath.Join(dest, filepath.Base(name))
+	}
+	return writeFile(target, gr, 0644)
+}
+
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := utils.EnsureDirExist(filepath.Dir(path)); err != nil {
+		return err
+	}
+	// 已存在的链接先删除, 避免写入链接指向的文件
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		_ = os.Remove(path)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,88 @@
+package file
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+type sChecksum struct {
+	f *SFile
+
+	Path      string `json:"path" yaml:"path"`
+	Algorithm string `json:"algorithm" yaml:"algorithm"` // md5, sha1, sha256, sha512, 默认sha256
+	Expected  string `json:"expected" yaml:"expected"`   // 期望值, 可带算法前缀如 sha256:xxx, 为空时只计算
+	Output    string `json:"output" yaml:"output"`       // 保存为输出的名称, 默认CHECKSUM
+}
+
+func (c *sChecksum) run(ctx context.Context) error {
+	path, err := c.f.path(c.Path)
+	if err != nil {
+		return err
+	}
+	expected := strings.TrimSpace(c.f.expand(c.Expected))
+	algorithm := strings.ToLower(c.Algorithm)
+	if before, after, found := strings.Cut(expected, ":"); found {
+		algorithm, expected = strings.ToLower(before), after
+	}
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, ok := hashes[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	h := newHash()
+	if _, err = io.Copy(h, &sReader{ctx: ctx, r: file}); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.f.storage.Log().Writef("%s %s  %s", algorithm, sum, c.f.display(path))
+
+	if c.Output == "" {
+		c.Output = "CHECKSUM"
+	}
+	if err = c.f.storage.Output().Insert(&models.SEnv{Name: c.Output, Value: sum}); err != nil {
+		return err
+	}
+	if expected != "" && !strings.EqualFold(sum, expected) {
+		return fmt.Errorf("checksum mismatch, expected %s, got %s", expected, sum)
+	}
+	return nil
+}
+
+// sReader 读取时响应取消
+type sReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (s *sReader) Read(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.r.Read(p)
+}
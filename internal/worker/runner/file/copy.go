@@ -0,0 +1,246 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+type sCopy struct {
+	f *SFile
+
+	Src          string `json:"src" yaml:"src"`   // 支持通配符
+	Dest         string `json:"dest" yaml:"dest"` // 以/结尾, 已存在的目录或多个源时复制到目录下
+	SkipExisting bool   `json:"skip_existing" yaml:"skip_existing"`
+}
+
+func (c *sCopy) run(ctx context.Context) error {
+	pairs, err := c.f.pairs(c.Src, c.Dest)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		c.f.storage.Log().Writef("copy %s to %s", c.f.display(pair[0]), c.f.display(pair[1]))
+		if err = copyPath(ctx, pair[0], pair[1], c.SkipExisting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sMove struct {
+	f *SFile
+
+	Src          string `json:"src" yaml:"src"`
+	Dest         string `json:"dest" yaml:"dest"`
+	SkipExisting bool   `json:"skip_existing" yaml:"skip_existing"`
+}
+
+func (m *sMove) run(ctx context.Context) error {
+	pairs, err := m.f.pairs(m.Src, m.Dest)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if _, err = os.Lstat(pair[1]); err == nil {
+			if m.SkipExisting {
+				m.f.storage.Log().Writef("skip %s, %s already exists", m.f.display(pair[0]), m.f.display(pair[1]))
+				continue
+			}
+			if err = os.RemoveAll(pair[1]); err != nil {
+				return err
+			}
+		}
+		m.f.storage.Log().Writef("move %s to %s", m.f.display(pair[0]), m.f.display(pair[1]))
+		if err = utils.EnsureDirExist(filepath.Dir(pair[1])); err != nil {
+			return err
+		}
+		if err = os.Rename(pair[0], pair[1]); err == nil {
+			continue
+		}
+		// 跨设备时复制后删除
+		if err = copyPath(ctx, pair[0], pair[1], false); err != nil {
+			return err
+		}
+		if err = os.RemoveAll(pair[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sDelete struct {
+	f      *SFile
+	sPaths `yaml:",inline"`
+}
+
+func (d *sDelete) run(ctx context.Context) error {
+	paths, err := d.f.globs(d.list())
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		d.f.storage.Log().Write("nothing to delete")
+		return nil
+	}
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path == filepath.Clean(d.f.workspace) || path == filepath.Dir(path) {
+			return fmt.Errorf("refuse to delete %s", path)
+		}
+		d.f.storage.Log().Writef("delete %s", d.f.display(path))
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sSymlink struct {
+	f *SFile
+
+	Src   string `json:"src" yaml:"src"`   // 链接指向的路径
+	Dest  string `json:"dest" yaml:"dest"` // 链接路径
+	Force bool   `json:"force" yaml:"force"`
+}
+
+func (s *sSymlink) run(context.Context) error {
+	src, err := s.f.path(s.Src)
+	if err != nil {
+		return err
+	}
+	dest, err := s.f.path(s.Dest)
+	if err != nil {
+		return err
+	}
+	if target, err := os.Readlink(dest); err == nil && target == src {
+		s.f.storage.Log().Writef("%s already links to %s", s.f.display(dest), s.f.display(src))
+		return nil
+	}
+	if _, err = os.Lstat(dest); err == nil {
+		if !s.Force {
+			return fmt.Errorf("%s already exists", s.f.display(dest))
+		}
+		if err = os.RemoveAll(dest); err != nil {
+			return err
+		}
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	s.f.storage.Log().Writef("link %s to %s", s.f.display(dest), s.f.display(src))
+	return os.Symlink(src, dest)
+}
+
+// pairs 展开源路径并计算对应的目标路径
+func (f *SFile) pairs(src, dest string) ([][2]string, error) {
+	sources, err := f.glob(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%s not found", src)
+	}
+	target, err := f.path(dest)
+	if err != nil {
+		return nil, err
+	}
+	var intoDir = len(sources) > 1 || strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, `\`)
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		intoDir = true
+	}
+	var res [][2]string
+	for _, source := range sources {
+		to := target
+		if intoDir {
+			to = filepath.Join(target, filepath.Base(source))
+		}
+		if to == source {
+			return nil, fmt.Errorf("%s and %s are the same", src, dest)
+		}
+		if strings.HasPrefix(to, source+string(filepath.Separator)) {
+			return nil, fmt.Errorf("cannot copy %s into itself", f.display(source))
+		}
+		res = append(res, [2]string{source, to})
+	}
+	return res, nil
+}
+
+// copyPath 递归复制, 保留权限, 符号链接复制为链接
+func copyPath(ctx context.Context, src, dest string, skipExisting bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			// 保证可写入目录下的文件
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if _, err = os.Lstat(target); err == nil {
+				if skipExisting {
+					return nil
+				}
+				_ = os.Remove(target)
+			}
+			if err = utils.EnsureDirExist(filepath.Dir(target)); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if _, err = os.Lstat(target); err == nil && skipExisting {
+				return nil
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	if err := utils.EnsureDirExist(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dest, mode)
+}
@@ -0,0 +1,164 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+const (
+	resultChanged   = "changed"
+	resultUnchanged = "unchanged"
+)
+
+// sEdit 编辑类操作的公共参数
+type sEdit struct {
+	Path   string `json:"path" yaml:"path"`
+	Create bool   `json:"create" yaml:"create"` // 文件不存在时创建
+	Backup bool   `json:"backup" yaml:"backup"` // 修改前备份为 <path>.<时间>.bak
+	Check  bool   `json:"check" yaml:"check"`   // 检查模式, 只报告是否需要修改, 不写入
+}
+
+// edit 读取文件, 由fn计算新内容, 有变化时写入并记录结果
+func (f *SFile) edit(e sEdit, fn func(old []byte) ([]byte, error)) error {
+	path, err := f.path(e.Path)
+	if err != nil {
+		return err
+	}
+	var mode os.FileMode = 0644
+	old, err := os.ReadFile(path)
+	missing := errors.Is(err, os.ErrNotExist)
+	switch {
+	case missing:
+		if !e.Create {
+			return fmt.Errorf("%s not found", f.display(path))
+		}
+	case err != nil:
+		return err
+	default:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	content, err := fn(old)
+	if err != nil {
+		return err
+	}
+	changed := missing || !bytes.Equal(old, content)
+	if err = f.storage.Output().Insert(&models.SEnv{Name: "CHANGED", Value: strconv.FormatBool(changed)}); err != nil {
+		return err
+	}
+	if !changed {
+		f.result = resultUnchanged
+		f.storage.Log().Writef("%s is unchanged", f.display(path))
+		return nil
+	}
+
+	f.storage.Log().Writef("--- %s", f.display(path))
+	for _, line := range utils.DiffLines(splitLines(string(old)), splitLines(string(content))) {
+		f.storage.Log().Write(line)
+	}
+	if e.Check {
+		f.result = resultChanged + " (check mode)"
+		f.storage.Log().Writef("check mode, %s is not written", f.display(path))
+		return nil
+	}
+
+	if e.Backup && !missing {
+		backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102150405"))
+		if err = copyFile(path, backup, mode); err != nil {
+			return err
+		}
+		f.storage.Log().Writef("backup %s to %s", f.display(path), f.display(backup))
+		if err = f.storage.Output().Insert(&models.SEnv{Name: "BACKUP", Value: backup}); err != nil {
+			return err
+		}
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	f.result = resultChanged
+	return nil
+}
+
+// sLines 按行编辑的文本, 保留原有的换行符
+type sLines struct {
+	lines []string
+	eol   string
+	noEOF bool // 原文件末尾没有换行
+}
+
+func newLines(content []byte) *sLines {
+	var l = &sLines{eol: "\n"}
+	if bytes.Contains(content, []byte("\r\n")) {
+		l.eol = "\r\n"
+	}
+	l.lines = splitLines(string(content))
+	l.noEOF = len(content) > 0 && !bytes.HasSuffix(content, []byte("\n"))
+	return l
+}
+
+func (l *sLines) bytes() []byte {
+	if len(l.lines) == 0 {
+		return nil
+	}
+	if l.noEOF {
+		return []byte(strings.Join(l.lines, l.eol))
+	}
+	return []byte(strings.Join(l.lines, l.eol) + l.eol)
+}
+
+// position 插入位置, after/before为正则, 取最后一个匹配行, 或EOF/BOF, 默认EOF
+func (l *sLines) position(after, before string) (int, error) {
+	switch {
+	case before == "BOF":
+		return 0, nil
+	case before != "":
+		re, err := regexp.Compile(before)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(l.lines) - 1; i >= 0; i-- {
+			if re.MatchString(l.lines[i]) {
+				return i, nil
+			}
+		}
+	case after != "" && after != "EOF":
+		re, err := regexp.Compile(after)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(l.lines) - 1; i >= 0; i-- {
+			if re.MatchString(l.lines[i]) {
+				return i + 1, nil
+			}
+		}
+	}
+	return len(l.lines), nil
+}
+
+func (l *sLines) insert(i int, lines ...string) {
+	l.lines = slices.Insert(l.lines, i, lines...)
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
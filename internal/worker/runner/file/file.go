@@ -0,0 +1,289 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
+)
+
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+// iOperation 文件操作
+type iOperation interface {
+	run(ctx context.Context) error
+}
+
+// operations 支持的步骤类型
+var operations = map[string]func(f *SFile) iOperation{
+	"copy":     func(f *SFile) iOperation { return &sCopy{f: f} },
+	"move":     func(f *SFile) iOperation { return &sMove{f: f} },
+	"delete":   func(f *SFile) iOperation { return &sDelete{f: f} },
+	"symlink":  func(f *SFile) iOperation { return &sSymlink{f: f} },
+	"chmod":    func(f *SFile) iOperation { return &sChmod{f: f} },
+	"chown":    func(f *SFile) iOperation { return &sChown{f: f} },
+	"archive":  func(f *SFile) iOperation { return &sArchive{f: f} },
+	"extract":  func(f *SFile) iOperation { return &sExtract{f: f} },
+	"checksum": func(f *SFile) iOperation { return &sChecksum{f: f} },
+	"template": func(f *SFile) iOperation { return &sTemplate{f: f} },
+
+	"lineinfile":  func(f *SFile) iOperation { return &sLineInFile{f: f} },
+	"blockinfile": func(f *SFile) iOperation { return &sBlockInFile{f: f} },
+	"ini":         func(f *SFile) iOperation { return &sKeys{f: f, format: formatINI} },
+	"yaml":        func(f *SFile) iOperation { return &sKeys{f: f, format: formatYAML} },
+	"json":        func(f *SFile) iOperation { return &sKeys{f: f, format: formatJSON} },
+	"toml":        func(f *SFile) iOperation { return &sKeys{f: f, format: formatTOML} },
+}
+
+// Supported 是否为文件操作步骤类型
+func Supported(typ string) bool {
+	_, ok := operations[strings.ToLower(typ)]
+	return ok
+}
+
+type SFile struct {
+	storage   storage.IStep
+	workspace string
+	operation string
+	envs      map[string]string
+	result    string // 编辑类操作的结果, changed/unchanged
+}
+
+func New(storage storage.IStep, operation, workspace string) (*SFile, error) {
+	operation = strings.ToLower(operation)
+	if !Supported(operation) {
+		return nil, fmt.Errorf("unsupported file operation %s", operation)
+	}
+	return &SFile{
+		storage:   storage,
+		workspace: workspace,
+		operation: operation,
+	}, nil
+}
+
+func (f *SFile) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			f.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+	content, err := f.storage.Content()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	op := operations[f.operation](f)
+	if err = json.Unmarshal([]byte(content), op); err != nil {
+		if err = yaml.Unmarshal([]byte(content), op); err != nil {
+			f.storage.Log().Write(err.Error())
+			return common.CodeSystemErr, err
+		}
+	}
+	f.loadEnvs()
+
+	timeout, err := f.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	if err = op.run(ctx); err != nil {
+		if ctx.Err() != nil {
+			if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+				return common.CodeTimeout, common.ErrTimeOut
+			}
+			return common.CodeKilled, common.ErrManual
+		}
+		f.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+	return common.CodeSuccess, nil
+}
+
+func (f *SFile) Clear() error {
+	return nil
+}
+
+func (f *SFile) Result() string {
+	return f.result
+}
+
+func (f *SFile) loadEnvs() {
+	f.envs = make(map[string]string)
+	for _, env := range f.storage.GlobalEnv().List() {
+		f.envs[env.Name] = env.Value
+	}
+	for _, env := range f.storage.Env().List() {
+		f.envs[env.Name] = env.Value
+	}
+	f.envs["TASK_NAME"] = f.storage.TaskName()
+	f.envs["TASK_STEP_NAME"] = f.storage.Name()
+	f.envs["TASK_WORKSPACE"] = f.workspace
+}
+
+// expand 替换${NAME}为步骤或任务环境变量, 未定义的保持原样
+func (f *SFile) expand(content string) string {
+	return envRegexp.ReplaceAllStringFunc(content, func(v string) string {
+		if value, ok := f.envs[envRegexp.FindStringSubmatch(v)[1]]; ok {
+			return value
+		}
+		return v
+	})
+}
+
+// path 相对路径位于工作目录下, 解析符号链接后需位于工作目录或挂载的卷目录内, 绝对路径需在服务端允许的范围内
+func (f *SFile) path(name string) (string, error) {
+	name = f.expand(strings.TrimSpace(name))
+	if name == "" {
+		return "", errors.New("path is empty")
+	}
+	if filepath.IsAbs(name) {
+		name = filepath.Clean(name)
+		if !config.App.FileAllowed(name) {
+			return "", fmt.Errorf("path %s is not allowed by policy", name)
+		}
+		return name, nil
+	}
+	return common.Path("path", f.workspace, name, volume.Roots(f.storage.TaskName())...)
+}
+
+// display 日志中显示的路径, 工作目录下的显示为相对路径
+func (f *SFile) display(path string) string {
+	if rel, err := filepath.Rel(f.workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// glob 展开路径中的通配符, 支持**匹配任意层级目录
+func (f *SFile) glob(pattern string) ([]string, error) {
+	path, err := f.path(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.ContainsAny(path, "*?[") {
+		if _, err = os.Lstat(path); err != nil {
+			return nil, nil
+		}
+		return []string{path}, nil
+	}
+	if !strings.Contains(path, "**") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		return f.inside(pattern, matches)
+	}
+
+	// 从通配符之前的目录开始遍历
+	base := path[:strings.IndexAny(path, "*?[")]
+	base = base[:strings.LastIndex(base, string(filepath.Separator))+1]
+	segments := strings.Split(filepath.ToSlash(strings.TrimPrefix(path, base)), "/")
+	var matches []string
+	err = filepath.WalkDir(base, func(name string, _ os.DirEntry, err error) error {
+		if err != nil || name == base {
+			return nil
+		}
+		rel, _ := filepath.Rel(base, name)
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, name)
+		}
+		return nil
+	})
+	sort.Strings(matches)
+	if err != nil {
+		return nil, err
+	}
+	return f.inside(pattern, matches)
+}
+
+// inside 相对路径展开的结果同样需位于工作目录或挂载的卷目录内
+func (f *SFile) inside(pattern string, matches []string) ([]string, error) {
+	if filepath.IsAbs(f.expand(strings.TrimSpace(pattern))) {
+		return matches, nil
+	}
+	for _, match := range matches {
+		rel, err := filepath.Rel(f.workspace, match)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = common.Path("path", f.workspace, rel, volume.Roots(f.storage.TaskName())...); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// globs 展开多个路径, 结果去重
+func (f *SFile) globs(patterns []string) ([]string, error) {
+	var seen = make(map[string]struct{})
+	var res []string
+	for _, pattern := range patterns {
+		matches, err := f.glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if _, ok := seen[match]; ok {
+				continue
+			}
+			seen[match] = struct{}{}
+			res = append(res, match)
+		}
+	}
+	return res, nil
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// sPaths 单个或多个路径
+type sPaths struct {
+	Path  string   `json:"path" yaml:"path"`
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+func (p sPaths) list() []string {
+	if p.Path == "" {
+		return p.Paths
+	}
+	return append([]string{p.Path}, p.Paths...)
+}
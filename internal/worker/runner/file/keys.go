@@ -0,0 +1,496 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/tidwall/sjson"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatINI  = "ini"
+	formatYAML = "yaml"
+	formatJSON = "json"
+	formatTOML = "toml"
+)
+
+// sKeys 按键路径设置或删除配置文件中的值, 路径以.分隔, 数组使用下标, 键名中的.使用\.转义
+type sKeys struct {
+	f      *SFile
+	sEdit  `yaml:",inline"`
+	format string
+
+	Set    map[string]any `json:"set" yaml:"set"`
+	Delete []string       `json:"delete" yaml:"delete"`
+}
+
+func (k *sKeys) run(context.Context) error {
+	if len(k.Set) == 0 && len(k.Delete) == 0 {
+		return errors.New("set or delete is required")
+	}
+	// 按键排序保证修改顺序稳定
+	var keys = make([]string, 0, len(k.Set))
+	for key, value := range k.Set {
+		keys = append(keys, key)
+		k.Set[key] = k.normalize(value)
+	}
+	sort.Strings(keys)
+
+	return k.f.edit(k.sEdit, func(old []byte) ([]byte, error) {
+		switch k.format {
+		case formatINI:
+			return k.ini(old, keys)
+		case formatJSON:
+			return k.json(old, keys)
+		case formatYAML:
+			return k.yaml(old, keys)
+		case formatTOML:
+			return k.toml(old, keys)
+		default:
+			return nil, fmt.Errorf("unsupported format %s", k.format)
+		}
+	})
+}
+
+// normalize 替换字符串中的环境变量, 整数形式的浮点数转为整数
+func (k *sKeys) normalize(value any) any {
+	switch v := value.(type) {
+	case string:
+		return k.f.expand(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case int:
+		return int64(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = k.normalize(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = k.normalize(item)
+		}
+	}
+	return value
+}
+
+func (k *sKeys) json(old []byte, keys []string) ([]byte, error) {
+	content := old
+	if len(bytes.TrimSpace(content)) == 0 {
+		content = []byte("{}")
+	}
+	var err error
+	for _, key := range keys {
+		if content, err = sjson.SetBytes(content, key, k.Set[key]); err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	for _, key := range k.Delete {
+		if content, err = sjson.DeleteBytes(content, key); err != nil {
+			return nil, fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+	var before, after any
+	_ = json.Unmarshal(old, &before)
+	if err = json.Unmarshal(content, &after); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(before, after) {
+		return old, nil
+	}
+	// 多行格式的文件按原缩进重新格式化
+	if bytes.Contains(bytes.TrimSpace(old), []byte("\n")) || len(bytes.TrimSpace(old)) == 0 {
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, content, "", indent(old)); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		content = buf.Bytes()
+	}
+	return content, nil
+}
+
+func (k *sKeys) yaml(old []byte, keys []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(old, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	for _, key := range keys {
+		var value = new(yaml.Node)
+		if err := value.Encode(k.Set[key]); err != nil {
+			return nil, err
+		}
+		if err := setNode(doc.Content[0], splitPath(key), value); err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	for _, key := range k.Delete {
+		deleteNode(doc.Content[0], splitPath(key))
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(max(len(strings.ReplaceAll(indent(old), "\t", "  ")), 2))
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	_ = encoder.Close()
+
+	var before, after any
+	_ = yaml.Unmarshal(old, &before)
+	if err := yaml.Unmarshal(buf.Bytes(), &after); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(before, after) {
+		return old, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func (k *sKeys) toml(old []byte, keys []string) ([]byte, error) {
+	var before, data map[string]any
+	if err := toml.Unmarshal(old, &before); err != nil {
+		return nil, err
+	}
+	_ = toml.Unmarshal(old, &data)
+	if data == nil {
+		data = make(map[string]any)
+	}
+	for _, key := range keys {
+		if _, err := setValue(data, splitPath(key), k.Set[key]); err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	for _, key := range k.Delete {
+		deleteValue(data, splitPath(key))
+	}
+	content, err := toml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var after map[string]any
+	if err = toml.Unmarshal(content, &after); err != nil {
+		return nil, err
+	}
+	if len(before) == 0 && len(after) == 0 || reflect.DeepEqual(before, after) {
+		return old, nil
+	}
+	return content, nil
+}
+
+// ini 按行修改, 保留注释与格式, 路径为 节.键, 无节的键位于文件开头, 删除整节使用 [节]
+func (k *sKeys) ini(old []byte, keys []string) ([]byte, error) {
+	lines := newLines(old)
+	for _, key := range keys {
+		value, err := iniValue(k.Set[key])
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+		section, name := iniKey(key)
+		iniSet(lines, section, name, value)
+	}
+	for _, key := range k.Delete {
+		if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+			iniDeleteSection(lines, strings.TrimSpace(key[1:len(key)-1]))
+			continue
+		}
+		section, name := iniKey(key)
+		var kept = lines.lines[:0:0]
+		iniScan(lines.lines, func(i int, sec, key string) {
+			if key == "" || sec != section || key != name {
+				kept = append(kept, lines.lines[i])
+			}
+		})
+		lines.lines = kept
+	}
+	return lines.bytes(), nil
+}
+
+func iniKey(path string) (section, name string) {
+	segments := splitPath(path)
+	return strings.Join(segments[:len(segments)-1], "."), segments[len(segments)-1]
+}
+
+func iniValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case map[string]any, []any:
+		return "", errors.New("value must be a scalar")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// iniScan 遍历每一行, 键值行给出所在的节与键名, 其他行键名为空
+func iniScan(lines []string, fn func(i int, section, key string)) {
+	var section string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, ";"):
+			fn(i, section, "")
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			fn(i, section, "")
+		default:
+			key, _, _ := strings.Cut(trimmed, "=")
+			fn(i, section, strings.TrimSpace(key))
+		}
+	}
+}
+
+func iniSet(lines *sLines, section, name, value string) {
+	var found, last, header = -1, -1, -1
+	iniScan(lines.lines, func(i int, sec, key string) {
+		if sec != section {
+			return
+		}
+		switch {
+		case key == "":
+			if section != "" && header < 0 && strings.HasPrefix(strings.TrimSpace(lines.lines[i]), "[") {
+				header = i
+			}
+			if section == "" && strings.TrimSpace(lines.lines[i]) != "" {
+				last = i
+			}
+		case key == name:
+			found, last = i, i
+		default:
+			last = i
+		}
+	})
+	if found >= 0 {
+		line := lines.lines[found]
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			lines.lines[found] = line + " = " + value
+			return
+		}
+		if strings.TrimSpace(line[eq+1:]) == value {
+			return
+		}
+		sep := "="
+		if strings.HasPrefix(line[eq+1:], " ") {
+			sep = "= "
+		}
+		lines.lines[found] = line[:eq] + sep + value
+		return
+	}
+	entry := name + " = " + value
+	switch {
+	case last >= 0:
+		lines.insert(last+1, entry)
+	case header >= 0:
+		lines.insert(header+1, entry)
+	case section == "":
+		lines.insert(0, entry)
+	default:
+		if len(lines.lines) > 0 && strings.TrimSpace(lines.lines[len(lines.lines)-1]) != "" {
+			lines.lines = append(lines.lines, "")
+		}
+		lines.lines = append(lines.lines, "["+section+"]", entry)
+	}
+}
+
+func iniDeleteSection(lines *sLines, section string) {
+	var kept = lines.lines[:0:0]
+	iniScan(lines.lines, func(i int, sec, _ string) {
+		if sec != section || section == "" {
+			kept = append(kept, lines.lines[i])
+		}
+	})
+	lines.lines = kept
+}
+
+// splitPath 按.分隔键路径, \.表示键名中的.
+func splitPath(path string) []string {
+	var res []string
+	var cur strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			cur.WriteByte('.')
+			i++
+		case path[i] == '.':
+			res = append(res, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(path[i])
+		}
+	}
+	return append(res, cur.String())
+}
+
+// indent 检测文件的缩进, 默认两个空格
+func indent(content []byte) string {
+	for _, line := range splitLines(string(content)) {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != line && trimmed != "" {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// setNode 在yaml节点中按路径设置值, 缺失的中间节点创建为映射
+func setNode(node *yaml.Node, path []string, value *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				// 保留原有的注释
+				value.HeadComment = node.Content[i+1].HeadComment
+				value.LineComment = node.Content[i+1].LineComment
+				value.FootComment = node.Content[i+1].FootComment
+				node.Content[i+1] = value
+				return nil
+			}
+			return setNode(node.Content[i+1], path[1:], value)
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+		if len(path) == 1 {
+			node.Content = append(node.Content, key, value)
+			return nil
+		}
+		child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		node.Content = append(node.Content, key, child)
+		return setNode(child, path[1:], value)
+	case yaml.SequenceNode:
+		i, err := index(path[0], len(node.Content))
+		if err != nil {
+			return err
+		}
+		if i == len(node.Content) {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		}
+		if len(path) == 1 {
+			node.Content[i] = value
+			return nil
+		}
+		return setNode(node.Content[i], path[1:], value)
+	default:
+		return fmt.Errorf("%s is not a mapping or sequence", path[0])
+	}
+}
+
+func deleteNode(node *yaml.Node, path []string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+				return
+			}
+			deleteNode(node.Content[i+1], path[1:])
+			return
+		}
+	case yaml.SequenceNode:
+		i, err := index(path[0], len(node.Content)-1)
+		if err != nil || i < 0 {
+			return
+		}
+		if len(path) == 1 {
+			node.Content = append(node.Content[:i], node.Content[i+1:]...)
+			return
+		}
+		deleteNode(node.Content[i], path[1:])
+	}
+}
+
+// setValue 在解析后的数据中按路径设置值, 缺失的中间节点创建为映射, 返回修改后的数据
+func setValue(data any, path []string, value any) (any, error) {
+	switch v := data.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			v[path[0]] = value
+			return v, nil
+		}
+		child, ok := v[path[0]]
+		if !ok {
+			child = make(map[string]any)
+		}
+		child, err := setValue(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[path[0]] = child
+		return v, nil
+	case []any:
+		i, err := index(path[0], len(v))
+		if err != nil {
+			return nil, err
+		}
+		if i == len(v) {
+			v = append(v, make(map[string]any))
+		}
+		if len(path) == 1 {
+			v[i] = value
+			return v, nil
+		}
+		if v[i], err = setValue(v[i], path[1:], value); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s is not a table or array", path[0])
+	}
+}
+
+// deleteValue 在解析后的数据中按路径删除值, 返回修改后的数据
+func deleteValue(data any, path []string) any {
+	switch v := data.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			delete(v, path[0])
+		} else if child, ok := v[path[0]]; ok {
+			v[path[0]] = deleteValue(child, path[1:])
+		}
+	case []any:
+		i, err := index(path[0], len(v)-1)
+		if err != nil || i < 0 {
+			return v
+		}
+		if len(path) == 1 {
+			return append(v[:i], v[i+1:]...)
+		}
+		v[i] = deleteValue(v[i], path[1:])
+	}
+	return data
+}
+
+// index 解析数组下标, -1表示末尾追加, 不能超过limit
+func index(s string, limit int) (int, error) {
+	if s == "-1" {
+		return limit, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i > limit {
+		return 0, fmt.Errorf("invalid index %s", s)
+	}
+	return i, nil
+}
@@ -0,0 +1,164 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type sLineInFile struct {
+	f     *SFile
+	sEdit `yaml:",inline"`
+
+	Regexp       string `json:"regexp" yaml:"regexp"`               // 匹配的行, 为空时按line全文匹配
+	Line         string `json:"line" yaml:"line"`                   // 期望的行内容
+	State        string `json:"state" yaml:"state"`                 // present(默认), absent, replaced
+	Backrefs     bool   `json:"backrefs" yaml:"backrefs"`           // line中可引用regexp的分组, 如$1
+	InsertAfter  string `json:"insert_after" yaml:"insert_after"`   // 未匹配时插入在此正则最后匹配的行后, 或EOF(默认)
+	InsertBefore string `json:"insert_before" yaml:"insert_before"` // 未匹配时插入在此正则最后匹配的行前, 或BOF
+}
+
+func (l *sLineInFile) run(context.Context) error {
+	state := strings.ToLower(l.State)
+	if state == "" {
+		state = "present"
+	}
+	line := l.f.expand(l.Line)
+	var match = func(s string) bool { return s == line }
+	var re *regexp.Regexp
+	if l.Regexp != "" {
+		var err error
+		if re, err = regexp.Compile(l.Regexp); err != nil {
+			return err
+		}
+		match = re.MatchString
+	} else if state == "replaced" || l.Backrefs {
+		return errors.New("regexp is required")
+	}
+	// replace 计算匹配行的新内容
+	var replace = func(s string) string {
+		if l.Backrefs && re != nil {
+			return string(re.ExpandString(nil, line, s, re.FindStringSubmatchIndex(s)))
+		}
+		return line
+	}
+
+	return l.f.edit(l.sEdit, func(old []byte) ([]byte, error) {
+		lines := newLines(old)
+		switch state {
+		case "absent":
+			var kept = lines.lines[:0:0]
+			for _, s := range lines.lines {
+				if !match(s) {
+					kept = append(kept, s)
+				}
+			}
+			lines.lines = kept
+		case "replaced":
+			for i, s := range lines.lines {
+				if match(s) {
+					lines.lines[i] = replace(s)
+				}
+			}
+		case "present":
+			// 替换最后一个匹配的行, 无匹配时插入
+			for i := len(lines.lines) - 1; i >= 0; i-- {
+				if match(lines.lines[i]) {
+					lines.lines[i] = replace(lines.lines[i])
+					return lines.bytes(), nil
+				}
+			}
+			if l.Backrefs {
+				// 引用分组时无匹配则不插入
+				return old, nil
+			}
+			for _, s := range lines.lines {
+				if s == line {
+					return old, nil
+				}
+			}
+			i, err := lines.position(l.InsertAfter, l.InsertBefore)
+			if err != nil {
+				return nil, err
+			}
+			lines.insert(i, line)
+		default:
+			return nil, fmt.Errorf("unsupported state %s", l.State)
+		}
+		return lines.bytes(), nil
+	})
+}
+
+type sBlockInFile struct {
+	f     *SFile
+	sEdit `yaml:",inline"`
+
+	Block        string `json:"block" yaml:"block"`
+	Marker       string `json:"marker" yaml:"marker"` // 标记行, {mark}替换为BEGIN/END, 默认 # {mark} AEF MANAGED BLOCK
+	State        string `json:"state" yaml:"state"`   // present(默认), absent
+	InsertAfter  string `json:"insert_after" yaml:"insert_after"`
+	InsertBefore string `json:"insert_before" yaml:"insert_before"`
+}
+
+func (b *sBlockInFile) run(context.Context) error {
+	state := strings.ToLower(b.State)
+	if state == "" {
+		state = "present"
+	}
+	if state != "present" && state != "absent" {
+		return fmt.Errorf("unsupported state %s", b.State)
+	}
+	marker := b.Marker
+	if marker == "" {
+		marker = "# {mark} AEF MANAGED BLOCK"
+	}
+	if !strings.Contains(marker, "{mark}") {
+		return errors.New("marker must contain {mark}")
+	}
+	begin := strings.ReplaceAll(marker, "{mark}", "BEGIN")
+	end := strings.ReplaceAll(marker, "{mark}", "END")
+	block := append([]string{begin}, splitLines(b.f.expand(b.Block))...)
+	block = append(block, end)
+
+	return b.f.edit(b.sEdit, func(old []byte) ([]byte, error) {
+		lines := newLines(old)
+		start, stop := markers(lines.lines, begin, end)
+		if start >= 0 && stop >= 0 {
+			var rest = lines.lines[stop+1:]
+			lines.lines = lines.lines[:start:start]
+			if state == "present" {
+				lines.lines = append(lines.lines, block...)
+			}
+			lines.lines = append(lines.lines, rest...)
+			return lines.bytes(), nil
+		}
+		if state == "absent" {
+			return old, nil
+		}
+		i, err := lines.position(b.InsertAfter, b.InsertBefore)
+		if err != nil {
+			return nil, err
+		}
+		lines.insert(i, block...)
+		return lines.bytes(), nil
+	})
+}
+
+// markers END与之前最近的BEGIN配对, 返回最后一对完整标记的位置, 不存在时返回-1
+func markers(lines []string, begin, end string) (start, stop int) {
+	start, stop = -1, -1
+	var open = -1
+	for i, s := range lines {
+		switch strings.TrimRight(s, " \t") {
+		case begin:
+			open = i
+		case end:
+			if open >= 0 {
+				start, stop, open = open, i, -1
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,27 @@
+package file
+
+import "testing"
+
+func TestMarkers(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       []string
+		start, stop int
+	}{
+		{name: "none", lines: []string{"a"}, start: -1, stop: -1},
+		{name: "block", lines: []string{"a", "B", "x", "E", "b"}, start: 1, stop: 3},
+		{name: "begin only", lines: []string{"B", "x"}, start: -1, stop: -1},
+		{name: "stray end before begin", lines: []string{"E", "B", "x", "E"}, start: 1, stop: 3},
+		{name: "two blocks", lines: []string{"B", "x", "E", "B", "y", "E"}, start: 3, stop: 5},
+		{name: "unclosed after block", lines: []string{"B", "x", "E", "B", "y"}, start: 0, stop: 2},
+		{name: "trailing spaces", lines: []string{"B ", "x", "E\t"}, start: 0, stop: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, stop := markers(tt.lines, "B", "E")
+			if start != tt.start || stop != tt.stop {
+				t.Errorf("markers = %d %d, want %d %d", start, stop, tt.start, tt.stop)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+type sChmod struct {
+	f      *SFile
+	sPaths `yaml:",inline"`
+
+	Mode      string `json:"mode" yaml:"mode"`         // 八进制, 如 0644
+	DirMode   string `json:"dir_mode" yaml:"dir_mode"` // 递归时目录的权限, 默认同mode
+	Recursive bool   `json:"recursive" yaml:"recursive"`
+}
+
+func (c *sChmod) run(ctx context.Context) error {
+	mode, err := parseMode(c.Mode)
+	if err != nil {
+		return err
+	}
+	dirMode := mode
+	if c.DirMode != "" {
+		if dirMode, err = parseMode(c.DirMode); err != nil {
+			return err
+		}
+	}
+	paths, err := c.f.globs(c.list())
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no file matched")
+	}
+	return c.f.walk(ctx, paths, c.Recursive, func(path string, info fs.FileInfo) error {
+		// chmod作用于链接指向的文件, 跳过链接
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		want := mode
+		if info.IsDir() {
+			want = dirMode
+		}
+		if info.Mode().Perm() == want {
+			return nil
+		}
+		c.f.storage.Log().Writef("chmod %04o %s", want, c.f.display(path))
+		return os.Chmod(path, want)
+	})
+}
+
+type sChown struct {
+	f      *SFile
+	sPaths `yaml:",inline"`
+
+	User      string `json:"user" yaml:"user"`   // 用户名或uid
+	Group     string `json:"group" yaml:"group"` // 组名或gid
+	Recursive bool   `json:"recursive" yaml:"recursive"`
+}
+
+func (c *sChown) run(ctx context.Context) error {
+	if c.User == "" && c.Group == "" {
+		return errors.New("user or group is required")
+	}
+	uid, gid := -1, -1
+	if c.User != "" {
+		u, err := user.Lookup(c.User)
+		if err != nil {
+			if u, err = user.LookupId(c.User); err != nil {
+				return fmt.Errorf("unknown user %s", c.User)
+			}
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("user %s has no numeric uid", c.User)
+		}
+	}
+	if c.Group != "" {
+		g, err := user.LookupGroup(c.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(c.Group); err != nil {
+				return fmt.Errorf("unknown group %s", c.Group)
+			}
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return fmt.Errorf("group %s has no numeric gid", c.Group)
+		}
+	}
+	paths, err := c.f.globs(c.list())
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no file matched")
+	}
+	return c.f.walk(ctx, paths, c.Recursive, func(path string, _ fs.FileInfo) error {
+		c.f.storage.Log().Writef("chown %s:%s %s", c.User, c.Group, c.f.display(path))
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// walk 对路径执行操作, recursive时包含目录下的所有文件
+func (f *SFile) walk(ctx context.Context, paths []string, recursive bool, fn func(path string, info fs.FileInfo) error) error {
+	for _, path := range paths {
+		if !recursive {
+			info, err := os.Lstat(path)
+			if err != nil {
+				return err
+			}
+			if err = fn(path, info); err != nil {
+				return err
+			}
+			continue
+		}
+		err := filepath.Walk(path, func(name string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fn(name, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, errors.New("mode is required")
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o7777 {
+		return 0, fmt.Errorf("invalid mode %s", s)
+	}
+	return os.FileMode(mode), nil
+}
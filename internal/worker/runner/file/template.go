@@ -0,0 +1,81 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/pkg/jinja"
+)
+
+type sTemplate struct {
+	f *SFile
+
+	Src     string         `json:"src" yaml:"src"`         // 模板文件
+	Content string         `json:"content" yaml:"content"` // 模板内容, 与src二选一
+	Dest    string         `json:"dest" yaml:"dest"`
+	Vars    map[string]any `json:"vars" yaml:"vars"` // 模板变量, 覆盖同名环境变量
+	Mode    string         `json:"mode" yaml:"mode"`
+}
+
+func (t *sTemplate) run(context.Context) error {
+	var tpl = t.Content
+	if t.Src != "" {
+		src, err := t.f.path(t.Src)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		tpl = string(b)
+	}
+	if tpl == "" {
+		return errors.New("src or content is required")
+	}
+	dest, err := t.f.path(t.Dest)
+	if err != nil {
+		return err
+	}
+	var mode os.FileMode = 0644
+	if t.Mode != "" {
+		if mode, err = parseMode(t.Mode); err != nil {
+			return err
+		}
+	}
+
+	var data = make(map[string]any, len(t.f.envs)+len(t.Vars))
+	for k, v := range t.f.envs {
+		data[k] = v
+	}
+	for k, v := range t.Vars {
+		data[k] = v
+	}
+	content, err := jinja.Parse(tpl, data)
+	if err != nil {
+		return err
+	}
+
+	if old, err := os.ReadFile(dest); err == nil && bytes.Equal(old, []byte(content)) {
+		t.f.storage.Log().Writef("%s is up to date", t.f.display(dest))
+		if t.Mode != "" {
+			return os.Chmod(dest, mode)
+		}
+		return nil
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	t.f.storage.Log().Writef("render %s", t.f.display(dest))
+	if err = os.WriteFile(dest, []byte(content), mode); err != nil {
+		return err
+	}
+	if t.Mode != "" {
+		return os.Chmod(dest, mode)
+	}
+	return nil
+}
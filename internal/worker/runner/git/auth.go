@@ -0,0 +1,154 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// env 依次从步骤和任务环境变量中获取
+func (g *SGit) env(name string) string {
+	if value, err := g.storage.Env().Get(name); err == nil && value != "" {
+		return value
+	}
+	value, _ := g.storage.GlobalEnv().Get(name)
+	return value
+}
+
+// auth 按仓库地址协议从环境变量生成认证方式, 无需认证时返回nil
+func (g *SGit) auth() (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(g.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch endpoint.Protocol {
+	case "http", "https":
+		password := g.env("GIT_PASSWORD")
+		if password == "" {
+			password = g.env("GIT_TOKEN")
+		}
+		if password == "" {
+			return nil, nil
+		}
+		username := g.env("GIT_USERNAME")
+		if username == "" {
+			username = "git"
+		}
+		return &githttp.BasicAuth{
+			Username: username,
+			Password: password,
+		}, nil
+	case "ssh":
+		return g.sshAuth(endpoint.User)
+	default:
+		return nil, nil
+	}
+}
+
+// sshAuth 依次使用私钥, 密码及ssh-agent认证
+func (g *SGit) sshAuth(user string) (transport.AuthMethod, error) {
+	if user == "" {
+		user = "git"
+	}
+	callback, err := g.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	helper := gitssh.HostKeyCallbackHelper{HostKeyCallback: callback}
+	if key := g.env("GIT_SSH_KEY"); key != "" {
+		pem, _err := os.ReadFile(key)
+		if _err != nil {
+			pem = []byte(key)
+		}
+		auth, err := gitssh.NewPublicKeys(user, pem, g.env("GIT_SSH_PASSPHRASE"))
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		auth.HostKeyCallbackHelper = helper
+		return auth, nil
+	}
+	if password := g.env("GIT_PASSWORD"); password != "" {
+		return &gitssh.Password{
+			User:                  user,
+			Password:              password,
+			HostKeyCallbackHelper: helper,
+		}, nil
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		auth, err := gitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("connect ssh agent: %w", err)
+		}
+		auth.HostKeyCallbackHelper = helper
+		return auth, nil
+	}
+	return nil, errors.New("no ssh auth method, set GIT_SSH_KEY, GIT_PASSWORD or SSH_AUTH_SOCK")
+}
+
+// hostKeyCallback 主机密钥校验, 优先使用指纹, 其次known_hosts
+func (g *SGit) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if fingerprint := g.env("GIT_SSH_HOST_KEY"); fingerprint != "" {
+		return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != fingerprint {
+				return fmt.Errorf("host key mismatch for %s: %s", hostname, ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}, nil
+	}
+	if insecure, _ := strconv.ParseBool(g.env("GIT_SSH_INSECURE")); insecure {
+		g.storage.Log().Write("WARNING: host key verification is disabled")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	knownHosts := g.env("GIT_KNOWN_HOSTS")
+	switch {
+	case knownHosts == "":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	case !strings.ContainsAny(knownHosts, " \n"):
+		// 文件路径
+	default:
+		// known_hosts内容, 写入临时文件
+		file, err := os.CreateTemp("", "known_hosts")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(knownHosts)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = file.Name()
+	}
+	callback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("load known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// redactURL 隐藏地址中的密码
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "******")
+	}
+	return u.String()
+}
@@ -0,0 +1,459 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"gopkg.in/yaml.v3"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+const remoteName = "origin"
+
+type SGit struct {
+	storage   storage.IStep
+	workspace string
+
+	URL        string `json:"url" yaml:"url"`
+	Ref        string `json:"ref" yaml:"ref"` // 分支, 标签, 完整引用名或提交, 依次匹配
+	Branch     string `json:"branch" yaml:"branch"`
+	Tag        string `json:"tag" yaml:"tag"`
+	Commit     string `json:"commit" yaml:"commit"`
+	Path       string `json:"path" yaml:"path"`   // 工作目录下的检出目录, 默认为仓库名
+	Depth      int    `json:"depth" yaml:"depth"` // 浅克隆深度, 使用镜像缓存时忽略
+	Submodules bool   `json:"submodules" yaml:"submodules"`
+	Mirror     *bool  `json:"mirror" yaml:"mirror"` // 是否使用镜像缓存, 未指定depth时默认开启
+}
+
+// sTarget 解析后的检出目标
+type sTarget struct {
+	name   plumbing.ReferenceName // 远端引用名, 检出提交时为空
+	hash   plumbing.Hash
+	branch string
+}
+
+func New(storage storage.IStep, workspace string) (*SGit, error) {
+	return &SGit{
+		storage:   storage,
+		workspace: workspace,
+	}, nil
+}
+
+func (g *SGit) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			g.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+	if err = g.init(); err != nil {
+		g.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	timeout, err := g.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	auth, err := g.auth()
+	if err != nil {
+		g.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	commit, err := g.checkout(ctx, auth)
+	if err != nil {
+		if ctx.Err() != nil {
+			if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+				return common.CodeTimeout, common.ErrTimeOut
+			}
+			return common.CodeKilled, common.ErrManual
+		}
+		g.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+	if err = g.outputs(commit); err != nil {
+		g.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	return common.CodeSuccess, nil
+}
+
+func (g *SGit) init() error {
+	content, err := g.storage.Content()
+	if err != nil {
+		return err
+	}
+	content = strings.TrimSpace(content)
+	if err = json.Unmarshal([]byte(content), g); err != nil {
+		if err = yaml.Unmarshal([]byte(content), g); err != nil {
+			// 内容仅为仓库地址
+			g.URL = content
+		}
+	}
+	if g.URL == "" {
+		return errors.New("url is required")
+	}
+	var refs int
+	for _, v := range []string{g.Ref, g.Branch, g.Tag, g.Commit} {
+		if v != "" {
+			refs++
+		}
+	}
+	if refs > 1 {
+		return errors.New("only one of ref, branch, tag and commit can be specified")
+	}
+	if g.Commit != "" && !plumbing.IsHash(g.Commit) {
+		return fmt.Errorf("commit %s must be a full sha", g.Commit)
+	}
+	if g.Path == "" {
+		g.Path = repoName(g.URL)
+	}
+	if g.Mirror == nil {
+		mirror := g.Depth <= 0
+		g.Mirror = &mirror
+	}
+	return nil
+}
+
+// checkout 克隆或更新仓库并检出目标, 使用镜像缓存时从本地镜像获取对象
+func (g *SGit) checkout(ctx context.Context, auth transport.AuthMethod) (*object.Commit, error) {
+	dir := filepath.Join(g.workspace, utils.PathEscape(g.Path))
+	if err := utils.EnsureDirExist(dir); err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		g.storage.Log().Writef("initialize repository in %s", g.Path)
+		repo, err = git.PlainInit(dir, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var source, sourceAuth, depth = g.URL, auth, g.Depth
+	if *g.Mirror {
+		if depth > 0 {
+			g.storage.Log().Write("depth is ignored when mirror cache is enabled")
+		}
+		source, err = g.mirror(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		sourceAuth, depth = nil, 0
+	}
+	if err = setRemote(repo, source); err != nil {
+		return nil, err
+	}
+	// 检出完成后恢复远端地址, 便于后续步骤直接使用git命令
+	defer func() {
+		if source != g.URL {
+			_ = setRemote(repo, g.URL)
+		}
+	}()
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: sourceAuth})
+	if err != nil {
+		return nil, fmt.Errorf("list remote refs: %w", err)
+	}
+	target, err := g.resolve(refs)
+	if err != nil {
+		return nil, err
+	}
+
+	var refSpecs []config.RefSpec
+	var local plumbing.ReferenceName
+	switch {
+	case target.name == "":
+		// 任意提交需获取全部历史
+		refSpecs = []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", remoteName)),
+			"+refs/tags/*:refs/tags/*",
+		}
+		depth = 0
+	case target.name.IsBranch():
+		local = plumbing.NewRemoteReferenceName(remoteName, target.name.Short())
+		refSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", target.name, local))}
+	default:
+		local = target.name
+		refSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", target.name, local))}
+	}
+	g.storage.Log().Writef("fetch %s from %s", g.describe(target), redactURL(g.URL))
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   refSpecs,
+		Depth:      depth,
+		Auth:       sourceAuth,
+		Progress:   g.progress(),
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+
+	hash := target.hash
+	if local != "" {
+		ref, err := repo.Reference(local, true)
+		if err != nil {
+			return nil, err
+		}
+		hash = ref.Hash()
+	}
+	commit, err := peel(repo, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	var opts = &git.CheckoutOptions{
+		Hash:  commit.Hash,
+		Force: true,
+	}
+	if target.branch != "" {
+		// 本地分支指向获取到的提交
+		branch := plumbing.NewBranchReferenceName(target.branch)
+		if err = repo.Storer.SetReference(plumbing.NewHashReference(branch, commit.Hash)); err != nil {
+			return nil, err
+		}
+		opts = &git.CheckoutOptions{
+			Branch: branch,
+			Force:  true,
+		}
+	}
+	if err = worktree.Checkout(opts); err != nil {
+		return nil, fmt.Errorf("checkout: %w", err)
+	}
+	g.storage.Log().Writef("checkout %s at %s", g.describe(target), commit.Hash)
+
+	if g.Submodules {
+		if source != g.URL {
+			// 子模块相对地址基于真实远端地址解析
+			if err = setRemote(repo, g.URL); err != nil {
+				return nil, err
+			}
+			source = g.URL
+		}
+		if err = g.submodules(ctx, worktree, auth); err != nil {
+			return nil, err
+		}
+	}
+	return commit, nil
+}
+
+// resolve 按远端引用列表解析检出目标
+func (g *SGit) resolve(refs []*plumbing.Reference) (*sTarget, error) {
+	var remote = make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, ref := range refs {
+		remote[ref.Name()] = ref
+	}
+	find := func(names ...plumbing.ReferenceName) *sTarget {
+		for _, name := range names {
+			if ref, ok := remote[name]; ok && ref.Type() == plumbing.HashReference {
+				target := &sTarget{name: name, hash: ref.Hash()}
+				if name.IsBranch() {
+					target.branch = name.Short()
+				}
+				return target
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case g.Commit != "":
+		return &sTarget{hash: plumbing.NewHash(g.Commit)}, nil
+	case g.Branch != "":
+		if target := find(plumbing.NewBranchReferenceName(g.Branch)); target != nil {
+			return target, nil
+		}
+		return nil, fmt.Errorf("branch %s not found", g.Branch)
+	case g.Tag != "":
+		if target := find(plumbing.NewTagReferenceName(g.Tag)); target != nil {
+			return target, nil
+		}
+		return nil, fmt.Errorf("tag %s not found", g.Tag)
+	case g.Ref != "":
+		name := plumbing.ReferenceName(g.Ref)
+		if target := find(name, plumbing.NewBranchReferenceName(g.Ref), plumbing.NewTagReferenceName(g.Ref)); target != nil {
+			return target, nil
+		}
+		if plumbing.IsHash(g.Ref) {
+			return &sTarget{hash: plumbing.NewHash(g.Ref)}, nil
+		}
+		return nil, fmt.Errorf("ref %s not found", g.Ref)
+	default:
+		// 远端默认分支
+		if head, ok := remote[plumbing.HEAD]; ok {
+			if head.Type() == plumbing.SymbolicReference {
+				if target := find(head.Target()); target != nil {
+					return target, nil
+				}
+			}
+			for _, ref := range refs {
+				if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+					return find(ref.Name()), nil
+				}
+			}
+		}
+		if target := find(plumbing.Master, plumbing.Main); target != nil {
+			return target, nil
+		}
+		return nil, errors.New("remote default branch not found, specify ref")
+	}
+}
+
+func (g *SGit) describe(target *sTarget) string {
+	switch {
+	case target.name == "":
+		return "commit " + target.hash.String()
+	case target.name.IsBranch():
+		return "branch " + target.name.Short()
+	case target.name.IsTag():
+		return "tag " + target.name.Short()
+	default:
+		return "ref " + target.name.String()
+	}
+}
+
+func (g *SGit) submodules(ctx context.Context, worktree *git.Worktree, auth transport.AuthMethod) error {
+	subs, err := worktree.Submodules()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		g.storage.Log().Writef("update submodule %s", sub.Config().Path)
+		err = sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+			Auth:              auth,
+		})
+		if err != nil {
+			return fmt.Errorf("submodule %s: %w", sub.Config().Path, err)
+		}
+	}
+	return nil
+}
+
+// outputs 保存检出的提交信息
+func (g *SGit) outputs(commit *object.Commit) error {
+	message := strings.TrimSpace(commit.Message)
+	g.storage.Log().Writef("commit %s", commit.Hash)
+	g.storage.Log().Writef("author %s <%s>", commit.Author.Name, commit.Author.Email)
+	g.storage.Log().Writef("date   %s", commit.Author.When.Format(time.RFC3339))
+	for _, line := range strings.Split(message, "\n") {
+		g.storage.Log().Write("    " + line)
+	}
+	return g.storage.Output().Insert(
+		&models.SEnv{Name: "GIT_COMMIT", Value: commit.Hash.String()},
+		&models.SEnv{Name: "GIT_COMMIT_SHORT", Value: commit.Hash.String()[:7]},
+		&models.SEnv{Name: "GIT_AUTHOR", Value: commit.Author.Name},
+		&models.SEnv{Name: "GIT_AUTHOR_EMAIL", Value: commit.Author.Email},
+		&models.SEnv{Name: "GIT_COMMIT_TIME", Value: commit.Author.When.Format(time.RFC3339)},
+		&models.SEnv{Name: "GIT_MESSAGE", Value: message},
+		&models.SEnv{Name: "GIT_PATH", Value: g.Path},
+	)
+}
+
+// peel 标签解引用到提交
+func peel(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	for {
+		obj, err := repo.Object(plumbing.AnyObject, hash)
+		if err != nil {
+			return nil, fmt.Errorf("object %s: %w", hash, err)
+		}
+		switch o := obj.(type) {
+		case *object.Commit:
+			return o, nil
+		case *object.Tag:
+			hash = o.Target
+		default:
+			return nil, fmt.Errorf("object %s is not a commit", hash)
+		}
+	}
+}
+
+// repoName 仓库地址中的仓库名
+func repoName(url string) string {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git")
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func setRemote(repo *git.Repository, url string) error {
+	if _, err := repo.Remote(remoteName); err == nil {
+		if err = repo.DeleteRemote(remoteName); err != nil {
+			return err
+		}
+	}
+	_, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+	})
+	return err
+}
+
+func (g *SGit) Clear() error {
+	return nil
+}
+
+// progress 远端进度按行写入日志, 只保留每个阶段的最终结果
+func (g *SGit) progress() *sProgress {
+	return &sProgress{g: g}
+}
+
+type sProgress struct {
+	g    *SGit
+	line []byte
+}
+
+func (p *sProgress) Write(b []byte) (int, error) {
+	for _, c := range b {
+		switch c {
+		case '\r':
+			p.line = p.line[:0]
+		case '\n':
+			if line := strings.TrimSpace(string(p.line)); line != "" {
+				p.g.storage.Log().Write(line)
+			}
+			p.line = p.line[:0]
+		default:
+			p.line = append(p.line, c)
+		}
+	}
+	return len(b), nil
+}
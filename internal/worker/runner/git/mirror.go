@@ -0,0 +1,94 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+
+	appconfig "github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+// mirrorProtocol 进程内读取镜像仓库, 不依赖git命令
+const mirrorProtocol = "aef-mirror"
+
+var (
+	mirrorOnce  sync.Once
+	mirrorLocks sync.Map
+
+	nameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+func mirrorDir() string {
+	return filepath.Join(appconfig.App.CacheDir(), "git")
+}
+
+// mirror 更新仓库的本地镜像, 返回供检出使用的地址
+//
+// 镜像为裸仓库, 保存全部分支及标签, 同一仓库的更新串行执行
+func (g *SGit) mirror(ctx context.Context, auth transport.AuthMethod) (string, error) {
+	root := mirrorDir()
+	mirrorOnce.Do(func() {
+		client.InstallProtocol(mirrorProtocol, server.NewClient(server.NewFilesystemLoader(osfs.New(root))))
+	})
+
+	sum := sha256.Sum256([]byte(g.URL))
+	name := fmt.Sprintf("%s-%s", hex.EncodeToString(sum[:8]), nameRegexp.ReplaceAllString(repoName(g.URL), "_"))
+	dir := filepath.Join(root, name)
+
+	value, _ := mirrorLocks.LoadOrStore(dir, new(sync.Mutex))
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if err = utils.EnsureDirExist(dir); err != nil {
+			return "", err
+		}
+		g.storage.Log().Writef("create mirror cache %s", name)
+		repo, err = git.PlainInit(dir, true)
+	}
+	if err != nil {
+		return "", fmt.Errorf("mirror cache: %w", err)
+	}
+	if err = setRemote(repo, g.URL); err != nil {
+		return "", err
+	}
+
+	var refSpecs = []config.RefSpec{
+		"+refs/heads/*:refs/heads/*",
+		"+refs/tags/*:refs/tags/*",
+	}
+	// 分支及标签以外的引用, 如 refs/pull/1/head
+	if strings.HasPrefix(g.Ref, "refs/") && !strings.HasPrefix(g.Ref, "refs/heads/") && !strings.HasPrefix(g.Ref, "refs/tags/") {
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+%s:%s", g.Ref, g.Ref)))
+	}
+	g.storage.Log().Writef("update mirror cache from %s", redactURL(g.URL))
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+		Progress:   g.progress(),
+		Tags:       git.NoTags,
+		Force:      true,
+		Prune:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("update mirror cache: %w", err)
+	}
+	return fmt.Sprintf("%s:///%s", mirrorProtocol, name), nil
+}
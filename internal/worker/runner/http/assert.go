@@ -0,0 +1,196 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+type SAssert struct {
+	Status  []string      `json:"status" yaml:"status"`   // 允许的状态码, 支持2xx形式
+	JSON    []*SJSONMatch `json:"json" yaml:"json"`       // gjson路径匹配
+	Body    []string      `json:"body" yaml:"body"`       // 响应体需匹配的正则
+	Latency string        `json:"latency" yaml:"latency"` // 最大耗时
+}
+
+type SJSONMatch struct {
+	Path    string `json:"path" yaml:"path"`
+	Exists  *bool  `json:"exists" yaml:"exists"`
+	Equals  any    `json:"equals" yaml:"equals"`
+	Matches string `json:"matches" yaml:"matches"` // 正则
+}
+
+type sResult struct {
+	resp    *http.Response
+	body    []byte
+	latency time.Duration
+}
+
+// value 按表达式取值
+//
+//	status: 状态码
+//	latency: 耗时(毫秒)
+//	body: 响应体
+//	header.<name>: 响应头
+//	json.<path>: gjson路径
+func (r *sResult) value(expr string) (string, error) {
+	switch {
+	case expr == "status":
+		return strconv.Itoa(r.resp.StatusCode), nil
+	case expr == "latency":
+		return strconv.FormatInt(r.latency.Milliseconds(), 10), nil
+	case expr == "body":
+		return string(r.body), nil
+	case strings.HasPrefix(expr, "header."):
+		return r.resp.Header.Get(strings.TrimPrefix(expr, "header.")), nil
+	case strings.HasPrefix(expr, "json."):
+		if !gjson.ValidBytes(r.body) {
+			return "", errors.New("response body is not valid json")
+		}
+		res := gjson.GetBytes(r.body, strings.TrimPrefix(expr, "json."))
+		if !res.Exists() {
+			return "", fmt.Errorf("%s not found in response", expr)
+		}
+		return res.String(), nil
+	default:
+		return "", fmt.Errorf("unknown output expression %s", expr)
+	}
+}
+
+// capture 保存输出
+func (h *SHttp) capture(r *sResult) error {
+	if len(h.Outputs) == 0 {
+		return nil
+	}
+	var outputs models.SEnvs
+	for name, expr := range h.Outputs {
+		value, err := r.value(expr)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+		outputs = append(outputs, &models.SEnv{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return h.storage.Output().Insert(outputs...)
+}
+
+// assert 校验响应, 返回所有未通过的断言
+func (h *SHttp) assert(r *sResult) error {
+	failures := h.Assert.Check(r.resp, r.body, r.latency)
+	if len(failures) == 0 {
+		return nil
+	}
+	for _, failure := range failures {
+		h.storage.Log().Write("assertion failed: " + failure)
+	}
+	return fmt.Errorf("assertion failed: %s", strings.Join(failures, "; "))
+}
+
+// Check 校验响应, 返回所有未通过的断言, 未指定状态码时只允许2xx
+func (a *SAssert) Check(resp *http.Response, body []byte, latency time.Duration) (failures []string) {
+	var status = []string{"2xx"}
+	if a != nil {
+		if len(a.Status) > 0 {
+			status = a.Status
+		}
+		failures = append(failures, a.checkJSON(body)...)
+		for _, expr := range a.Body {
+			reg, err := regexp.Compile(expr)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("invalid body regexp %s: %v", expr, err))
+				continue
+			}
+			if !reg.Match(body) {
+				failures = append(failures, fmt.Sprintf("body does not match %s", expr))
+			}
+		}
+		if a.Latency != "" {
+			limit, err := time.ParseDuration(a.Latency)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("invalid latency %s", a.Latency))
+			} else if latency > limit {
+				failures = append(failures, fmt.Sprintf("latency %s exceeds %s", latency.Round(time.Millisecond), limit))
+			}
+		}
+	}
+	if !matchStatus(status, resp.StatusCode) {
+		failures = slices.Insert(failures, 0, fmt.Sprintf("status %d not in %v", resp.StatusCode, status))
+	}
+	return
+}
+
+func matchStatus(status []string, code int) bool {
+	for _, s := range status {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if len(s) == 3 && strings.HasSuffix(s, "xx") {
+			if strconv.Itoa(code/100) == s[:1] {
+				return true
+			}
+			continue
+		}
+		if s == strconv.Itoa(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *SAssert) checkJSON(body []byte) (failures []string) {
+	if len(a.JSON) == 0 {
+		return
+	}
+	if !gjson.ValidBytes(body) {
+		return []string{"response body is not valid json"}
+	}
+	for _, m := range a.JSON {
+		if m == nil || m.Path == "" {
+			continue
+		}
+		res := gjson.GetBytes(body, m.Path)
+		if m.Exists != nil && res.Exists() != *m.Exists {
+			failures = append(failures, fmt.Sprintf("json %s exists is %t", m.Path, res.Exists()))
+			continue
+		}
+		if m.Equals != nil && !equals(res, m.Equals) {
+			failures = append(failures, fmt.Sprintf("json %s is %s, expected %v", m.Path, res.Raw, m.Equals))
+		}
+		if m.Matches != "" {
+			reg, err := regexp.Compile(m.Matches)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("invalid json regexp %s: %v", m.Matches, err))
+				continue
+			}
+			if !reg.MatchString(res.String()) {
+				failures = append(failures, fmt.Sprintf("json %s does not match %s", m.Path, m.Matches))
+			}
+		}
+	}
+	return
+}
+
+// equals 按期望值的类型比较
+func equals(res gjson.Result, expected any) bool {
+	switch v := expected.(type) {
+	case bool:
+		return (res.Type == gjson.True || res.Type == gjson.False) && res.Bool() == v
+	case int:
+		return res.Type == gjson.Number && res.Float() == float64(v)
+	case float64:
+		return res.Type == gjson.Number && res.Float() == v
+	case string:
+		return res.String() == v
+	default:
+		return res.Exists() && fmt.Sprint(res.Value()) == fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,469 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
+)
+
+const (
+	// 日志中请求及响应体的最大长度
+	maxLogBody = 4 * 1024
+	// 响应体读取上限
+	maxBody = 32 * 1024 * 1024
+)
+
+// 需要在日志中脱敏的请求头
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+type SHttp struct {
+	storage   storage.IStep
+	workspace string
+	secrets   []string
+
+	Method          string            `json:"method" yaml:"method"`
+	URL             string            `json:"url" yaml:"url"`
+	Query           map[string]string `json:"query" yaml:"query"`
+	Headers         map[string]string `json:"headers" yaml:"headers"`
+	Body            string            `json:"body" yaml:"body"`
+	JSON            any               `json:"json" yaml:"json"`
+	Form            map[string]string `json:"form" yaml:"form"`
+	BodyFile        string            `json:"body_file" yaml:"body_file"` // 工作目录下的文件作为请求体
+	Auth            *SAuth            `json:"auth" yaml:"auth"`
+	TLS             *STLS             `json:"tls" yaml:"tls"`
+	Timeout         string            `json:"timeout" yaml:"timeout"`
+	FollowRedirects *bool             `json:"follow_redirects" yaml:"follow_redirects"`
+	SaveTo          string            `json:"save_to" yaml:"save_to"` // 响应体保存到工作目录下的文件
+	Assert          *SAssert          `json:"assert" yaml:"assert"`
+	Outputs         map[string]string `json:"outputs" yaml:"outputs"`
+}
+
+type SAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Bearer   string `json:"bearer" yaml:"bearer"`
+}
+
+type STLS struct {
+	Insecure   bool   `json:"insecure" yaml:"insecure"`
+	CA         string `json:"ca" yaml:"ca"`     // PEM内容或文件路径
+	Cert       string `json:"cert" yaml:"cert"` // 客户端证书, PEM内容或文件路径
+	Key        string `json:"key" yaml:"key"`   // 客户端私钥, PEM内容或文件路径
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+func New(storage storage.IStep, workspace string) (*SHttp, error) {
+	return &SHttp{
+		storage:   storage,
+		workspace: workspace,
+	}, nil
+}
+
+func (h *SHttp) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			h.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+	if err = h.init(); err != nil {
+		h.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	timeout, err := h.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	client, err := h.client()
+	if err != nil {
+		h.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	req, err := h.request(ctx)
+	if err != nil {
+		h.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+				return common.CodeTimeout, common.ErrTimeOut
+			}
+			return common.CodeKilled, common.ErrManual
+		}
+		h.storage.Log().Write(h.redact(err.Error()))
+		return common.CodeFailed, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	latency := time.Since(start)
+	if err != nil {
+		h.storage.Log().Write(h.redact(err.Error()))
+		return common.CodeFailed, err
+	}
+	h.logResponse(resp, body, latency)
+
+	if h.SaveTo != "" {
+		if err = h.save(body); err != nil {
+			h.storage.Log().Write(err.Error())
+			return common.CodeSystemErr, err
+		}
+	}
+	var result = &sResult{
+		resp:    resp,
+		body:    body,
+		latency: latency,
+	}
+	// 先断言, 错误响应无法提取输出时仍报告断言结果
+	if err = h.assert(result); err != nil {
+		return common.CodeFailed, err
+	}
+	if err = h.capture(result); err != nil {
+		h.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+	return common.CodeSuccess, nil
+}
+
+func (h *SHttp) init() error {
+	content, err := h.storage.Content()
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal([]byte(content), h); err != nil {
+		if err = yaml.Unmarshal([]byte(content), h); err != nil {
+			return err
+		}
+	}
+	h.expandAll()
+	if h.URL == "" {
+		return errors.New("url is required")
+	}
+	if h.Method == "" {
+		h.Method = http.MethodGet
+	}
+	h.Method = strings.ToUpper(h.Method)
+	if h.Auth != nil {
+		h.secrets = append(h.secrets, h.Auth.Password, h.Auth.Bearer)
+	}
+	return nil
+}
+
+// expandAll 替换请求中的${NAME}为步骤或任务环境变量, 未定义的保持原样
+func (h *SHttp) expandAll() {
+	var envs = make(map[string]string)
+	for _, env := range h.storage.GlobalEnv().List() {
+		envs[env.Name] = env.Value
+	}
+	for _, env := range h.storage.Env().List() {
+		envs[env.Name] = env.Value
+	}
+	envs["TASK_NAME"] = h.storage.TaskName()
+	envs["TASK_STEP_NAME"] = h.storage.Name()
+	envs["TASK_WORKSPACE"] = h.workspace
+	expand := func(content string) string {
+		return envRegexp.ReplaceAllStringFunc(content, func(s string) string {
+			name := envRegexp.FindStringSubmatch(s)[1]
+			value, ok := envs[name]
+			if !ok {
+				return s
+			}
+			if h.isSecret(name) {
+				h.secrets = append(h.secrets, value)
+			}
+			return value
+		})
+	}
+	expandMap := func(m map[string]string) {
+		for k, v := range m {
+			m[k] = expand(v)
+		}
+	}
+	h.URL = expand(h.URL)
+	h.Body = expand(h.Body)
+	h.BodyFile = expand(h.BodyFile)
+	h.JSON = expandAny(h.JSON, expand)
+	expandMap(h.Query)
+	expandMap(h.Headers)
+	expandMap(h.Form)
+	if h.Auth != nil {
+		h.Auth.Username = expand(h.Auth.Username)
+		h.Auth.Password = expand(h.Auth.Password)
+		h.Auth.Bearer = expand(h.Auth.Bearer)
+	}
+	if h.TLS != nil {
+		h.TLS.CA = expand(h.TLS.CA)
+		h.TLS.Cert = expand(h.TLS.Cert)
+		h.TLS.Key = expand(h.TLS.Key)
+		h.TLS.ServerName = expand(h.TLS.ServerName)
+	}
+}
+
+// expandAny 递归替换json请求体中的字符串
+func expandAny(v any, expand func(string) string) any {
+	switch val := v.(type) {
+	case string:
+		return expand(val)
+	case map[string]any:
+		for k, item := range val {
+			val[k] = expandAny(item, expand)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = expandAny(item, expand)
+		}
+	}
+	return v
+}
+
+// isSecret 根据名称判断是否为敏感变量
+func (h *SHttp) isSecret(name string) bool {
+	name = strings.ToUpper(name)
+	for _, v := range []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL"} {
+		if strings.Contains(name, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SHttp) redact(s string) string {
+	for _, secret := range h.secrets {
+		if len(secret) < 4 {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, "******")
+	}
+	return s
+}
+
+func (h *SHttp) client() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if h.TLS != nil {
+		config := &tls.Config{
+			InsecureSkipVerify: h.TLS.Insecure,
+			ServerName:         h.TLS.ServerName,
+		}
+		if h.TLS.CA != "" {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(h.readPEM(h.TLS.CA)) {
+				return nil, errors.New("invalid tls ca")
+			}
+			config.RootCAs = pool
+		}
+		if h.TLS.Cert != "" || h.TLS.Key != "" {
+			cert, err := tls.X509KeyPair(h.readPEM(h.TLS.Cert), h.readPEM(h.TLS.Key))
+			if err != nil {
+				return nil, fmt.Errorf("invalid tls client certificate: %w", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = config
+	}
+	client := &http.Client{
+		Transport: transport,
+	}
+	if h.Timeout != "" {
+		timeout, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %s", h.Timeout)
+		}
+		client.Timeout = timeout
+	}
+	if h.FollowRedirects != nil && !*h.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
+}
+
+// readPEM 内容不是PEM时作为工作目录下的文件路径读取
+func (h *SHttp) readPEM(s string) []byte {
+	if strings.Contains(s, "-----BEGIN") {
+		return []byte(s)
+	}
+	if name, err := h.path("file", s); err == nil {
+		if b, err := os.ReadFile(name); err == nil {
+			return b
+		}
+	}
+	return []byte(s)
+}
+
+// path 工作目录下的文件, 解析符号链接后需位于工作目录或挂载的卷目录内
+func (h *SHttp) path(kind, name string) (string, error) {
+	return common.Path(kind, h.workspace, name, volume.Roots(h.storage.TaskName())...)
+}
+
+func (h *SHttp) request(ctx context.Context) (*http.Request, error) {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Query) > 0 {
+		query := u.Query()
+		for k, v := range h.Query {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	var body []byte
+	var contentType string
+	switch {
+	case h.JSON != nil:
+		body, err = json.Marshal(h.JSON)
+		if err != nil {
+			return nil, err
+		}
+		contentType = "application/json"
+	case len(h.Form) > 0:
+		form := url.Values{}
+		for k, v := range h.Form {
+			form.Set(k, v)
+		}
+		body = []byte(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case h.BodyFile != "":
+		var name string
+		if name, err = h.path("body_file", h.BodyFile); err != nil {
+			return nil, err
+		}
+		body, err = os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		body = []byte(h.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range h.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	if h.Auth != nil {
+		switch {
+		case h.Auth.Bearer != "":
+			req.Header.Set("Authorization", "Bearer "+h.Auth.Bearer)
+		case h.Auth.Username != "":
+			req.SetBasicAuth(h.Auth.Username, h.Auth.Password)
+		}
+	}
+	h.logRequest(req, body)
+	return req, nil
+}
+
+func (h *SHttp) logRequest(req *http.Request, body []byte) {
+	h.storage.Log().Write(h.redact(fmt.Sprintf("> %s %s", req.Method, req.URL.String())))
+	h.logHeaders(">", req.Header)
+	h.logBody(body)
+}
+
+func (h *SHttp) logResponse(resp *http.Response, body []byte, latency time.Duration) {
+	h.storage.Log().Writef("< %s %s (%s)", resp.Proto, resp.Status, latency.Round(time.Millisecond))
+	h.logHeaders("<", resp.Header)
+	h.logBody(body)
+}
+
+func (h *SHttp) logHeaders(prefix string, header http.Header) {
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		value := strings.Join(header[name], ", ")
+		for _, v := range sensitiveHeaders {
+			if strings.EqualFold(name, v) {
+				value = "******"
+				break
+			}
+		}
+		h.storage.Log().Write(h.redact(fmt.Sprintf("%s %s: %s", prefix, name, value)))
+	}
+}
+
+func (h *SHttp) logBody(body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	var truncated bool
+	if len(body) > maxLogBody {
+		body, truncated = body[:maxLogBody], true
+	}
+	for _, line := range strings.Split(h.redact(string(body)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		h.storage.Log().Write(line)
+	}
+	if truncated {
+		h.storage.Log().Writef("... truncated, only the first %d bytes are shown", maxLogBody)
+	}
+}
+
+func (h *SHttp) save(body []byte) error {
+	name, err := h.path("save_to", h.SaveTo)
+	if err != nil {
+		return err
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(name)); err != nil {
+		return err
+	}
+	return os.WriteFile(name, body, os.ModePerm)
+}
+
+func (h *SHttp) Clear() error {
+	return nil
+}
@@ -8,3 +8,8 @@ type IRunner interface {
 	Run(ctx context.Context) (exit int64, err error)
 	Clear() error
 }
+
+// IResult 可选, 执行成功后由执行器提供的结果描述, 如changed/unchanged
+type IResult interface {
+	Result() string
+}
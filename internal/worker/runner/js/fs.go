@@ -0,0 +1,129 @@
+package js
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
+)
+
+type sFileInfo struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"isDir"`
+	ModTime int64  `json:"modTime"` // 毫秒时间戳
+}
+
+// fs 文件操作, 路径相对于任务工作目录且不能越出工作目录
+func (j *SJs) fs() map[string]any {
+	return map[string]any{
+		"readFile": func(name string) (string, error) {
+			path, err := j.path(name)
+			if err != nil {
+				return "", err
+			}
+			b, err := os.ReadFile(path)
+			return string(b), err
+		},
+		"writeFile": func(name, data string) error {
+			return j.writeFile(name, data, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+		},
+		"appendFile": func(name, data string) error {
+			return j.writeFile(name, data, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+		},
+		"exists": func(name string) bool {
+			path, err := j.path(name)
+			if err != nil {
+				return false
+			}
+			_, err = os.Stat(path)
+			return err == nil
+		},
+		"stat": func(name string) (*sFileInfo, error) {
+			path, err := j.path(name)
+			if err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			return fileInfo(info), nil
+		},
+		"readDir": func(name string) ([]*sFileInfo, error) {
+			path, err := j.path(name)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			var res = make([]*sFileInfo, 0, len(entries))
+			for _, entry := range entries {
+				info, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				res = append(res, fileInfo(info))
+			}
+			return res, nil
+		},
+		"mkdir": func(name string) error {
+			path, err := j.path(name)
+			if err != nil {
+				return err
+			}
+			return utils.EnsureDirExist(path)
+		},
+		"remove": func(name string) error {
+			path, err := j.path(name)
+			if err != nil {
+				return err
+			}
+			if path == filepath.Clean(j.workspace) {
+				return errors.New("cannot remove workspace")
+			}
+			return os.RemoveAll(path)
+		},
+	}
+}
+
+func (j *SJs) writeFile(name, data string, flag int) error {
+	path, err := j.path(name)
+	if err != nil {
+		return err
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(path)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, flag, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	return err
+}
+
+// path 转换为工作目录下的绝对路径, 解析符号链接后需位于工作目录或挂载的卷目录内
+func (j *SJs) path(name string) (string, error) {
+	// 绝对路径及..均视为相对于工作目录
+	rel, err := filepath.Rel(j.workspace, filepath.Join(j.workspace, utils.PathEscape(name)))
+	if err != nil {
+		return "", err
+	}
+	return common.Path("path", j.workspace, rel, volume.Roots(j.storage.TaskName())...)
+}
+
+func fileInfo(info os.FileInfo) *sFileInfo {
+	return &sFileInfo{
+		Name:    info.Name(),
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime().UnixMilli(),
+	}
+}
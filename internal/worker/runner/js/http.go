@@ -0,0 +1,136 @@
+package js
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 响应体读取上限
+const maxBody = 32 * 1024 * 1024
+
+type sRequest struct {
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Query    map[string]string `json:"query"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	JSON     any               `json:"json"`
+	Timeout  string            `json:"timeout"`
+	Insecure bool              `json:"insecure"`
+}
+
+type sResponse struct {
+	Status     int               `json:"status"`
+	StatusText string            `json:"statusText"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+}
+
+// Json 响应体按json解析, 脚本中为 resp.json()
+func (r *sResponse) Json() (any, error) {
+	var v any
+	err := json.Unmarshal([]byte(r.Body), &v)
+	return v, err
+}
+
+// http 同步http客户端, 请求随步骤取消
+func (j *SJs) http() map[string]any {
+	return map[string]any{
+		"request": j.request,
+		"get": func(url string, headers map[string]string) (*sResponse, error) {
+			return j.request(&sRequest{Method: http.MethodGet, URL: url, Headers: headers})
+		},
+		"post": func(url string, body any, headers map[string]string) (*sResponse, error) {
+			req := &sRequest{Method: http.MethodPost, URL: url, Headers: headers}
+			if s, ok := body.(string); ok {
+				req.Body = s
+			} else {
+				req.JSON = body
+			}
+			return j.request(req)
+		},
+	}
+}
+
+func (j *SJs) request(r *sRequest) (*sResponse, error) {
+	if r == nil || r.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Query) > 0 {
+		query := u.Query()
+		for k, v := range r.Query {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+
+	var body = []byte(r.Body)
+	if r.JSON != nil {
+		body, err = json.Marshal(r.JSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(j.ctx, strings.ToUpper(r.Method), u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if r.JSON != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if r.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{
+		Transport: transport,
+	}
+	if r.Timeout != "" {
+		client.Timeout, err = time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %s", r.Timeout)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string, len(resp.Header))
+	for k, v := range resp.Header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	return &sResponse{
+		Status:     resp.StatusCode,
+		StatusText: resp.Status,
+		Headers:    headers,
+		Body:       string(b),
+	}, nil
+}
@@ -0,0 +1,112 @@
+package js
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/dop251/goja"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+// 脚本调用栈深度上限, 避免无限递归耗尽内存
+const maxCallStackSize = 1024
+
+type SJs struct {
+	vm        *goja.Runtime
+	storage   storage.IStep
+	workspace string
+
+	ctx context.Context
+}
+
+func New(storage storage.IStep, workspace string) (*SJs, error) {
+	return &SJs{
+		storage:   storage,
+		workspace: workspace,
+	}, nil
+}
+
+func (j *SJs) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			j.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+
+	timeout, err := j.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	j.ctx = ctx
+
+	content, err := j.storage.Content()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	program, err := goja.Compile(j.storage.Name()+".js", content, false)
+	if err != nil {
+		j.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+
+	j.vm = goja.New()
+	j.vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	j.vm.SetMaxCallStackSize(maxCallStackSize)
+	if err = j.register(); err != nil {
+		j.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	// 步骤取消或超时后中断脚本执行
+	stop := context.AfterFunc(ctx, func() {
+		j.vm.Interrupt(context.Cause(ctx))
+	})
+	defer stop()
+
+	_, err = j.vm.RunProgram(program)
+	if err == nil {
+		return common.CodeSuccess, nil
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if e, ok := interrupted.Value().(*sExit); ok {
+			return e.code, nil
+		}
+	}
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	var overflow *goja.StackOverflowError
+	if errors.As(err, &overflow) {
+		err = fmt.Errorf("maximum call stack size %d exceeded%s", maxCallStackSize, err)
+		j.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		j.storage.Log().Write(exception.String())
+		return common.CodeFailed, errors.New(exception.Value().String())
+	}
+	j.storage.Log().Write(err.Error())
+	return common.CodeFailed, err
+}
+
+func (j *SJs) Clear() error {
+	return nil
+}
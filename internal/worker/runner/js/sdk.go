@@ -0,0 +1,122 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+// sExit 由aef.exit触发, 中断脚本并以指定退出码返回
+type sExit struct {
+	code int64
+}
+
+// register 注册console及aef全局对象
+func (j *SJs) register() error {
+	console := j.vm.NewObject()
+	for _, name := range []string{"log", "info", "warn", "error", "debug"} {
+		if err := console.Set(name, j.log); err != nil {
+			return err
+		}
+	}
+	if err := j.vm.Set("console", console); err != nil {
+		return err
+	}
+
+	aef := j.vm.NewObject()
+	var exports = map[string]any{
+		"log":       j.log,
+		"env":       j.env,
+		"envs":      j.envs,
+		"workspace": j.workspace,
+		"taskName":  j.storage.TaskName(),
+		"stepName":  j.storage.Name(),
+		"setOutput": j.setOutput,
+		"sleep":     j.sleep,
+		"exit":      j.exit,
+		"fs":        j.fs(),
+		"http":      j.http(),
+	}
+	for name, value := range exports {
+		if err := aef.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return j.vm.Set("aef", aef)
+}
+
+// log 参数以空格拼接写入步骤日志, 对象按json输出
+func (j *SJs) log(call goja.FunctionCall) goja.Value {
+	var args = make([]string, 0, len(call.Arguments))
+	for _, arg := range call.Arguments {
+		args = append(args, j.format(arg))
+	}
+	j.storage.Log().Write(strings.Join(args, " "))
+	return goja.Undefined()
+}
+
+func (j *SJs) format(v goja.Value) string {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return fmt.Sprint(v)
+	}
+	if _, ok := v.(*goja.Object); !ok {
+		return v.String()
+	}
+	if _, ok := goja.AssertFunction(v); ok {
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v.String()
+	}
+	return string(b)
+}
+
+func (j *SJs) env(name string) string {
+	return j.envs()[name]
+}
+
+// envs 任务环境变量, 步骤环境变量优先
+func (j *SJs) envs() map[string]string {
+	var envs = make(map[string]string)
+	for _, env := range j.storage.GlobalEnv().List() {
+		envs[env.Name] = env.Value
+	}
+	for _, env := range j.storage.Env().List() {
+		envs[env.Name] = env.Value
+	}
+	envs["TASK_NAME"] = j.storage.TaskName()
+	envs["TASK_STEP_NAME"] = j.storage.Name()
+	envs["TASK_WORKSPACE"] = j.workspace
+	return envs
+}
+
+// setOutput 字符串原样保存, 其他类型按json保存
+func (j *SJs) setOutput(name string, value goja.Value) error {
+	if name == "" {
+		return fmt.Errorf("output name is required")
+	}
+	return j.storage.Output().Insert(&models.SEnv{
+		Name:  name,
+		Value: j.format(value),
+	})
+}
+
+// sleep 毫秒, 步骤取消时立即返回
+func (j *SJs) sleep(ms int64) {
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-j.ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func (j *SJs) exit(code int64) {
+	j.vm.Interrupt(&sExit{code: code})
+}
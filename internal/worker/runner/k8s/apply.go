@@ -0,0 +1,318 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+const defaultFieldManager = "autoexecflow"
+
+// sApplier 通过server-side apply应用任意清单
+type sApplier struct {
+	storage   storage.IStep
+	client    dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string // 未指定命名空间的对象使用的命名空间
+	manager   string
+	force     bool
+	check     bool
+	wait      bool
+	prune     labels.Selector // 为空时不清理
+
+	interval time.Duration // 等待就绪的轮询间隔
+}
+
+// sApplied 已应用的对象
+type sApplied struct {
+	mapping *meta.RESTMapping
+	obj     *unstructured.Unstructured
+}
+
+func (a *sApplied) key() string {
+	return a.mapping.Resource.String() + "|" + a.obj.GetNamespace() + "|" + a.obj.GetName()
+}
+
+func (k *SKubectl) apply(ctx context.Context) error {
+	objs, err := k.manifests()
+	if err != nil {
+		return err
+	}
+	namespace, err := k.getResourceValue("", k.Namespace, "NAMESPACE")
+	if err != nil {
+		return err
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	a := &sApplier{
+		storage:   k.storage,
+		client:    k.dynamicClient,
+		mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(k.client.Discovery())),
+		namespace: namespace,
+		manager:   k.FieldManager,
+		force:     k.Force,
+		check:     k.Check,
+		wait:      k.Wait == nil || *k.Wait,
+		interval:  2 * time.Second,
+	}
+	if a.manager == "" {
+		a.manager = defaultFieldManager
+	}
+	if k.Prune != "" {
+		if a.prune, err = labels.Parse(k.Prune); err != nil {
+			return fmt.Errorf("invalid prune selector: %w", err)
+		}
+	}
+	return a.run(ctx, objs)
+}
+
+func (a *sApplier) run(ctx context.Context, objs []*unstructured.Unstructured) error {
+	if a.prune != nil {
+		// 清理以标签识别资源, 清单中的对象必须带有该标签, 否则会在下次应用时被删除
+		for _, obj := range objs {
+			if !a.prune.Matches(labels.Set(obj.GetLabels())) {
+				return fmt.Errorf("%s %s does not match prune selector %s", obj.GetKind(), obj.GetName(), a.prune)
+			}
+		}
+	}
+	// 命名空间与CRD需要先于其他资源应用
+	sort.SliceStable(objs, func(i, j int) bool {
+		return applyOrder(objs[i]) < applyOrder(objs[j])
+	})
+
+	var applied []*sApplied
+	for _, obj := range objs {
+		res, err := a.applyOne(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		applied = append(applied, res)
+	}
+	if a.prune != nil {
+		if err := a.pruneAll(ctx, applied); err != nil {
+			return err
+		}
+	}
+	if !a.wait || a.check {
+		return nil
+	}
+	for _, res := range applied {
+		if err := a.waitReady(ctx, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOrder(obj *unstructured.Unstructured) int {
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// mapping 查找对象对应的资源, 找不到时刷新缓存重试, 用于同一清单中先定义的CRD
+func (a *sApplier) mapping(obj *unstructured.Unstructured) (*meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil && meta.IsNoMatchError(err) {
+		if mapper, ok := a.mapper.(meta.ResettableRESTMapper); ok {
+			mapper.Reset()
+			mapping, err = a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+	return mapping, err
+}
+
+func (a *sApplier) resource(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return a.client.Resource(mapping.Resource).Namespace(namespace)
+	}
+	return a.client.Resource(mapping.Resource)
+}
+
+func (a *sApplier) applyOne(ctx context.Context, obj *unstructured.Unstructured) (*sApplied, error) {
+	if obj.GetName() == "" {
+		return nil, errors.New("metadata.name is required by server-side apply")
+	}
+	mapping, err := a.mapping(obj)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(a.namespace)
+		}
+	} else {
+		obj.SetNamespace("")
+	}
+	client := a.resource(mapping, obj.GetNamespace())
+	live, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		live = nil
+	}
+
+	opts := metav1.ApplyOptions{FieldManager: a.manager, Force: a.force}
+	if a.check {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	result, err := client.Apply(ctx, obj.GetName(), obj, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	name := display(mapping, result)
+	switch {
+	case a.check:
+		lines := utils.DiffLines(sanitize(live), sanitize(result))
+		if len(lines) == 0 {
+			a.storage.Log().Writef("%s unchanged (dry run)", name)
+			break
+		}
+		if live == nil {
+			a.storage.Log().Writef("%s created (dry run)", name)
+		} else {
+			a.storage.Log().Writef("%s configured (dry run)", name)
+		}
+		for _, line := range lines {
+			a.storage.Log().Write(line)
+		}
+	case live == nil:
+		a.storage.Log().Writef("%s created", name)
+	case live.GetResourceVersion() == result.GetResourceVersion():
+		a.storage.Log().Writef("%s unchanged", name)
+	default:
+		a.storage.Log().Writef("%s configured", name)
+	}
+	return &sApplied{mapping: mapping, obj: result}, nil
+}
+
+// pruneAll 删除清单中各类资源下匹配标签但本次未应用的对象
+func (a *sApplier) pruneAll(ctx context.Context, applied []*sApplied) error {
+	var keep = make(map[string]struct{}, len(applied))
+	var scopes = make(map[string]*sApplied)
+	for _, res := range applied {
+		keep[res.key()] = struct{}{}
+		scopes[res.mapping.Resource.String()+"|"+res.obj.GetNamespace()] = res
+	}
+	var keys = make([]string, 0, len(scopes))
+	for key := range scopes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		res := scopes[key]
+		client := a.resource(res.mapping, res.obj.GetNamespace())
+		list, err := client.List(ctx, metav1.ListOptions{LabelSelector: a.prune.String()})
+		if err != nil {
+			return fmt.Errorf("list %s: %w", res.mapping.Resource.Resource, err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			current := &sApplied{mapping: res.mapping, obj: item}
+			if _, ok := keep[current.key()]; ok {
+				continue
+			}
+			// 由控制器创建的对象随所属对象清理
+			if item.GetDeletionTimestamp() != nil || metav1.GetControllerOf(item) != nil {
+				continue
+			}
+			name := display(res.mapping, item)
+			if a.check {
+				a.storage.Log().Writef("%s pruned (dry run)", name)
+				continue
+			}
+			policy := metav1.DeletePropagationBackground
+			err = client.Delete(ctx, item.GetName(), metav1.DeleteOptions{PropagationPolicy: &policy})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("prune %s: %w", name, err)
+			}
+			a.storage.Log().Writef("%s pruned", name)
+		}
+	}
+	return nil
+}
+
+// waitReady 轮询对象直到按类型的健康规则判断为就绪
+func (a *sApplier) waitReady(ctx context.Context, res *sApplied) error {
+	client := a.resource(res.mapping, res.obj.GetNamespace())
+	var last string
+	err := wait.PollUntilContextCancel(ctx, a.interval, true, func(ctx context.Context) (bool, error) {
+		obj, err := client.Get(ctx, res.obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		status, ready, err := health(obj)
+		if err != nil {
+			return false, err
+		}
+		if status != last {
+			a.storage.Log().Write(status)
+			last = status
+		}
+		return ready, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s is not ready: %s", display(res.mapping, res.obj), last)
+		}
+		return err
+	}
+	return nil
+}
+
+// display 日志中的资源名称, 如 deployment.apps/nginx -n default
+func display(mapping *meta.RESTMapping, obj *unstructured.Unstructured) string {
+	name := strings.ToLower(mapping.GroupVersionKind.Kind)
+	if mapping.GroupVersionKind.Group != "" {
+		name += "." + mapping.GroupVersionKind.Group
+	}
+	name += "/" + obj.GetName()
+	if obj.GetNamespace() != "" {
+		name += " -n " + obj.GetNamespace()
+	}
+	return name
+}
+
+// sanitize 去除服务端维护的字段后转为yaml行, 用于比较差异
+func sanitize(obj *unstructured.Unstructured) []string {
+	if obj == nil {
+		return nil
+	}
+	obj = obj.DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	content, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
@@ -0,0 +1,289 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+)
+
+var configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// sTestStep 仅记录日志的步骤存储
+type sTestStep struct {
+	storage.IStep
+	log *sTestLog
+}
+
+func (s *sTestStep) Log() storage.ILog {
+	return s.log
+}
+
+type sTestLog struct {
+	storage.ILog
+	lines []string
+}
+
+func (l *sTestLog) Write(contents ...string) {
+	l.lines = append(l.lines, strings.Join(contents, " "))
+}
+
+func (l *sTestLog) Writef(format string, args ...interface{}) {
+	l.Write(fmt.Sprintf(format, args...))
+}
+
+func configMap(name string, labels map[string]string, data map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName(name)
+	obj.SetNamespace(metav1.NamespaceDefault)
+	obj.SetLabels(labels)
+	if data != nil {
+		_ = unstructured.SetNestedStringMap(obj.Object, data, "data")
+	}
+	return obj
+}
+
+// newTestApplier 使用静态RESTMapper与fake动态客户端, 以整体替换模拟server-side apply, 内容变化时递增resourceVersion
+func newTestApplier(objs ...runtime.Object) (*sApplier, *dynamicfake.FakeDynamicClient, *sTestLog) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMapGVR: "ConfigMapList",
+	}, objs...)
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != ktypes.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		gvr, ns := patch.GetResource(), patch.GetNamespace()
+		live, err := client.Tracker().Get(gvr, ns, patch.GetName())
+		if apierrors.IsNotFound(err) {
+			obj.SetResourceVersion("1")
+			return true, obj, client.Tracker().Create(gvr, obj, ns)
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		current := live.(*unstructured.Unstructured)
+		obj.SetResourceVersion(current.GetResourceVersion())
+		if equality.Semantic.DeepEqual(current.Object, obj.Object) {
+			return true, current, nil
+		}
+		version, _ := strconv.Atoi(current.GetResourceVersion())
+		obj.SetResourceVersion(strconv.Itoa(version + 1))
+		return true, obj, client.Tracker().Update(gvr, obj, ns)
+	})
+
+	log := &sTestLog{}
+	return &sApplier{
+		storage:   &sTestStep{log: log},
+		client:    client,
+		mapper:    mapper,
+		namespace: metav1.NamespaceDefault,
+		manager:   defaultFieldManager,
+	}, client, log
+}
+
+func TestApplyOne(t *testing.T) {
+	live := configMap("app", nil, map[string]string{"key": "old"})
+	live.SetResourceVersion("1")
+	tests := []struct {
+		name  string
+		live  *unstructured.Unstructured
+		obj   *unstructured.Unstructured
+		check bool
+		want  []string
+	}{
+		{
+			name: "created",
+			obj:  configMap("app", nil, map[string]string{"key": "new"}),
+			want: []string{"configmap/app -n default created"},
+		},
+		{
+			name: "unchanged",
+			live: live,
+			obj:  configMap("app", nil, map[string]string{"key": "old"}),
+			want: []string{"configmap/app -n default unchanged"},
+		},
+		{
+			name: "configured",
+			live: live,
+			obj:  configMap("app", nil, map[string]string{"key": "new"}),
+			want: []string{"configmap/app -n default configured"},
+		},
+		{
+			name:  "dry run unchanged",
+			live:  live,
+			obj:   configMap("app", nil, map[string]string{"key": "old"}),
+			check: true,
+			want:  []string{"configmap/app -n default unchanged (dry run)"},
+		},
+		{
+			name:  "dry run created",
+			obj:   configMap("app", nil, map[string]string{"key": "new"}),
+			check: true,
+			want: []string{
+				"configmap/app -n default created (dry run)",
+				"@@ -1 +1 @@",
+				"+apiVersion: v1",
+				"+data:",
+				"+    key: new",
+				"+kind: ConfigMap",
+				"+metadata:",
+				"+    name: app",
+				"+    namespace: default",
+			},
+		},
+		{
+			name:  "dry run diff",
+			live:  live,
+			obj:   configMap("app", nil, map[string]string{"key": "new"}),
+			check: true,
+			want: []string{
+				"configmap/app -n default configured (dry run)",
+				"@@ -3 +3 @@",
+				"-    key: old",
+				"+    key: new",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if tt.live != nil {
+				objs = append(objs, tt.live.DeepCopy())
+			}
+			a, _, log := newTestApplier(objs...)
+			a.check = tt.check
+			res, err := a.applyOne(context.Background(), tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.obj.GetName() != "app" || res.mapping.Resource != configMapGVR {
+				t.Errorf("applied = %s %s", res.mapping.Resource, res.obj.GetName())
+			}
+			if !slices.Equal(log.lines, tt.want) {
+				t.Errorf("log = %q, want %q", log.lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOneDefaultNamespace(t *testing.T) {
+	a, client, _ := newTestApplier()
+	a.namespace = "apps"
+	obj := configMap("app", nil, nil)
+	obj.SetNamespace("")
+	if _, err := a.applyOne(context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(configMapGVR).Namespace("apps").Get(context.Background(), "app", metav1.GetOptions{}); err != nil {
+		t.Errorf("object not applied in default namespace: %v", err)
+	}
+
+	obj = configMap("", nil, nil)
+	if _, err := a.applyOne(context.Background(), obj); err == nil {
+		t.Error("expected error for object without name")
+	}
+}
+
+func TestPruneAll(t *testing.T) {
+	selector := map[string]string{"app": "demo"}
+	owned := configMap("owned", selector, nil)
+	owned.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "demo",
+		UID:        "1",
+		Controller: func(b bool) *bool { return &b }(true),
+	}})
+	seed := func() []runtime.Object {
+		return []runtime.Object{
+			configMap("applied", selector, nil),
+			configMap("stale", selector, nil),
+			configMap("unlabeled", nil, nil),
+			owned.DeepCopy(),
+		}
+	}
+	tests := []struct {
+		name  string
+		check bool
+		log   []string
+		left  []string
+	}{
+		{
+			name: "prune",
+			log:  []string{"configmap/stale -n default pruned"},
+			left: []string{"applied", "owned", "unlabeled"},
+		},
+		{
+			name:  "dry run",
+			check: true,
+			log:   []string{"configmap/stale -n default pruned (dry run)"},
+			left:  []string{"applied", "owned", "stale", "unlabeled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, client, log := newTestApplier(seed()...)
+			a.check = tt.check
+			a.prune = labels.SelectorFromSet(selector)
+			mapping, err := a.mapper.RESTMapping(schema.GroupKind{Kind: "ConfigMap"}, "v1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			applied := []*sApplied{{mapping: mapping, obj: configMap("applied", selector, nil)}}
+			if err = a.pruneAll(context.Background(), applied); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(log.lines, tt.log) {
+				t.Errorf("log = %q, want %q", log.lines, tt.log)
+			}
+			list, err := client.Resource(configMapGVR).Namespace(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var left []string
+			for _, item := range list.Items {
+				left = append(left, item.GetName())
+			}
+			slices.Sort(left)
+			if !slices.Equal(left, tt.left) {
+				t.Errorf("left = %v, want %v", left, tt.left)
+			}
+		})
+	}
+}
+
+func TestRunPruneSelectorMismatch(t *testing.T) {
+	a, _, _ := newTestApplier()
+	a.prune = labels.SelectorFromSet(map[string]string{"app": "demo"})
+	err := a.run(context.Background(), []*unstructured.Unstructured{configMap("app", nil, nil)})
+	if err == nil || !strings.Contains(err.Error(), "does not match prune selector") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -3,9 +3,12 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubetypes "k8s.io/apimachinery/pkg/types"
@@ -18,7 +21,8 @@ import (
 
 type SDeployment struct {
 	context.Context
-	Client appv1.DeploymentInterface
+	Client      appv1.DeploymentInterface
+	ReplicaSets appv1.ReplicaSetInterface
 	*types.SResource
 	Storage storage.IStep
 }
@@ -102,3 +106,66 @@ func (d *SDeployment) Println() error {
 		return nil
 	})
 }
+
+func (d *SDeployment) Template() (*corev1.PodTemplateSpec, error) {
+	result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Spec.Template.DeepCopy(), nil
+}
+
+func (d *SDeployment) Restore(template *corev1.PodTemplateSpec) error {
+	return kuberetry.RetryOnConflict(kuberetry.DefaultRetry, func() error {
+		result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		result.Spec.Template = *template.DeepCopy()
+		_, err = d.Client.Update(d.Context, result, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Rollback 恢复到ReplicaSet记录的版本
+func (d *SDeployment) Rollback(revision int64) error {
+	result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(result.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	list, err := d.ReplicaSets.List(d.Context, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	var templates = make(map[int64]*corev1.PodTemplateSpec)
+	var revisions []int64
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if !metav1.IsControlledBy(rs, result) {
+			continue
+		}
+		rev, err := strconv.ParseInt(rs.Annotations[types.RevisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		templates[rev] = rs.Spec.Template.DeepCopy()
+		revisions = append(revisions, rev)
+	}
+	current, _ := strconv.ParseInt(result.Annotations[types.RevisionAnnotation], 10, 64)
+	target, err := types.PickRevision(revisions, current, revision)
+	if err != nil {
+		return err
+	}
+	if target == current {
+		d.Storage.Log().Writef("%s %s/%s is already at revision %d", d.GetKind(), d.GetNamespace(), d.GetName(), target)
+		return nil
+	}
+	d.Storage.Log().Writef("rollback %s %s/%s from revision %d to %d", d.GetKind(), d.GetNamespace(), d.GetName(), current, target)
+	template := templates[target]
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	return d.Restore(template)
+}
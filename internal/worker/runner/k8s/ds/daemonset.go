@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubetypes "k8s.io/apimachinery/pkg/types"
@@ -17,7 +18,8 @@ import (
 
 type SDaemonSet struct {
 	context.Context
-	Client appv1.DaemonSetInterface
+	Client    appv1.DaemonSetInterface
+	Revisions appv1.ControllerRevisionInterface
 	*types.SResource
 	Storage storage.IStep
 }
@@ -85,3 +87,64 @@ func (d *SDaemonSet) Println() error {
 		return nil
 	})
 }
+
+func (d *SDaemonSet) Template() (*corev1.PodTemplateSpec, error) {
+	result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Spec.Template.DeepCopy(), nil
+}
+
+func (d *SDaemonSet) Restore(template *corev1.PodTemplateSpec) error {
+	return kuberetry.RetryOnConflict(kuberetry.DefaultRetry, func() error {
+		result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		result.Spec.Template = *template.DeepCopy()
+		_, err = d.Client.Update(d.Context, result, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Rollback 恢复到ControllerRevision记录的版本
+func (d *SDaemonSet) Rollback(revision int64) error {
+	result, err := d.Client.Get(d.Context, d.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(result.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	list, err := d.Revisions.List(d.Context, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	var patches = make(map[int64][]byte)
+	var revisions []int64
+	var current int64
+	for i := range list.Items {
+		rev := &list.Items[i]
+		if !metav1.IsControlledBy(rev, result) {
+			continue
+		}
+		patches[rev.Revision] = rev.Data.Raw
+		revisions = append(revisions, rev.Revision)
+		// 最新的版本即当前版本
+		current = max(current, rev.Revision)
+	}
+	target, err := types.PickRevision(revisions, current, revision)
+	if err != nil {
+		return err
+	}
+	if target == current {
+		d.Storage.Log().Writef("%s %s/%s is already at revision %d", d.GetKind(), d.GetNamespace(), d.GetName(), target)
+		return nil
+	}
+	d.Storage.Log().Writef("rollback %s %s/%s from revision %d to %d", d.GetKind(), d.GetNamespace(), d.GetName(), current, target)
+	// 版本数据是spec.template的策略合并补丁
+	_, err = d.Client.Patch(d.Context, d.GetName(), kubetypes.StrategicMergePatchType, patches[target], metav1.PatchOptions{})
+	return err
+}
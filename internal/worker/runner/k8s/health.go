@@ -0,0 +1,87 @@
+package k8s
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// health 按资源类型判断是否就绪, 返回当前状态描述
+func health(obj *unstructured.Unstructured) (string, bool, error) {
+	var viewer = &sStatusViewer{}
+	name := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	if obj.GetNamespace() == "" {
+		name = fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Deployment.apps":
+		return viewer.watchDeployment(obj)
+	case "DaemonSet.apps":
+		return viewer.watchDaemonSet(obj)
+	case "StatefulSet.apps":
+		return viewer.watchStatefulSet(obj)
+	case "Job.batch":
+		if cond := condition(obj, "Failed"); cond != nil && cond["status"] == "True" {
+			return "", false, fmt.Errorf("%s failed: %v", name, cond["message"])
+		}
+		if cond := condition(obj, "Complete"); cond != nil && cond["status"] == "True" {
+			return fmt.Sprintf("%s completed", name), true, nil
+		}
+		succeeded, _, _ := unstructured.NestedInt64(obj.Object, "status", "succeeded")
+		active, _, _ := unstructured.NestedInt64(obj.Object, "status", "active")
+		return fmt.Sprintf("waiting for %s to complete: %d succeeded, %d active", name, succeeded, active), false, nil
+	case "Pod":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		switch phase {
+		case "Succeeded":
+			return fmt.Sprintf("%s succeeded", name), true, nil
+		case "Failed":
+			return "", false, fmt.Errorf("%s failed", name)
+		}
+		if cond := condition(obj, "Ready"); cond != nil && cond["status"] == "True" {
+			return fmt.Sprintf("%s is ready", name), true, nil
+		}
+		return fmt.Sprintf("waiting for %s to be ready, phase %s", name, phase), false, nil
+	case "Service":
+		serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+		if serviceType != "LoadBalancer" {
+			return fmt.Sprintf("%s is ready", name), true, nil
+		}
+		ingress, _, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+		if len(ingress) == 0 {
+			return fmt.Sprintf("waiting for %s load balancer address", name), false, nil
+		}
+		return fmt.Sprintf("%s is ready", name), true, nil
+	case "Namespace":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		if phase != "" && phase != "Active" {
+			return fmt.Sprintf("waiting for %s to be active, phase %s", name, phase), false, nil
+		}
+		return fmt.Sprintf("%s is active", name), true, nil
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		if cond := condition(obj, "Established"); cond != nil && cond["status"] == "True" {
+			return fmt.Sprintf("%s is established", name), true, nil
+		}
+		return fmt.Sprintf("waiting for %s to be established", name), false, nil
+	}
+
+	// 其他资源: 控制器已观察到最新版本, 且存在Ready条件时为True
+	if observed, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found && observed < obj.GetGeneration() {
+		return fmt.Sprintf("waiting for %s spec update to be observed", name), false, nil
+	}
+	if cond := condition(obj, "Ready"); cond != nil && cond["status"] != "True" {
+		return fmt.Sprintf("waiting for %s to be ready: %v", name, cond["message"]), false, nil
+	}
+	return fmt.Sprintf("%s is ready", name), true, nil
+}
+
+// condition 返回status.conditions中指定类型的条件
+func condition(obj *unstructured.Unstructured, typ string) map[string]any {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if cond, ok := c.(map[string]any); ok && cond["type"] == typ {
+			return cond
+		}
+	}
+	return nil
+}
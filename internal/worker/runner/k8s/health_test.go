@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func object(apiVersion, kind, namespace string, fields map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	if obj.Object == nil {
+		obj.Object = map[string]any{}
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName("demo")
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+func conditions(typ, status string) map[string]any {
+	return map[string]any{
+		"conditions": []any{map[string]any{"type": typ, "status": status, "message": "reason"}},
+	}
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    *unstructured.Unstructured
+		status string
+		ready  bool
+		err    bool
+	}{
+		{
+			name: "deployment rolled out",
+			obj: object("apps/v1", "Deployment", "default", map[string]any{
+				"metadata": map[string]any{"generation": int64(2)},
+				"spec":     map[string]any{"replicas": int64(2)},
+				"status": map[string]any{
+					"observedGeneration": int64(2), "replicas": int64(2),
+					"updatedReplicas": int64(2), "availableReplicas": int64(2),
+				},
+			}),
+			status: "deployment default/demo successfully rolled out",
+			ready:  true,
+		},
+		{
+			name: "deployment updating",
+			obj: object("apps/v1", "Deployment", "default", map[string]any{
+				"metadata": map[string]any{"generation": int64(2)},
+				"spec":     map[string]any{"replicas": int64(2)},
+				"status":   map[string]any{"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(1)},
+			}),
+			status: "waiting for deployment default/demo rollout to finish: 1 out of 2 new replicas have been updated...",
+		},
+		{
+			name:   "job complete",
+			obj:    object("batch/v1", "Job", "default", map[string]any{"status": conditions("Complete", "True")}),
+			status: "Job default/demo completed",
+			ready:  true,
+		},
+		{
+			name: "job failed",
+			obj:  object("batch/v1", "Job", "default", map[string]any{"status": conditions("Failed", "True")}),
+			err:  true,
+		},
+		{
+			name:   "job running",
+			obj:    object("batch/v1", "Job", "default", map[string]any{"status": map[string]any{"active": int64(1)}}),
+			status: "waiting for Job default/demo to complete: 0 succeeded, 1 active",
+		},
+		{
+			name:   "pod succeeded",
+			obj:    object("v1", "Pod", "default", map[string]any{"status": map[string]any{"phase": "Succeeded"}}),
+			status: "Pod default/demo succeeded",
+			ready:  true,
+		},
+		{
+			name: "pod failed",
+			obj:  object("v1", "Pod", "default", map[string]any{"status": map[string]any{"phase": "Failed"}}),
+			err:  true,
+		},
+		{
+			name: "pod ready",
+			obj: object("v1", "Pod", "default", map[string]any{
+				"status": map[string]any{"phase": "Running", "conditions": conditions("Ready", "True")["conditions"]},
+			}),
+			status: "Pod default/demo is ready",
+			ready:  true,
+		},
+		{
+			name:   "pod pending",
+			obj:    object("v1", "Pod", "default", map[string]any{"status": map[string]any{"phase": "Pending"}}),
+			status: "waiting for Pod default/demo to be ready, phase Pending",
+		},
+		{
+			name:   "service cluster ip",
+			obj:    object("v1", "Service", "default", map[string]any{"spec": map[string]any{"type": "ClusterIP"}}),
+			status: "Service default/demo is ready",
+			ready:  true,
+		},
+		{
+			name:   "service load balancer pending",
+			obj:    object("v1", "Service", "default", map[string]any{"spec": map[string]any{"type": "LoadBalancer"}}),
+			status: "waiting for Service default/demo load balancer address",
+		},
+		{
+			name: "service load balancer ready",
+			obj: object("v1", "Service", "default", map[string]any{
+				"spec":   map[string]any{"type": "LoadBalancer"},
+				"status": map[string]any{"loadBalancer": map[string]any{"ingress": []any{map[string]any{"ip": "10.0.0.1"}}}},
+			}),
+			status: "Service default/demo is ready",
+			ready:  true,
+		},
+		{
+			name:   "namespace terminating",
+			obj:    object("v1", "Namespace", "", map[string]any{"status": map[string]any{"phase": "Terminating"}}),
+			status: "waiting for Namespace demo to be active, phase Terminating",
+		},
+		{
+			name:   "crd established",
+			obj:    object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", map[string]any{"status": conditions("Established", "True")}),
+			status: "CustomResourceDefinition demo is established",
+			ready:  true,
+		},
+		{
+			name: "custom resource not observed",
+			obj: object("example.com/v1", "Widget", "default", map[string]any{
+				"metadata": map[string]any{"generation": int64(3)},
+				"status":   map[string]any{"observedGeneration": int64(2)},
+			}),
+			status: "waiting for Widget default/demo spec update to be observed",
+		},
+		{
+			name:   "custom resource not ready",
+			obj:    object("example.com/v1", "Widget", "default", map[string]any{"status": conditions("Ready", "False")}),
+			status: "waiting for Widget default/demo to be ready: reason",
+		},
+		{
+			name:   "configmap",
+			obj:    object("v1", "ConfigMap", "default", nil),
+			status: "ConfigMap default/demo is ready",
+			ready:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ready, err := health(tt.obj)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if status != tt.status || ready != tt.ready {
+				t.Errorf("health = %q %v, want %q %v", status, ready, tt.status, tt.ready)
+			}
+		})
+	}
+}
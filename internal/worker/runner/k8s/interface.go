@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
@@ -17,6 +18,11 @@ type IResource interface {
 	Update() error
 	Println() error
 	Restart() error
+	// Template 当前的Pod模板, 用于失败时恢复
+	Template() (*corev1.PodTemplateSpec, error)
+	Restore(template *corev1.PodTemplateSpec) error
+	// Rollback 回滚到指定版本, 0为上一个版本
+	Rollback(revision int64) error
 }
 
 func ResourceFor(ctx context.Context, storage storage.IStep, client *kubernetes.Clientset, resource *types.SResource) IResource {
@@ -24,15 +30,17 @@ func ResourceFor(ctx context.Context, storage storage.IStep, client *kubernetes.
 	switch resource.GetKind() {
 	case types.Deployment:
 		rs = &deploy.SDeployment{
-			Context:   ctx,
-			Client:    client.AppsV1().Deployments(resource.GetNamespace()),
-			SResource: resource,
-			Storage:   storage,
+			Context:     ctx,
+			Client:      client.AppsV1().Deployments(resource.GetNamespace()),
+			ReplicaSets: client.AppsV1().ReplicaSets(resource.GetNamespace()),
+			SResource:   resource,
+			Storage:     storage,
 		}
 	case types.DaemonSet:
 		rs = &ds.SDaemonSet{
 			Context:   ctx,
 			Client:    client.AppsV1().DaemonSets(resource.GetNamespace()),
+			Revisions: client.AppsV1().ControllerRevisions(resource.GetNamespace()),
 			SResource: resource,
 			Storage:   storage,
 		}
@@ -40,6 +48,7 @@ func ResourceFor(ctx context.Context, storage storage.IStep, client *kubernetes.
 		rs = &sts.SStatefulSet{
 			Context:   ctx,
 			Client:    client.AppsV1().StatefulSets(resource.GetNamespace()),
+			Revisions: client.AppsV1().ControllerRevisions(resource.GetNamespace()),
 			SResource: resource,
 			Storage:   storage,
 		}
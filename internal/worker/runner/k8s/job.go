@@ -0,0 +1,473 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+var nameRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// sJob 运行一次性的Job或Pod, 转发容器日志并返回容器退出码
+type sJob struct {
+	storage   storage.IStep
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	job       *batchv1.Job // 为空时运行的是Pod
+	interval  time.Duration
+
+	wg       sync.WaitGroup
+	streamed map[string]struct{} // 已开始转发日志的容器
+	waiting  map[string]string   // 容器最近一次的等待原因
+}
+
+func (k *SKubectl) job(ctx context.Context) (int64, error) {
+	obj, err := k.jobObject()
+	if err != nil {
+		k.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	namespace, err := k.getResourceValue(obj.GetNamespace(), k.Namespace, "NAMESPACE")
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	var envs []corev1.EnvVar
+	if k.InjectEnv {
+		for name, value := range k.envs() {
+			if name == "TASK_WORKSPACE" {
+				continue
+			}
+			envs = append(envs, corev1.EnvVar{Name: name, Value: fmt.Sprint(value)})
+		}
+		sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	}
+	var ttl int32
+	if k.TTL != nil {
+		ttl = *k.TTL
+	}
+
+	j := &sJob{
+		storage:   k.storage,
+		client:    k.client,
+		namespace: namespace,
+		interval:  2 * time.Second,
+		streamed:  make(map[string]struct{}),
+		waiting:   make(map[string]string),
+	}
+	if err = j.create(ctx, obj, envs, ttl); err != nil {
+		k.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	output := "POD_NAME"
+	if j.job != nil {
+		output = "JOB_NAME"
+	}
+	if err = k.storage.Output().Insert(&models.SEnv{Name: output, Value: j.name}); err != nil {
+		return common.CodeSystemErr, err
+	}
+
+	code, err := j.wait(ctx)
+	j.cleanup(ctx, ttl)
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	if err != nil {
+		k.storage.Log().Write(err.Error())
+	}
+	return code, err
+}
+
+// jobObject 从清单中读取Job或Pod, 未提供清单时按镜像生成Job
+func (k *SKubectl) jobObject() (*unstructured.Unstructured, error) {
+	if k.Manifests == "" && len(k.Files) == 0 {
+		if k.Image == "" {
+			return nil, errors.New("manifests, files or image is required")
+		}
+		name := strings.Trim(nameRegexp.ReplaceAllString(strings.ToLower(k.storage.Name()), "-"), "-")
+		if len(name) > 50 {
+			name = strings.Trim(name[:50], "-")
+		}
+		if name == "" {
+			name = "aef-job"
+		}
+		var container = map[string]any{"name": "job", "image": k.Image}
+		if len(k.Command) > 0 {
+			container["command"] = toAny(k.Command)
+		}
+		if len(k.Args) > 0 {
+			container["args"] = toAny(k.Args)
+		}
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]any{"generateName": name + "-"},
+			"spec": map[string]any{
+				"template": map[string]any{
+					"spec": map[string]any{"containers": []any{container}},
+				},
+			},
+		}}, nil
+	}
+	objs, err := k.manifests()
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) != 1 {
+		return nil, fmt.Errorf("expected one Job or Pod, got %d objects", len(objs))
+	}
+	switch objs[0].GroupVersionKind().GroupKind().String() {
+	case "Job.batch", "Pod":
+		return objs[0], nil
+	default:
+		return nil, fmt.Errorf("expected Job or Pod, got %s", objs[0].GetKind())
+	}
+}
+
+func toAny(s []string) []any {
+	var res = make([]any, len(s))
+	for i, v := range s {
+		res[i] = v
+	}
+	return res
+}
+
+// create 创建Job或Pod, 默认不重启不重试
+func (j *sJob) create(ctx context.Context, obj *unstructured.Unstructured, envs []corev1.EnvVar, ttl int32) error {
+	var job = new(batchv1.Job)
+	if obj.GetKind() == "Pod" {
+		var pod = new(corev1.Pod)
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+			return err
+		}
+		// Pod没有ttl控制器, 保留一段时间时包装为Job交由Job控制器清理, 执行器重启也不会遗留
+		if ttl > 0 {
+			job = wrapPod(pod)
+			j.storage.Log().Writef("pod is wrapped in a job for ttl %ds", ttl)
+		} else {
+			pod.Namespace = j.namespace
+			preparePod(&pod.Spec, envs)
+			pod, err := j.client.CoreV1().Pods(j.namespace).Create(ctx, pod, metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			j.name = pod.Name
+			j.storage.Log().Writef("%s created", j.display())
+			return nil
+		}
+	} else if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, job); err != nil {
+		return err
+	}
+	job.Namespace = j.namespace
+	preparePod(&job.Spec.Template.Spec, envs)
+	if job.Spec.BackoffLimit == nil {
+		job.Spec.BackoffLimit = new(int32)
+	}
+	// ttl为0时由执行器在读取结果后删除, 否则交由Job控制器清理
+	if job.Spec.TTLSecondsAfterFinished == nil && ttl > 0 {
+		job.Spec.TTLSecondsAfterFinished = &ttl
+	}
+	job, err := j.client.BatchV1().Jobs(j.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	j.job, j.name = job, job.Name
+	j.storage.Log().Writef("%s created", j.display())
+	return nil
+}
+
+// wrapPod 以Pod的名称, 标签及注解生成运行该Pod的Job
+func wrapPod(pod *corev1.Pod) *batchv1.Job {
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:         pod.Name,
+			GenerateName: pod.GenerateName,
+			Labels:       pod.Labels,
+			Annotations:  pod.Annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      pod.Labels,
+					Annotations: pod.Annotations,
+				},
+				Spec: pod.Spec,
+			},
+		},
+	}
+}
+
+func preparePod(spec *corev1.PodSpec, envs []corev1.EnvVar) {
+	if spec.RestartPolicy == "" || spec.RestartPolicy == corev1.RestartPolicyAlways {
+		spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			for _, env := range envs {
+				if !hasEnv(containers[i].Env, env.Name) {
+					containers[i].Env = append(containers[i].Env, env)
+				}
+			}
+		}
+	}
+}
+
+func hasEnv(envs []corev1.EnvVar, name string) bool {
+	for _, env := range envs {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wait 等待结束, 期间为已启动的容器转发日志
+func (j *sJob) wait(ctx context.Context) (int64, error) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		pods, err := j.pods(ctx)
+		if err != nil && ctx.Err() == nil && !transient(err) {
+			return common.CodeSystemErr, err
+		}
+		for i := range pods {
+			if err = j.watchPod(ctx, &pods[i]); err != nil {
+				return common.CodeFailed, err
+			}
+		}
+		done, code, err := j.finished(ctx, pods)
+		if done {
+			j.drain()
+			if err == nil {
+				j.storage.Log().Writef("%s succeeded", j.display())
+			}
+			return code, err
+		}
+		select {
+		case <-ctx.Done():
+			return common.CodeKilled, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pods Job的所有Pod, 按创建时间排序
+func (j *sJob) pods(ctx context.Context) ([]corev1.Pod, error) {
+	if j.job == nil {
+		pod, err := j.client.CoreV1().Pods(j.namespace).Get(ctx, j.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return []corev1.Pod{*pod}, nil
+	}
+	// 选择器由服务端在创建时生成
+	selector := "job-name=" + j.name
+	if j.job.Spec.Selector != nil {
+		s, err := metav1.LabelSelectorAsSelector(j.job.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		selector = s.String()
+	}
+	list, err := j.client.CoreV1().Pods(j.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(a, b int) bool {
+		return list.Items[a].CreationTimestamp.Before(&list.Items[b].CreationTimestamp)
+	})
+	return list.Items, nil
+}
+
+// watchPod 记录容器的等待原因, 为启动的容器转发日志
+func (j *sJob) watchPod(ctx context.Context, pod *corev1.Pod) error {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	multiple := len(pod.Spec.InitContainers)+len(pod.Spec.Containers) > 1
+	for _, status := range statuses {
+		key := pod.Name + "/" + status.Name
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "", "ContainerCreating", "PodInitializing":
+			default:
+				if j.waiting[key] != waiting.Reason {
+					j.waiting[key] = waiting.Reason
+					j.storage.Log().Writef("pod/%s container %s is waiting: %s %s", pod.Name, status.Name, waiting.Reason, waiting.Message)
+				}
+				if waiting.Reason == "InvalidImageName" || waiting.Reason == "ErrImageNeverPull" {
+					return fmt.Errorf("pod/%s container %s: %s", pod.Name, status.Name, waiting.Reason)
+				}
+			}
+			continue
+		}
+		if _, ok := j.streamed[key]; ok {
+			continue
+		}
+		j.streamed[key] = struct{}{}
+		var prefix string
+		if multiple {
+			prefix = "[" + status.Name + "] "
+		}
+		j.wg.Add(1)
+		go func(pod, container, prefix string) {
+			defer j.wg.Done()
+			if err := j.stream(ctx, pod, container, prefix); err != nil && ctx.Err() == nil {
+				j.storage.Log().Writef("pod/%s container %s logs: %s", pod, container, err)
+			}
+		}(pod.Name, status.Name, prefix)
+	}
+	return nil
+}
+
+func (j *sJob) stream(ctx context.Context, pod, container, prefix string) error {
+	reader, err := j.client.CoreV1().Pods(j.namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		j.storage.Log().Write(prefix + scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// drain 等待日志转发结束, 已结束的容器日志很快读完
+func (j *sJob) drain() {
+	var done = make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		j.storage.Log().Write("timed out waiting for container logs")
+	}
+}
+
+// finished 判断是否结束, 失败时返回失败容器的退出码
+func (j *sJob) finished(ctx context.Context, pods []corev1.Pod) (bool, int64, error) {
+	if j.job == nil {
+		if len(pods) == 0 {
+			return false, 0, nil
+		}
+		switch pods[0].Status.Phase {
+		case corev1.PodSucceeded:
+			return true, common.CodeSuccess, nil
+		case corev1.PodFailed:
+			return true, exitCode(pods), fmt.Errorf("%s failed %s", j.display(), pods[0].Status.Reason)
+		}
+		return false, 0, nil
+	}
+
+	job, err := j.client.BatchV1().Jobs(j.namespace).Get(ctx, j.name, metav1.GetOptions{})
+	if err != nil {
+		if ctx.Err() != nil || transient(err) {
+			return false, 0, nil
+		}
+		return true, common.CodeSystemErr, fmt.Errorf("get %s: %w", j.display(), err)
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return true, common.CodeSuccess, nil
+		case batchv1.JobFailed:
+			return true, exitCode(pods), fmt.Errorf("%s failed: %s %s", j.display(), cond.Reason, cond.Message)
+		}
+	}
+	return false, 0, nil
+}
+
+// transient 可在下次轮询时重试的错误
+func transient(err error) bool {
+	return apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// exitCode 最后一个Pod中第一个非0的容器退出码
+func exitCode(pods []corev1.Pod) int64 {
+	for i := len(pods) - 1; i >= 0; i-- {
+		statuses := append(append([]corev1.ContainerStatus{}, pods[i].Status.InitContainerStatuses...), pods[i].Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if t := status.State.Terminated; t != nil && t.ExitCode != 0 {
+				return int64(t.ExitCode)
+			}
+		}
+	}
+	return common.CodeFailed
+}
+
+// cleanup 取消或超时时删除, 否则按ttl由Job控制器清理
+func (j *sJob) cleanup(ctx context.Context, ttl int32) {
+	if ctx.Err() == nil && ttl > 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	if err := j.delete(ctx); err != nil {
+		j.storage.Log().Writef("delete %s failed: %s", j.display(), err)
+		return
+	}
+	j.storage.Log().Writef("%s deleted", j.display())
+}
+
+func (j *sJob) delete(ctx context.Context) error {
+	policy := metav1.DeletePropagationBackground
+	opts := metav1.DeleteOptions{PropagationPolicy: &policy}
+	var err error
+	if j.job == nil {
+		err = j.client.CoreV1().Pods(j.namespace).Delete(ctx, j.name, opts)
+	} else {
+		err = j.client.BatchV1().Jobs(j.namespace).Delete(ctx, j.name, opts)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (j *sJob) display() string {
+	if j.job == nil {
+		return "pod/" + j.name + " -n " + j.namespace
+	}
+	return "job.batch/" + j.name + " -n " + j.namespace
+}
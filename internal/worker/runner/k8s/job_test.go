@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+)
+
+func newTestJob(objs ...runtime.Object) (*sJob, *fake.Clientset) {
+	client := fake.NewClientset(objs...)
+	return &sJob{
+		storage:   &sTestStep{log: &sTestLog{}},
+		client:    client,
+		namespace: metav1.NamespaceDefault,
+		streamed:  make(map[string]struct{}),
+		waiting:   make(map[string]string),
+	}, client
+}
+
+func podObject() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]any{"name": "demo", "labels": map[string]any{"app": "demo"}},
+		"spec": map[string]any{
+			"containers": []any{map[string]any{"name": "main", "image": "busybox"}},
+		},
+	}}
+}
+
+func TestCreatePod(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  int32
+		job  bool
+	}{
+		{name: "bare pod without ttl", ttl: 0},
+		{name: "pod wrapped in job with ttl", ttl: 60, job: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, client := newTestJob()
+			if err := j.create(context.Background(), podObject(), nil, tt.ttl); err != nil {
+				t.Fatal(err)
+			}
+			jobs, _ := client.BatchV1().Jobs(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+			pods, _ := client.CoreV1().Pods(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+			if !tt.job {
+				if j.job != nil || len(jobs.Items) != 0 || len(pods.Items) != 1 {
+					t.Fatalf("jobs %d pods %d", len(jobs.Items), len(pods.Items))
+				}
+				return
+			}
+			if j.job == nil || len(jobs.Items) != 1 || len(pods.Items) != 0 {
+				t.Fatalf("jobs %d pods %d", len(jobs.Items), len(pods.Items))
+			}
+			job := jobs.Items[0]
+			if job.Name != "demo" || job.Spec.Template.Labels["app"] != "demo" {
+				t.Errorf("job meta = %s %v", job.Name, job.Spec.Template.Labels)
+			}
+			if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != tt.ttl {
+				t.Errorf("ttl = %v", job.Spec.TTLSecondsAfterFinished)
+			}
+			if job.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyNever {
+				t.Errorf("restart policy = %s", job.Spec.Template.Spec.RestartPolicy)
+			}
+			if job.Spec.BackoffLimit == nil || *job.Spec.BackoffLimit != 0 {
+				t.Errorf("backoff limit = %v", job.Spec.BackoffLimit)
+			}
+		})
+	}
+}
+
+func TestFinishedJob(t *testing.T) {
+	complete := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: metav1.NamespaceDefault},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+		}},
+	}
+	running := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: metav1.NamespaceDefault}}
+	tests := []struct {
+		name   string
+		objs   []runtime.Object
+		getErr error
+		done   bool
+		code   int64
+		err    bool
+	}{
+		{name: "complete", objs: []runtime.Object{complete}, done: true, code: common.CodeSuccess},
+		{name: "running", objs: []runtime.Object{running}},
+		{name: "deleted", done: true, code: common.CodeSystemErr, err: true},
+		{
+			name:   "forbidden",
+			getErr: apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "jobs"}, "demo", nil),
+			done:   true, code: common.CodeSystemErr, err: true,
+		},
+		{name: "unavailable", getErr: apierrors.NewServiceUnavailable("try again")},
+		{name: "throttled", getErr: apierrors.NewTooManyRequests("slow down", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, client := newTestJob(tt.objs...)
+			j.name, j.job = "demo", running
+			if tt.getErr != nil {
+				client.PrependReactor("get", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.getErr
+				})
+			}
+			done, code, err := j.finished(context.Background(), nil)
+			if done != tt.done || code != tt.code || (err != nil) != tt.err {
+				t.Errorf("finished = %v %d %v, want %v %d error %v", done, code, err, tt.done, tt.code, tt.err)
+			}
+		})
+	}
+}
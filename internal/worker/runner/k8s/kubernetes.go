@@ -21,6 +21,8 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/k8s/types"
 )
 
+const rollbackTimeout = 5 * time.Minute
+
 type SKubectl struct {
 	kubeConf      *rest.Config
 	client        *kubernetes.Clientset
@@ -33,7 +35,27 @@ type SKubectl struct {
 	Namespace           string             `json:"namespace"`
 	ImageTag            string             `json:"image_tag"`
 	IgnoreInitContainer *bool              `json:"ignoreInitContainer"`
+	RollbackOnFailure   *bool              `json:"rollback_on_failure"`
 	Resources           []*types.SResource `json:"resources"`
+	Strategy            *types.SStrategy   `json:"strategy"` // 分批发布, 为空时所有资源并发执行
+
+	// apply
+	Manifests    string         `json:"manifests"`     // 内联清单, 支持多文档
+	Files        []string       `json:"files"`         // 清单文件或目录, 支持通配符
+	Vars         map[string]any `json:"vars"`          // 模板变量, 覆盖同名环境变量
+	Raw          bool           `json:"raw"`           // 不按jinja模板渲染, 只替换${NAME}
+	Prune        string         `json:"prune"`         // 标签选择器, 删除匹配但不在清单中的同类资源
+	Wait         *bool          `json:"wait"`          // 等待资源就绪, 默认true
+	Check        bool           `json:"check"`         // 检查模式, 只输出差异不修改
+	Force        bool           `json:"force"`         // 字段冲突时强制接管
+	FieldManager string         `json:"field_manager"` // 默认autoexecflow
+
+	// job, 清单同apply, 只能包含一个Job或Pod
+	Image     string   `json:"image"` // 未提供清单时按镜像创建Job
+	Command   []string `json:"command"`
+	Args      []string `json:"args"`
+	InjectEnv bool     `json:"inject_env"` // 将任务与步骤环境变量注入容器
+	TTL       *int32   `json:"ttl"`        // 结束后保留的秒数, 默认0立即删除
 }
 
 func New(storage storage.IStep, command, workspace string) (*SKubectl, error) {
@@ -107,6 +129,9 @@ func (k *SKubectl) init() (err error) {
 		if res.IgnoreInitContainer == nil {
 			k.Resources[kk].IgnoreInitContainer = k.IgnoreInitContainer
 		}
+		if res.RollbackOnFailure == nil {
+			k.Resources[kk].RollbackOnFailure = k.RollbackOnFailure
+		}
 	}
 	return
 }
@@ -126,13 +151,28 @@ func (k *SKubectl) Run(ctx context.Context) (exit int64, err error) {
 	}
 	if timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
 		defer cancel()
 	}
 
 	if err = k.init(); err != nil {
 		return common.CodeSystemErr, err
 	}
+	if k.subCommand == "apply" {
+		if err = k.apply(ctx); err != nil {
+			return k.failed(ctx, err)
+		}
+		return common.CodeSuccess, nil
+	}
+	if k.subCommand == "job" {
+		return k.job(ctx)
+	}
+	if k.Strategy != nil {
+		if err = k.rollout(ctx); err != nil {
+			return k.failed(ctx, err)
+		}
+		return common.CodeSuccess, nil
+	}
 	var wg sync.WaitGroup
 	var errCh = make(chan error, len(k.Resources))
 	var done = make(chan struct{})
@@ -173,6 +213,18 @@ func (k *SKubectl) Run(ctx context.Context) (exit int64, err error) {
 	return
 }
 
+// failed 执行失败的退出码, 超时及强杀以上下文为准, 其余为执行失败
+func (k *SKubectl) failed(ctx context.Context, err error) (int64, error) {
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	k.storage.Log().Write(err.Error())
+	return common.CodeFailed, err
+}
+
 func (k *SKubectl) getResourceValue(lValue, gValue, env string) (string, error) {
 	if lValue != "" {
 		return lValue, nil
@@ -198,9 +250,16 @@ func (k *SKubectl) run(ctx context.Context, resource *types.SResource) (err erro
 			return err
 		}
 	case "update":
+		if resource.GetRollbackOnFailure() {
+			return k.updateWithRollback(ctx, rs, resource)
+		}
 		if err = rs.Update(); err != nil {
 			return err
 		}
+	case "rollback":
+		if err = rs.Rollback(resource.Revision); err != nil {
+			return err
+		}
 	case "scale":
 		if err = rs.Scale(resource.GetReplicas()); err != nil {
 			return err
@@ -215,6 +274,38 @@ func (k *SKubectl) run(ctx context.Context, resource *types.SResource) (err erro
 	return rs.Println()
 }
 
+// updateWithRollback 更新后滚动失败时恢复更新前的模板, 并等待回滚完成
+func (k *SKubectl) updateWithRollback(ctx context.Context, rs IResource, resource *types.SResource) error {
+	template, err := rs.Template()
+	if err != nil {
+		return err
+	}
+	if err = rs.Update(); err != nil {
+		return err
+	}
+	err = Status(ctx, k.storage, k.dynamicClient, resource)
+	if err == nil {
+		return rs.Println()
+	}
+	k.storage.Log().Writef("%s %s/%s rollout failed: %v, rolling back", resource.GetKind(), resource.GetNamespace(), resource.GetName(), err)
+
+	// 步骤可能已超时或被取消, 回滚使用独立的超时
+	rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	rs = ResourceFor(rbCtx, k.storage, k.client, resource)
+	if rbErr := rs.Restore(template); rbErr != nil {
+		k.storage.Log().Writef("%s %s/%s rollback failed: %v", resource.GetKind(), resource.GetNamespace(), resource.GetName(), rbErr)
+		return errors.Join(err, rbErr)
+	}
+	if rbErr := Status(rbCtx, k.storage, k.dynamicClient, resource); rbErr != nil {
+		k.storage.Log().Writef("%s %s/%s rollback failed: %v", resource.GetKind(), resource.GetNamespace(), resource.GetName(), rbErr)
+		return errors.Join(err, rbErr)
+	}
+	k.storage.Log().Writef("%s %s/%s rolled back successfully", resource.GetKind(), resource.GetNamespace(), resource.GetName())
+	_ = rs.Println()
+	return fmt.Errorf("rollout failed and was rolled back: %w", err)
+}
+
 func (k *SKubectl) Clear() error {
 	return nil
 }
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/pkg/jinja"
+)
+
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+// manifests 读取内联清单与清单文件, 渲染后解析为对象
+func (k *SKubectl) manifests() ([]*unstructured.Unstructured, error) {
+	type source struct {
+		name    string
+		content string
+	}
+	var sources []source
+	if strings.TrimSpace(k.Manifests) != "" {
+		sources = append(sources, source{name: "manifests", content: k.Manifests})
+	}
+	for _, pattern := range k.Files {
+		files, err := k.manifestFiles(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s not found", pattern)
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, source{name: file, content: string(content)})
+		}
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("manifests or files is required")
+	}
+
+	var data = k.envs()
+	for key, value := range k.Vars {
+		data[key] = value
+	}
+	var objs []*unstructured.Unstructured
+	for _, src := range sources {
+		content := envRegexp.ReplaceAllStringFunc(src.content, func(v string) string {
+			if value, ok := data[envRegexp.FindStringSubmatch(v)[1]].(string); ok {
+				return value
+			}
+			return v
+		})
+		if !k.Raw {
+			var err error
+			if content, err = jinja.Parse(content, data); err != nil {
+				return nil, fmt.Errorf("%s: %w", src.name, err)
+			}
+		}
+		res, err := decodeManifests(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.name, err)
+		}
+		objs = append(objs, res...)
+	}
+	return objs, nil
+}
+
+// manifestFiles 展开清单路径, 目录下的yaml/yml/json文件按名称排序
+func (k *SKubectl) manifestFiles(pattern string) ([]string, error) {
+	path := strings.TrimSpace(pattern)
+	if filepath.IsAbs(path) {
+		path = filepath.Clean(path)
+		if !config.App.FileAllowed(path) {
+			return nil, fmt.Errorf("path %s is not allowed by policy", path)
+		}
+	} else {
+		path = filepath.Join(k.workspace, utils.PathEscape(path))
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+		entries, err := os.ReadDir(match)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(match, entry.Name()))
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// decodeManifests 解析多文档yaml或json, List展开为其中的对象
+func decodeManifests(content string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+	for i := 1; ; i++ {
+		var obj map[string]any
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", i)
+		}
+		if u.IsList() {
+			err := u.EachListItem(func(item runtime.Object) error {
+				objs = append(objs, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+			continue
+		}
+		if u.GetName() == "" && u.GetGenerateName() == "" {
+			return nil, fmt.Errorf("document %d: %s has no name", i, u.GetKind())
+		}
+		objs = append(objs, u)
+	}
+}
+
+func (k *SKubectl) envs() map[string]any {
+	var envs = make(map[string]any)
+	for _, env := range k.storage.GlobalEnv().List() {
+		envs[env.Name] = env.Value
+	}
+	for _, env := range k.storage.Env().List() {
+		envs[env.Name] = env.Value
+	}
+	envs["TASK_NAME"] = k.storage.TaskName()
+	envs["TASK_STEP_NAME"] = k.storage.Name()
+	envs["TASK_WORKSPACE"] = k.workspace
+	return envs
+}
@@ -61,11 +61,10 @@ func Status(ctx context.Context, storage storage.IStep, dynamicClient dynamic.In
 			return dynamicClient.Resource(gvr).Namespace(resource.GetNamespace()).Watch(ctx, options)
 		},
 	}
-	s.status()
-	return nil
+	return s.status()
 }
 
-func (s *sStatusViewer) status() {
+func (s *sStatusViewer) status() error {
 	_, err := watchtools.UntilWithSync(s.ctx, s.lw, &unstructured.Unstructured{}, nil, func(e watch.Event) (bool, error) {
 		switch t := e.Type; t {
 		case watch.Added, watch.Modified:
@@ -88,8 +87,9 @@ func (s *sStatusViewer) status() {
 	})
 	if err != nil {
 		s.storage.Log().Writef("error: %s", err.Error())
-		return
+		return err
 	}
+	return nil
 }
 
 func (s *sStatusViewer) getResourceCondition(status appsv1.DeploymentStatus, condType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
@@ -141,7 +141,7 @@ func (s *sStatusViewer) watchDaemonSet(obj runtime.Unstructured) (string, bool,
 		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, daemon, err)
 	}
 	if daemon.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
-		return "", true, fmt.Errorf("rollout status is only available for %s strategy type", appsv1.RollingUpdateStatefulSetStrategyType)
+		return fmt.Sprintf("rollout status is only available for %s strategy type, skip waiting", appsv1.RollingUpdateDaemonSetStrategyType), true, nil
 	}
 	if daemon.Generation <= daemon.Status.ObservedGeneration {
 		if daemon.Status.UpdatedNumberScheduled < daemon.Status.DesiredNumberScheduled {
@@ -166,7 +166,7 @@ func (s *sStatusViewer) watchStatefulSet(obj runtime.Unstructured) (string, bool
 		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, sts, err)
 	}
 	if sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
-		return "", true, fmt.Errorf("rollout status is only available for %s strategy type", appsv1.RollingUpdateStatefulSetStrategyType)
+		return fmt.Sprintf("rollout status is only available for %s strategy type, skip waiting", appsv1.RollingUpdateStatefulSetStrategyType), true, nil
 	}
 	if sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration {
 		return fmt.Sprintf("waiting for stateful set %s/%s spec update to be observed...",
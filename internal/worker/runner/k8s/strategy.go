@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/k8s/types"
+)
+
+// waves 按策略将资源分批
+func (k *SKubectl) waves() ([][]*types.SResource, error) {
+	var s = k.Strategy
+	if len(s.Waves) == 0 {
+		size := s.BatchSize
+		if size <= 0 {
+			size = 1
+		}
+		var waves [][]*types.SResource
+		for i := 0; i < len(k.Resources); i += size {
+			waves = append(waves, k.Resources[i:min(i+size, len(k.Resources))])
+		}
+		return waves, nil
+	}
+
+	var assigned = make(map[*types.SResource]bool, len(k.Resources))
+	var waves [][]*types.SResource
+	for i, names := range s.Waves {
+		var wave []*types.SResource
+		for _, name := range names {
+			res, err := k.lookup(name)
+			if err != nil {
+				return nil, fmt.Errorf("wave %d: %w", i+1, err)
+			}
+			if assigned[res] {
+				return nil, fmt.Errorf("wave %d: %s is already in a previous wave", i+1, name)
+			}
+			assigned[res] = true
+			wave = append(wave, res)
+		}
+		if len(wave) > 0 {
+			waves = append(waves, wave)
+		}
+	}
+	var rest []*types.SResource
+	for _, res := range k.Resources {
+		if !assigned[res] {
+			rest = append(rest, res)
+		}
+	}
+	if len(rest) > 0 {
+		waves = append(waves, rest)
+	}
+	return waves, nil
+}
+
+// lookup 按name或namespace/name查找资源
+func (k *SKubectl) lookup(name string) (*types.SResource, error) {
+	var found *types.SResource
+	for _, res := range k.Resources {
+		if name != res.GetName() && name != res.GetNamespace()+"/"+res.GetName() {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%s matches multiple resources, use namespace/name", name)
+		}
+		found = res
+	}
+	if found == nil {
+		return nil, fmt.Errorf("resource %s not found", name)
+	}
+	return found, nil
+}
+
+// rollout 按批次执行, 批次间暂停或等待确认, 失败数超过限制或验证失败时中止
+func (k *SKubectl) rollout(ctx context.Context) error {
+	var s = k.Strategy
+	var pause time.Duration
+	if s.Pause != "" {
+		var err error
+		if pause, err = time.ParseDuration(s.Pause); err != nil {
+			return fmt.Errorf("invalid pause %s", s.Pause)
+		}
+	}
+	waves, err := k.waves()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, wave := range waves {
+		if i > 0 {
+			if err = k.between(ctx, i+1, pause); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+		}
+		k.storage.Log().Writef("wave %d/%d: %s", i+1, len(waves), names(wave))
+		errs = append(errs, k.runWave(ctx, wave)...)
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, context.Cause(ctx))...)
+		}
+		if len(errs) > s.MaxFailures {
+			k.skipped(waves[i+1:])
+			return errors.Join(append(errs, fmt.Errorf("%d resources failed, exceeds max_failures %d, rollout aborted at wave %d", len(errs), s.MaxFailures, i+1))...)
+		}
+		if s.Verify != nil {
+			if err = k.verify(ctx, s.Verify, i+1, wave); err != nil {
+				k.skipped(waves[i+1:])
+				return errors.Join(append(errs, fmt.Errorf("wave %d verification failed, rollout aborted: %w", i+1, err))...)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		k.storage.Log().Writef("rollout finished with %d failed resources", len(errs))
+	}
+	return errors.Join(errs...)
+}
+
+// runWave 并发执行同一批次的资源, 返回各资源的错误
+func (k *SKubectl) runWave(ctx context.Context, wave []*types.SResource) []error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, resource := range wave {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := k.run(ctx, resource); err != nil {
+				k.storage.Log().Writef("%s/%s Error: %s", resource.GetNamespace(), resource.GetName(), err.Error())
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s/%s: %w", resource.GetNamespace(), resource.GetName(), err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// between 下一批次开始前暂停或等待确认
+func (k *SKubectl) between(ctx context.Context, wave int, pause time.Duration) error {
+	if pause > 0 {
+		k.storage.Log().Writef("pause %s before wave %d", pause, wave)
+		if err := sleep(ctx, pause); err != nil {
+			return err
+		}
+	}
+	if k.Strategy.Approval {
+		k.storage.Log().Writef("waiting for approval before wave %d, approve with the step action approve", wave)
+		if err := common.WaitApproval(ctx, k.storage.TaskName(), k.storage.Name()); err != nil {
+			return err
+		}
+		k.storage.Log().Writef("wave %d approved", wave)
+	}
+	return nil
+}
+
+func (k *SKubectl) skipped(waves [][]*types.SResource) {
+	for _, wave := range waves {
+		k.storage.Log().Writef("skipped: %s", names(wave))
+	}
+}
+
+func names(wave []*types.SResource) string {
+	var list = make([]string, 0, len(wave))
+	for _, res := range wave {
+		list = append(list, res.GetKind()+" "+res.GetNamespace()+"/"+res.GetName())
+	}
+	return strings.Join(list, ", ")
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubetypes "k8s.io/apimachinery/pkg/types"
@@ -19,8 +20,9 @@ import (
 type SStatefulSet struct {
 	context.Context
 	*types.SResource
-	Client  appv1.StatefulSetInterface
-	Storage storage.IStep
+	Client    appv1.StatefulSetInterface
+	Revisions appv1.ControllerRevisionInterface
+	Storage   storage.IStep
 }
 
 func (s *SStatefulSet) Restart() error {
@@ -101,3 +103,65 @@ func (s *SStatefulSet) Println() error {
 		return nil
 	})
 }
+
+func (s *SStatefulSet) Template() (*corev1.PodTemplateSpec, error) {
+	result, err := s.Client.Get(s.Context, s.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Spec.Template.DeepCopy(), nil
+}
+
+func (s *SStatefulSet) Restore(template *corev1.PodTemplateSpec) error {
+	return kuberetry.RetryOnConflict(kuberetry.DefaultRetry, func() error {
+		result, err := s.Client.Get(s.Context, s.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		result.Spec.Template = *template.DeepCopy()
+		_, err = s.Client.Update(s.Context, result, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Rollback 恢复到ControllerRevision记录的版本
+func (s *SStatefulSet) Rollback(revision int64) error {
+	result, err := s.Client.Get(s.Context, s.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(result.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	list, err := s.Revisions.List(s.Context, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	var patches = make(map[int64][]byte)
+	var revisions []int64
+	var current int64
+	for i := range list.Items {
+		rev := &list.Items[i]
+		if !metav1.IsControlledBy(rev, result) {
+			continue
+		}
+		patches[rev.Revision] = rev.Data.Raw
+		revisions = append(revisions, rev.Revision)
+		if rev.Name == result.Status.UpdateRevision {
+			current = rev.Revision
+		}
+	}
+	target, err := types.PickRevision(revisions, current, revision)
+	if err != nil {
+		return err
+	}
+	if target == current {
+		s.Storage.Log().Writef("%s %s/%s is already at revision %d", s.GetKind(), s.GetNamespace(), s.GetName(), target)
+		return nil
+	}
+	s.Storage.Log().Writef("rollback %s %s/%s from revision %d to %d", s.GetKind(), s.GetNamespace(), s.GetName(), current, target)
+	// 版本数据是spec.template的策略合并补丁
+	_, err = s.Client.Patch(s.Context, s.GetName(), kubetypes.StrategicMergePatchType, patches[target], metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+const RevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// PickRevision 从已有版本中选择回滚目标, revision为0时取当前版本之前的最新版本
+func PickRevision(revisions []int64, current, revision int64) (int64, error) {
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+	if revision > 0 {
+		for _, r := range revisions {
+			if r == revision {
+				return r, nil
+			}
+		}
+		return 0, fmt.Errorf("revision %d not found, available revisions %v", revision, revisions)
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i] < current {
+			return revisions[i], nil
+		}
+	}
+	return 0, fmt.Errorf("no revision before %d, available revisions %v", current, revisions)
+}
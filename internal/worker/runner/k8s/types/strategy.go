@@ -0,0 +1,42 @@
+package types
+
+import (
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/http"
+)
+
+// SStrategy 分批发布策略, 按批次依次更新资源
+type SStrategy struct {
+	BatchSize   int        `json:"batch_size"`   // 每批资源数, 未指定waves时生效, 默认1
+	Waves       [][]string `json:"waves"`        // 按名称或namespace/name分批, 未列出的资源追加为最后一批
+	Pause       string     `json:"pause"`        // 批次间暂停时长
+	Approval    bool       `json:"approval"`     // 批次间等待人工确认
+	MaxFailures int        `json:"max_failures"` // 允许失败的资源数, 超过后中止后续批次
+	Verify      *SVerify   `json:"verify"`       // 每批完成后的验证
+}
+
+// SVerify 批次验证, 在重试次数内通过即可
+type SVerify struct {
+	HTTP     *SHTTPCheck   `json:"http"`
+	Metric   *SMetricCheck `json:"metric"`
+	Delay    string        `json:"delay"`    // 批次完成后首次验证前的等待时长
+	Retries  int           `json:"retries"`  // 默认3
+	Interval string        `json:"interval"` // 重试间隔, 默认10s
+}
+
+// SHTTPCheck 请求地址, 状态码与响应内容满足条件时通过
+type SHTTPCheck struct {
+	URL      string            `json:"url"`
+	Method   string            `json:"method"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	Assert   *http.SAssert     `json:"assert"`   // 与http执行器断言相同, 未指定时只允许2xx
+	Timeout  string            `json:"timeout"`  // 默认10s
+	Insecure bool              `json:"insecure"` // 跳过证书校验
+}
+
+// SMetricCheck 查询Prometheus指标, 表达式结果为true时通过
+type SMetricCheck struct {
+	URL   string `json:"url"`   // Prometheus地址
+	Query string `json:"query"` // PromQL
+	Expr  string `json:"expr"`  // 如 value < 0.05, value为首个结果, values为全部结果
+}
@@ -16,6 +16,8 @@ type SResource struct {
 	ImageTag            string `json:"image_tag"`
 	IgnoreInitContainer *bool  `json:"ignoreInitContainer"`
 	Env                 []Env  `json:"env"`
+	RollbackOnFailure   *bool  `json:"rollback_on_failure"` // update后滚动失败时恢复到更新前的模板
+	Revision            int64  `json:"revision"`            // rollback的目标版本, 默认上一个版本
 }
 
 type Env struct {
@@ -61,6 +63,10 @@ func (r *SResource) GetReplicas() int32 {
 	return *r.Replicas
 }
 
+func (r *SResource) GetRollbackOnFailure() bool {
+	return r.RollbackOnFailure != nil && *r.RollbackOnFailure
+}
+
 func (r *SResource) GetImageTag() string {
 	return r.ImageTag
 }
@@ -0,0 +1,210 @@
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/k8s/types"
+)
+
+// verify 批次完成后执行验证, 在重试次数内通过即可
+func (k *SKubectl) verify(ctx context.Context, v *types.SVerify, wave int, resources []*types.SResource) error {
+	if v.HTTP == nil && v.Metric == nil {
+		return errors.New("verify requires http or metric")
+	}
+	var retries = v.Retries
+	if retries <= 0 {
+		retries = 3
+	}
+	var interval = 10 * time.Second
+	if v.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(v.Interval); err != nil {
+			return fmt.Errorf("invalid interval %s", v.Interval)
+		}
+	}
+	if v.Delay != "" {
+		delay, err := time.ParseDuration(v.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %s", v.Delay)
+		}
+		k.storage.Log().Writef("wait %s before verifying wave %d", delay, wave)
+		if err = sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	var list = make([]string, 0, len(resources))
+	for _, res := range resources {
+		list = append(list, res.GetName())
+	}
+	// ${WAVE}与${RESOURCES}替换为当前批次序号与资源名称
+	replacer := strings.NewReplacer("${WAVE}", strconv.Itoa(wave), "${RESOURCES}", strings.Join(list, ","))
+
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		if attempt > 1 {
+			if err = sleep(ctx, interval); err != nil {
+				return err
+			}
+		}
+		var msg string
+		if v.HTTP != nil {
+			msg, err = httpCheck(ctx, v.HTTP, replacer)
+		}
+		if err == nil && v.Metric != nil {
+			msg, err = metricCheck(ctx, v.Metric, replacer)
+		}
+		if err == nil {
+			k.storage.Log().Writef("wave %d verification passed: %s", wave, msg)
+			return nil
+		}
+		k.storage.Log().Writef("wave %d verification attempt %d/%d failed: %v", wave, attempt, retries, err)
+	}
+	return err
+}
+
+// httpCheck 请求后按http执行器的断言校验响应
+func httpCheck(ctx context.Context, c *types.SHTTPCheck, replacer *strings.Replacer) (string, error) {
+	var timeout = 10 * time.Second
+	if c.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return "", fmt.Errorf("invalid timeout %s", c.Timeout)
+		}
+	}
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	target := replacer.Replace(c.URL)
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), target, strings.NewReader(replacer.Replace(c.Body)))
+	if err != nil {
+		return "", err
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	latency := time.Since(start)
+	if err != nil {
+		return "", err
+	}
+	if failures := c.Assert.Check(resp, body, latency); len(failures) > 0 {
+		return "", fmt.Errorf("%s %s assertion failed: %s", req.Method, target, strings.Join(failures, "; "))
+	}
+	return fmt.Sprintf("%s %s returned status %d", req.Method, target, resp.StatusCode), nil
+}
+
+// metricCheck 通过Prometheus即时查询获取指标, 使用表达式判断
+func metricCheck(ctx context.Context, m *types.SMetricCheck, replacer *strings.Replacer) (string, error) {
+	if m.URL == "" || m.Query == "" || m.Expr == "" {
+		return "", errors.New("metric requires url, query and expr")
+	}
+	query := replacer.Replace(m.Query)
+	values, err := promQuery(ctx, m.URL, query)
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("query %s returned no data", query)
+	}
+	env := map[string]any{"value": values[0], "values": values}
+	program, err := expr.Compile(m.Expr, expr.Env(env), expr.AsBool())
+	if err != nil {
+		return "", fmt.Errorf("invalid expr %s: %w", m.Expr, err)
+	}
+	result, err := expr.Run(program, env)
+	if err != nil {
+		return "", err
+	}
+	if !result.(bool) {
+		return "", fmt.Errorf("%s is false, value %v", m.Expr, values[0])
+	}
+	return fmt.Sprintf("%s, value %v", m.Expr, values[0]), nil
+}
+
+// promQuery 执行/api/v1/query, 返回vector或scalar结果的值
+func promQuery(ctx context.Context, base, query string) ([]float64, error) {
+	target := strings.TrimSuffix(base, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("query %s: status %d: %w", query, resp.StatusCode, err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("query %s: %s", query, res.Error)
+	}
+
+	var samples [][2]any
+	switch res.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Value [2]any `json:"value"`
+		}
+		if err = json.Unmarshal(res.Data.Result, &vector); err != nil {
+			return nil, err
+		}
+		for _, v := range vector {
+			samples = append(samples, v.Value)
+		}
+	case "scalar":
+		var scalar [2]any
+		if err = json.Unmarshal(res.Data.Result, &scalar); err != nil {
+			return nil, err
+		}
+		samples = append(samples, scalar)
+	default:
+		return nil, fmt.Errorf("query %s: unsupported result type %s", query, res.Data.ResultType)
+	}
+
+	var values = make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		s, _ := sample[1].(string)
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: invalid value %v", query, sample[1])
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
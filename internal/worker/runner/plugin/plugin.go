@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/plugin"
+	sdk "github.com/xmapst/AutoExecFlow/pkg/plugin"
+)
+
+// SPlugin 由插件进程执行的步骤
+type SPlugin struct {
+	storage   storage.IStep
+	typ       string
+	workspace string
+	result    string
+}
+
+// Supported 是否有插件声明了该类型, 包括重启中的插件
+func Supported(typ string) bool {
+	_, _, ok := plugin.Lookup(typ)
+	return ok
+}
+
+func New(storage storage.IStep, typ, workspace string) (*SPlugin, error) {
+	return &SPlugin{
+		storage:   storage,
+		typ:       typ,
+		workspace: workspace,
+	}, nil
+}
+
+func (p *SPlugin) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			p.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+
+	name, client, ok := plugin.Lookup(p.typ)
+	if !ok {
+		err = fmt.Errorf("no plugin handles type %s", p.typ)
+		p.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	if client == nil {
+		err = fmt.Errorf("plugin %s handling type %s is not running", name, p.typ)
+		p.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	req, err := p.request()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(req.Timeout)*time.Millisecond, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	events, err := client.Run(ctx, req)
+	if err != nil {
+		return p.failed(ctx, name, err)
+	}
+	for {
+		event, err := events.Recv()
+		if err != nil {
+			return p.failed(ctx, name, err)
+		}
+		if event.Log != nil {
+			p.log(event.Log)
+		}
+		if len(event.Outputs) > 0 {
+			var outputs models.SEnvs
+			for _, v := range event.Outputs {
+				outputs = append(outputs, &models.SEnv{Name: v.Name, Value: v.Value})
+			}
+			if err = p.storage.Output().Insert(outputs...); err != nil {
+				p.storage.Log().Write(err.Error())
+				return common.CodeSystemErr, err
+			}
+		}
+		if event.Result != "" {
+			p.result = event.Result
+		}
+		if event.Exit != nil {
+			if event.Exit.Error != "" {
+				return event.Exit.Code, errors.New(event.Exit.Error)
+			}
+			return event.Exit.Code, nil
+		}
+	}
+}
+
+func (p *SPlugin) request() (*sdk.SRunRequest, error) {
+	content, err := p.storage.Content()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := p.storage.Timeout()
+	if err != nil {
+		return nil, err
+	}
+	action, err := p.storage.Action()
+	if err != nil {
+		return nil, err
+	}
+	rule, err := p.storage.Rule()
+	if err != nil {
+		return nil, err
+	}
+	var req = &sdk.SRunRequest{
+		Task:      p.storage.TaskName(),
+		Step:      p.storage.Name(),
+		Type:      p.typ,
+		Content:   content,
+		Workspace: p.workspace,
+		Timeout:   timeout.Milliseconds(),
+		Action:    action,
+		Rule:      rule,
+	}
+	for _, env := range p.storage.GlobalEnv().List() {
+		req.Env = append(req.Env, &sdk.SEnv{Name: env.Name, Value: env.Value})
+	}
+	for _, env := range p.storage.Env().List() {
+		req.Env = append(req.Env, &sdk.SEnv{Name: env.Name, Value: env.Value})
+	}
+	return req, nil
+}
+
+func (p *SPlugin) log(l *sdk.SLog) {
+	var log = &models.SStepLog{
+		Timestamp: time.Now().UnixNano(),
+		Content:   l.Content,
+		Stream:    l.Stream,
+		Level:     l.Level,
+	}
+	if utils.HasAnsi(l.Content) {
+		log.Content, log.Raw = utils.StripAnsi(l.Content), l.Content
+	}
+	_ = p.storage.Log().Insert(log)
+}
+
+// failed 连接中断时区分步骤取消, 超时与插件异常
+func (p *SPlugin) failed(ctx context.Context, name string, err error) (int64, error) {
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	err = fmt.Errorf("plugin %s: %w", name, err)
+	p.storage.Log().Write(err.Error())
+	return common.CodeSystemErr, err
+}
+
+func (p *SPlugin) Clear() error {
+	_, client, _ := plugin.Lookup(p.typ)
+	if client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return client.Clear(ctx, &sdk.SClearRequest{
+		Task: p.storage.TaskName(),
+		Step: p.storage.Name(),
+	})
+}
+
+// Result 插件返回的执行结果描述
+func (p *SPlugin) Result() string {
+	return p.result
+}
@@ -5,8 +5,15 @@ import (
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/exec"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/file"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/git"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/http"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/js"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/k8s"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/mkdir"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/plugin"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/sql"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/ssh"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/touch"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/wasm"
 	"github.com/xmapst/AutoExecFlow/internal/worker/runner/yaegi"
@@ -23,14 +30,28 @@ func New(
 	switch {
 	case strings.HasPrefix(commandType, "kubectl"):
 		return k8s.New(storage, strings.TrimPrefix(commandType, "kubectl@"), workspace)
+	case strings.HasPrefix(commandType, "ssh@"):
+		return ssh.New(storage, strings.TrimPrefix(commandType, "ssh@"), workspace)
+	case strings.EqualFold(commandType, "http"):
+		return http.New(storage, workspace)
+	case strings.EqualFold(commandType, "git"):
+		return git.New(storage, workspace)
+	case strings.EqualFold(commandType, "sql"):
+		return sql.New(storage, workspace)
 	case strings.EqualFold(commandType, "mkdir"):
 		return mkdir.New(storage, workspace)
 	case strings.EqualFold(commandType, "touch"):
 		return touch.New(storage, workspace)
+	case strings.EqualFold(commandType, "js"):
+		return js.New(storage, workspace)
+	case file.Supported(commandType):
+		return file.New(storage, commandType, workspace)
 	case strings.EqualFold(commandType, "yaegi"):
 		return yaegi.New(storage, workspace)
 	case strings.EqualFold(commandType, "wasm"):
 		return wasm.New(storage, workspace)
+	case plugin.Supported(commandType):
+		return plugin.New(storage, commandType, workspace)
 	default:
 		return exec.New(storage, commandType, workspace, scriptDir)
 	}
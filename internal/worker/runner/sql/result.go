@@ -0,0 +1,287 @@
+package sql
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
+)
+
+// 日志中单元格的最大宽度
+const maxCellWidth = 64
+
+type sResult struct {
+	columns  []string
+	rows     [][]any // 无需完整结果集时只保留日志中显示的行
+	total    int     // 返回行数
+	affected int64
+}
+
+// objects 结果集转换为对象数组
+func (r *sResult) objects() []map[string]any {
+	var res = make([]map[string]any, 0, len(r.rows))
+	for _, row := range r.rows {
+		var obj = make(map[string]any, len(r.columns))
+		for i, column := range r.columns {
+			obj[column] = row[i]
+		}
+		res = append(res, obj)
+	}
+	return res
+}
+
+// value 按表达式取值
+//
+//	affected: 影响行数, 查询语句为返回行数
+//	rows: 返回行数
+//	json: 结果集json
+//	json.<path>: 结果集json的gjson路径, 如 json.0.id
+//	<column>: 第一行指定列的值
+func (r *sResult) value(expr string) (string, error) {
+	switch {
+	case expr == "affected":
+		return strconv.FormatInt(r.affected, 10), nil
+	case expr == "rows":
+		return strconv.Itoa(r.total), nil
+	case expr == "json":
+		b, err := json.Marshal(r.objects())
+		return string(b), err
+	case strings.HasPrefix(expr, "json."):
+		b, err := json.Marshal(r.objects())
+		if err != nil {
+			return "", err
+		}
+		res := gjson.GetBytes(b, strings.TrimPrefix(expr, "json."))
+		if !res.Exists() {
+			return "", fmt.Errorf("%s not found in result", expr)
+		}
+		return res.String(), nil
+	default:
+		if len(r.rows) == 0 {
+			return "", fmt.Errorf("%s not found, result is empty", expr)
+		}
+		for i, column := range r.columns {
+			if strings.EqualFold(column, expr) {
+				return cell(r.rows[0][i]), nil
+			}
+		}
+		return "", fmt.Errorf("column %s not found in result", expr)
+	}
+}
+
+// capture 保存输出
+func (s *SSql) capture(stmt *SStatement, r *sResult) error {
+	if len(stmt.Outputs) == 0 {
+		return nil
+	}
+	var outputs models.SEnvs
+	for name, expr := range stmt.Outputs {
+		value, err := r.value(expr)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+		outputs = append(outputs, &models.SEnv{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return s.storage.Output().Insert(outputs...)
+}
+
+// assert 校验影响行数及返回行数
+func (s *SSql) assert(stmt *SStatement, r *sResult) error {
+	var failures []string
+	if stmt.Affected != "" {
+		ok, err := compare(stmt.Affected, r.affected)
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else if !ok {
+			failures = append(failures, fmt.Sprintf("affected rows %d, expected %s", r.affected, stmt.Affected))
+		}
+	}
+	if stmt.Rows != "" {
+		ok, err := compare(stmt.Rows, int64(r.total))
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else if !ok {
+			failures = append(failures, fmt.Sprintf("rows %d, expected %s", r.total, stmt.Rows))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	for _, failure := range failures {
+		s.storage.Log().Write("assertion failed: " + failure)
+	}
+	return fmt.Errorf("statement %s assertion failed: %s", stmt.Name, strings.Join(failures, "; "))
+}
+
+// compare 支持 1, =1, !=0, >0, >=1, <10, <=10
+func compare(expr string, n int64) (bool, error) {
+	expr = strings.TrimSpace(expr)
+	var op = "="
+	for _, v := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if strings.HasPrefix(expr, v) {
+			op, expr = v, strings.TrimSpace(strings.TrimPrefix(expr, v))
+			break
+		}
+	}
+	expected, err := strconv.ParseInt(expr, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid assertion %s", expr)
+	}
+	switch op {
+	case ">=":
+		return n >= expected, nil
+	case "<=":
+		return n <= expected, nil
+	case "!=":
+		return n != expected, nil
+	case ">":
+		return n > expected, nil
+	case "<":
+		return n < expected, nil
+	default:
+		return n == expected, nil
+	}
+}
+
+// logTable 结果集以表格形式写入日志
+func (s *SSql) logTable(r *sResult) {
+	if len(r.columns) == 0 {
+		return
+	}
+	var rows = r.rows
+	if len(rows) > s.MaxRows {
+		rows = rows[:s.MaxRows]
+	}
+	var widths = make([]int, len(r.columns))
+	var cells = make([][]string, len(rows))
+	for i, column := range r.columns {
+		widths[i] = utf8.RuneCountInString(column)
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, v := range row {
+			c := truncate(cell(v))
+			cells[i][j] = c
+			widths[j] = max(widths[j], utf8.RuneCountInString(c))
+		}
+	}
+
+	var border bytes.Buffer
+	border.WriteString("+")
+	for _, w := range widths {
+		border.WriteString(strings.Repeat("-", w+2))
+		border.WriteString("+")
+	}
+	line := func(values []string) string {
+		var b strings.Builder
+		b.WriteString("|")
+		for i, v := range values {
+			b.WriteString(" ")
+			b.WriteString(v)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+			b.WriteString(" |")
+		}
+		return b.String()
+	}
+	s.storage.Log().Write(border.String())
+	s.storage.Log().Write(line(r.columns))
+	s.storage.Log().Write(border.String())
+	for _, row := range cells {
+		s.storage.Log().Write(line(row))
+	}
+	s.storage.Log().Write(border.String())
+	if r.total > len(rows) {
+		s.storage.Log().Writef("... %d more rows not shown", r.total-len(rows))
+	}
+}
+
+// save 结果集保存到工作目录, .json保存为对象数组, 其他保存为csv
+func (s *SSql) save(name string, r *sResult) error {
+	path, err := common.Path("save_to", s.workspace, name, volume.Roots(s.storage.TaskName())...)
+	if err != nil {
+		return err
+	}
+	if err = utils.EnsureDirExist(filepath.Dir(path)); err != nil {
+		return err
+	}
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(r.objects(), "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.Write(r.columns); err != nil {
+			return err
+		}
+		for _, row := range r.rows {
+			var record = make([]string, len(row))
+			for i, v := range row {
+				if v != nil {
+					record[i] = cell(v)
+				}
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	if err := os.WriteFile(path, data, os.ModePerm); err != nil {
+		return err
+	}
+	s.storage.Log().Writef("result saved to %s", name)
+	return nil
+}
+
+// normalize 驱动返回的值转换为可json序列化的类型
+func normalize(v any) any {
+	switch val := v.(type) {
+	case []byte:
+		if utf8.Valid(val) {
+			return string(val)
+		}
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+func cell(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprint(v)
+}
+
+func truncate(s string) string {
+	s = strings.NewReplacer("\r", "\\r", "\n", "\\n", "\t", "\\t").Replace(s)
+	if utf8.RuneCountInString(s) <= maxCellWidth {
+		return s
+	}
+	return string([]rune(s)[:maxCellWidth-3]) + "..."
+}
@@ -0,0 +1,161 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"github.com/segmentio/ksuid"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+)
+
+// upload 将工作目录下的文件或目录上传到远端
+func (s *SSSH) upload(client *ssh.Client) error {
+	if len(s.Upload) == 0 {
+		return nil
+	}
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	for _, f := range s.Upload {
+		local := filepath.Join(s.workspace, utils.PathEscape(f.Src))
+		err = filepath.WalkDir(local, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(local, name)
+			if err != nil {
+				return err
+			}
+			remote := path.Join(f.Dst, filepath.ToSlash(rel))
+			if d.IsDir() {
+				return sc.MkdirAll(remote)
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			return s.uploadFile(sc, name, remote)
+		})
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", f.Src, err)
+		}
+		s.storage.Log().Writef("uploaded %s to %s", f.Src, f.Dst)
+	}
+	return nil
+}
+
+func (s *SSSH) uploadFile(sc *sftp.Client, local, remote string) error {
+	src, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err = sc.MkdirAll(path.Dir(remote)); err != nil {
+		return err
+	}
+	dst, err := sc.Create(remote)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if _, err = dst.ReadFrom(src); err != nil {
+		return err
+	}
+	if info, _err := src.Stat(); _err == nil {
+		_ = sc.Chmod(remote, info.Mode().Perm())
+	}
+	return nil
+}
+
+// uploadScript 通过sftp将脚本上传到远端并返回路径, 仅登录用户可读写
+func (s *SSSH) uploadScript(client *ssh.Client) (string, error) {
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return "", err
+	}
+	defer sc.Close()
+	name := path.Join(s.ScriptDir, ".aef_"+ksuid.New().String()+".sh")
+	file, err := sc.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return "", fmt.Errorf("upload script: %w", err)
+	}
+	if err = file.Chmod(0700); err == nil {
+		_, err = file.Write([]byte(s.script()))
+	}
+	if _err := file.Close(); err == nil {
+		err = _err
+	}
+	if err != nil {
+		_ = sc.Remove(name)
+		return "", fmt.Errorf("upload script: %w", err)
+	}
+	return name, nil
+}
+
+// download 将远端文件或目录下载到工作目录
+func (s *SSSH) download(client *ssh.Client) error {
+	if len(s.Download) == 0 {
+		return nil
+	}
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	for _, f := range s.Download {
+		local := filepath.Join(s.workspace, utils.PathEscape(f.Dst))
+		walker := sc.Walk(f.Src)
+		for walker.Step() {
+			if err = walker.Err(); err != nil {
+				break
+			}
+			rel := strings.TrimPrefix(walker.Path(), path.Clean(f.Src))
+			target := filepath.Join(local, filepath.FromSlash(rel))
+			info := walker.Stat()
+			if info.IsDir() {
+				if err = os.MkdirAll(target, os.ModePerm); err != nil {
+					break
+				}
+				continue
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			if err = s.downloadFile(sc, walker.Path(), target, info.Mode().Perm()); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("download %s: %w", f.Src, err)
+		}
+		s.storage.Log().Writef("downloaded %s to %s", f.Src, f.Dst)
+	}
+	return nil
+}
+
+func (s *SSSH) downloadFile(sc *sftp.Client, remote, local string, perm fs.FileMode) error {
+	src, err := sc.Open(remote)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err = os.MkdirAll(filepath.Dir(local), os.ModePerm); err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(local, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
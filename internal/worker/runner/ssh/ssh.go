@@ -0,0 +1,412 @@
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/yaml.v3"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
+)
+
+type SSSH struct {
+	storage   storage.IStep
+	workspace string
+	user      string
+	addr      string
+	closers   []io.Closer
+
+	Shell     string       `json:"shell" yaml:"shell"`           // 远端解释器, 默认bash
+	Script    string       `json:"script" yaml:"script"`         // 远端执行的脚本
+	ScriptDir string       `json:"script_dir" yaml:"script_dir"` // 远端脚本的上传目录, 默认/tmp
+	Upload    []*SFileCopy `json:"upload" yaml:"upload"`         // 执行前上传到远端
+	Download  []*SFileCopy `json:"download" yaml:"download"`     // 执行后下载到工作目录
+}
+
+type SFileCopy struct {
+	Src string `json:"src" yaml:"src"`
+	Dst string `json:"dst" yaml:"dst"`
+}
+
+// New target格式为 [user@]host[:port]
+func New(storage storage.IStep, target, workspace string) (*SSSH, error) {
+	if target == "" {
+		return nil, errors.New("ssh host is empty")
+	}
+	s := &SSSH{
+		storage:   storage,
+		workspace: workspace,
+		addr:      target,
+	}
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		s.user, s.addr = target[:i], target[i+1:]
+	}
+	return s, nil
+}
+
+func (s *SSSH) Run(ctx context.Context) (exit int64, err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			s.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+	if err = s.init(); err != nil {
+		s.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	timeout, err := s.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		s.storage.Log().Write(err.Error())
+		return s.exitCode(ctx, common.CodeSystemErr, err)
+	}
+	defer client.Close()
+	// 取消时关闭连接, 中断所有阻塞中的操作
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
+	if err = s.upload(client); err != nil {
+		s.storage.Log().Write(err.Error())
+		return s.exitCode(ctx, common.CodeSystemErr, err)
+	}
+	exit, err = s.exec(ctx, client)
+	if ctx.Err() != nil {
+		return s.exitCode(ctx, exit, err)
+	}
+	if exit == common.CodeSuccess {
+		if err = s.download(client); err != nil {
+			s.storage.Log().Write(err.Error())
+			return s.exitCode(ctx, common.CodeSystemErr, err)
+		}
+	}
+	return exit, err
+}
+
+func (s *SSSH) init() error {
+	content, err := s.storage.Content()
+	if err != nil {
+		return err
+	}
+	// 非结构化内容直接作为脚本执行
+	if _err := json.Unmarshal([]byte(content), s); _err != nil {
+		if _err = yaml.Unmarshal([]byte(content), s); _err != nil {
+			s.Script = content
+		}
+	}
+	if s.Script == "" && len(s.Upload) == 0 && len(s.Download) == 0 {
+		s.Script = content
+	}
+	if s.Shell == "" {
+		s.Shell = "bash"
+	}
+	if s.ScriptDir == "" {
+		s.ScriptDir = "/tmp"
+	}
+	for _, f := range append(s.Upload, s.Download...) {
+		if f == nil || f.Src == "" || f.Dst == "" {
+			return errors.New("src and dst of file copy are required")
+		}
+	}
+
+	if s.user == "" {
+		s.user = s.env("SSH_USER")
+	}
+	if s.user == "" {
+		s.user = "root"
+	}
+	if _, _, err = net.SplitHostPort(s.addr); err != nil {
+		port := s.env("SSH_PORT")
+		if port == "" {
+			port = "22"
+		}
+		s.addr = net.JoinHostPort(strings.Trim(s.addr, "[]"), port)
+	}
+	return nil
+}
+
+// env 依次从步骤和任务环境变量中获取
+func (s *SSSH) env(name string) string {
+	if value, err := s.storage.Env().Get(name); err == nil && value != "" {
+		return value
+	}
+	value, _ := s.storage.GlobalEnv().Get(name)
+	return value
+}
+
+func (s *SSSH) dial(ctx context.Context) (*ssh.Client, error) {
+	auth, err := s.authMethods()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := s.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User:            s.user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, s.addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// authMethods 支持私钥, 密码及ssh-agent认证
+func (s *SSSH) authMethods() (auth []ssh.AuthMethod, err error) {
+	if key := s.env("SSH_PRIVATE_KEY"); key != "" {
+		pem, _err := s.privateKey(key)
+		if _err != nil {
+			return nil, _err
+		}
+		var signer ssh.Signer
+		if passphrase := s.env("SSH_PASSPHRASE"); passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password := s.env("SSH_PASSWORD"); password != "" {
+		auth = append(auth, ssh.Password(password), ssh.KeyboardInteractive(
+			func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			},
+		))
+	}
+	// 只使用步骤或任务环境变量中的agent, 不使用服务进程的agent
+	if sock := s.env("SSH_AUTH_SOCK"); sock != "" {
+		conn, _err := net.Dial("unix", sock)
+		if _err != nil {
+			return nil, fmt.Errorf("connect ssh agent: %w", _err)
+		}
+		s.closers = append(s.closers, conn)
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("no ssh auth method, set SSH_PRIVATE_KEY, SSH_PASSWORD or SSH_AUTH_SOCK")
+	}
+	return auth, nil
+}
+
+// privateKey 私钥内容, 或工作空间内的私钥文件, 不读取服务端的其他路径
+func (s *SSSH) privateKey(key string) ([]byte, error) {
+	if strings.Contains(key, "-----BEGIN") {
+		return []byte(key), nil
+	}
+	name, err := common.File("SSH_PRIVATE_KEY", s.workspace, key, volume.Roots(s.storage.TaskName())...)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(name)
+}
+
+// hostKeyCallback 主机密钥校验, 优先使用指纹, 其次known_hosts
+func (s *SSSH) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if fingerprint := s.env("SSH_HOST_KEY"); fingerprint != "" {
+		return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != fingerprint {
+				return fmt.Errorf("host key mismatch for %s: %s", hostname, ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}, nil
+	}
+	if insecure, _ := strconv.ParseBool(s.env("SSH_INSECURE")); insecure {
+		s.storage.Log().Write("WARNING: host key verification is disabled")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	knownHosts := s.env("SSH_KNOWN_HOSTS")
+	switch {
+	case knownHosts == "":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	case !strings.ContainsAny(knownHosts, " \n"):
+		// 工作空间内的文件
+		name, err := common.File("SSH_KNOWN_HOSTS", s.workspace, knownHosts, volume.Roots(s.storage.TaskName())...)
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = name
+	default:
+		// known_hosts内容, 写入临时文件
+		file, err := os.CreateTemp("", "known_hosts")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(knownHosts)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = file.Name()
+	}
+	callback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("load known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+func (s *SSSH) exec(ctx context.Context, client *ssh.Client) (int64, error) {
+	if strings.TrimSpace(s.Script) == "" {
+		return common.CodeSuccess, nil
+	}
+	script, err := s.uploadScript(client)
+	if err != nil {
+		s.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		s.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	defer session.Close()
+	// 取消时向远端发送SIGKILL并关闭会话
+	stop := context.AfterFunc(ctx, func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+	})
+	defer stop()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	if err = session.Start(s.Shell + " " + quote(script)); err != nil {
+		s.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.copyOutput(&wg, stdout)
+	go s.copyOutput(&wg, stderr)
+	wg.Wait()
+
+	err = session.Wait()
+	if err == nil {
+		return common.CodeSuccess, nil
+	}
+	if ctx.Err() != nil {
+		return common.CodeKilled, err
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return int64(exitErr.ExitStatus()), nil
+	}
+	s.storage.Log().Write(err.Error())
+	return common.CodeFailed, err
+}
+
+// script 在脚本前注入环境变量, 远端sshd通常不接受setenv, 脚本包含环境变量的值, 启动后即删除自身
+func (s *SSSH) script() string {
+	var builder strings.Builder
+	builder.WriteString("rm -f -- \"$0\"\n")
+	write := func(name, value string) {
+		builder.WriteString(fmt.Sprintf("export %s=%s\n", name, quote(value)))
+	}
+	for _, env := range s.storage.GlobalEnv().List() {
+		if !strings.HasPrefix(env.Name, "SSH_") {
+			write(env.Name, env.Value)
+		}
+	}
+	for _, env := range s.storage.Env().List() {
+		if !strings.HasPrefix(env.Name, "SSH_") {
+			write(env.Name, env.Value)
+		}
+	}
+	write("TASK_NAME", s.storage.TaskName())
+	write("TASK_STEP_NAME", s.storage.Name())
+	builder.WriteString(s.Script)
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+func (s *SSSH) copyOutput(wg *sync.WaitGroup, reader io.Reader) {
+	defer wg.Done()
+	common.ReadLines(reader, func(line string) {
+		if line = strings.TrimSpace(line); line != "" {
+			s.storage.Log().Write(line)
+		}
+	})
+}
+
+func (s *SSSH) exitCode(ctx context.Context, exit int64, err error) (int64, error) {
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	return exit, err
+}
+
+func (s *SSSH) Clear() error {
+	var err error
+	for _, c := range s.closers {
+		err = errors.Join(err, c.Close())
+	}
+	s.closers = nil
+	return err
+}
+
+// quote 单引号转义
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -1,14 +1,53 @@
 package wasm
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"gopkg.in/yaml.v3"
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
+	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 )
 
+// wasm页大小 64KiB
+const pageSize = 64 * 1024
+
+var errFuelExhausted = errors.New("fuel exhausted, function call budget reached")
+
 type SWasm struct {
 	storage   storage.IStep
 	workspace string
+	workdir   string
+	args      []string
+	stdin     io.ReadCloser
+
+	Module string   `json:"module" yaml:"module"` // 工作目录下的wasm文件路径
+	Binary string   `json:"binary" yaml:"binary"` // base64编码的wasm二进制
+	Args   []string `json:"args" yaml:"args"`     // 命令行参数
+	Memory string   `json:"memory" yaml:"memory"` // 内存上限, 如: 64MiB
+	Fuel   uint64   `json:"fuel" yaml:"fuel"`     // 函数调用次数预算, 不限制CPU时间, 0为不限制
 }
 
 func New(storage storage.IStep, workspace string) (*SWasm, error) {
@@ -19,11 +58,255 @@ func New(storage storage.IStep, workspace string) (*SWasm, error) {
 }
 
 func (w *SWasm) Run(ctx context.Context) (exit int64, err error) {
-	//TODO implement me
-	panic("implement me")
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("panic during execution %v", _r)
+			exit = common.CodeSystemErr
+			w.storage.Log().Write(err.Error(), string(debug.Stack()))
+		}
+	}()
+
+	binary, err := w.init()
+	if err != nil {
+		w.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+	if err = w.input(); err != nil {
+		w.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	timeout, err := w.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	// 步骤超时是执行时间的硬性上限, 不含函数调用的循环也会在超时后中断
+	if timeout <= 0 {
+		return common.CodeSystemErr, errors.New("wasm step requires a timeout")
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+	defer cancel()
+
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if w.Memory != "" {
+		var limit uint64
+		limit, err = humanize.ParseBytes(w.Memory)
+		if err != nil {
+			return common.CodeSystemErr, fmt.Errorf("invalid memory limit %s: %w", w.Memory, err)
+		}
+		pages := limit / pageSize
+		if pages == 0 {
+			pages = 1
+		}
+		config = config.WithMemoryLimitPages(uint32(min(pages, 65536)))
+	}
+	if w.Fuel > 0 {
+		ctx = experimental.WithFunctionListenerFactory(ctx, &sFuel{remaining: int64(w.Fuel)})
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	defer func() {
+		_ = runtime.Close(context.Background())
+	}()
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		w.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	stdout, stderr := w.output(), w.output()
+	defer func() {
+		stdout.Flush()
+		stderr.Flush()
+	}()
+	modConfig := wazero.NewModuleConfig().
+		WithName(w.storage.Name()).
+		WithArgs(append(append([]string{w.storage.Name()}, w.Args...), w.args...)...).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		// 工作目录作为唯一的文件系统挂载到根目录
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(w.workspace, "/")).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	if w.stdin != nil {
+		modConfig = modConfig.WithStdin(w.stdin)
+	}
+	for name, value := range w.envs() {
+		modConfig = modConfig.WithEnv(name, value)
+	}
+
+	mod, err := runtime.InstantiateModule(ctx, compiled, modConfig)
+	if mod != nil {
+		_ = mod.Close(context.Background())
+	}
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), common.ErrTimeOut) {
+			return common.CodeTimeout, common.ErrTimeOut
+		}
+		return common.CodeKilled, common.ErrManual
+	}
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			return int64(exitErr.ExitCode()), nil
+		}
+		w.storage.Log().Write(err.Error())
+		if errors.Is(err, errFuelExhausted) {
+			return common.CodeFailed, errFuelExhausted
+		}
+		return common.CodeFailed, err
+	}
+	return common.CodeSuccess, nil
+}
+
+func (w *SWasm) init() ([]byte, error) {
+	content, err := w.storage.Content()
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimSpace(content)
+	// 内容本身就是base64编码的wasm二进制
+	if binary, _err := base64.StdEncoding.DecodeString(content); _err == nil && w.isWasm(binary) {
+		return binary, nil
+	}
+	if err = json.Unmarshal([]byte(content), w); err != nil {
+		if err = yaml.Unmarshal([]byte(content), w); err != nil {
+			return nil, err
+		}
+	}
+	var binary []byte
+	switch {
+	case w.Binary != "":
+		binary, err = base64.StdEncoding.DecodeString(strings.TrimSpace(w.Binary))
+		if err != nil {
+			return nil, fmt.Errorf("invalid binary: %w", err)
+		}
+	case w.Module != "":
+		binary, err = os.ReadFile(filepath.Join(w.workspace, utils.PathEscape(w.Module)))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("module or binary is required")
+	}
+	if !w.isWasm(binary) {
+		return nil, errors.New("invalid wasm module")
+	}
+	return binary, nil
+}
+
+// input 工作目录, 参数及标准输入, 步骤参数追加在内容中的args之后
+func (w *SWasm) input() error {
+	input, err := w.storage.Input()
+	if err != nil {
+		return err
+	}
+	roots := volume.Roots(w.storage.TaskName())
+	dir, err := common.Workdir(w.workspace, input.Workdir, roots...)
+	if err != nil {
+		return err
+	}
+	// 工作空间挂载在根目录, 模块内的工作目录为挂载点下的相对路径
+	rel, err := filepath.Rel(w.workspace, dir)
+	if err != nil {
+		return err
+	}
+	w.workdir = path.Join("/", filepath.ToSlash(rel))
+	w.args = input.Args
+	w.stdin, err = common.Stdin(w.workspace, input.Stdin, input.StdinFile, roots...)
+	return err
+}
+
+func (w *SWasm) isWasm(binary []byte) bool {
+	return bytes.HasPrefix(binary, []byte("\x00asm"))
+}
+
+func (w *SWasm) envs() map[string]string {
+	var envs = make(map[string]string)
+	for _, env := range w.storage.GlobalEnv().List() {
+		envs[env.Name] = env.Value
+	}
+	for _, env := range w.storage.Env().List() {
+		envs[env.Name] = env.Value
+	}
+	envs["TASK_NAME"] = w.storage.TaskName()
+	envs["TASK_STEP_NAME"] = w.storage.Name()
+	// 模块内看到的工作目录为挂载点
+	envs["TASK_WORKSPACE"] = "/"
+	// wasip1的Go程序以PWD作为初始工作目录
+	envs["PWD"] = w.workdir
+	envs["TASK_WORKDIR"] = w.workdir
+	return envs
 }
 
 func (w *SWasm) Clear() error {
-	//TODO implement me
-	panic("implement me")
+	if w.stdin != nil {
+		return w.stdin.Close()
+	}
+	return nil
 }
+
+func (w *SWasm) output() *sOutput {
+	return &sOutput{
+		storage: w.storage,
+	}
+}
+
+// sOutput 按行写入步骤日志
+type sOutput struct {
+	storage storage.IStep
+	mu      sync.Mutex
+	buf     []byte
+}
+
+func (s *sOutput) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			break
+		}
+		s.write(s.buf[:i])
+		s.buf = s.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (s *sOutput) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(s.buf)
+	s.buf = nil
+}
+
+func (s *sOutput) write(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	s.storage.Log().Write(string(line))
+}
+
+// sFuel 以函数调用次数作为燃料, 耗尽后中断执行, 只是调用预算, 不能限制CPU时间
+type sFuel struct {
+	remaining int64
+}
+
+func (f *sFuel) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return f
+}
+
+func (f *sFuel) Before(context.Context, api.Module, api.FunctionDefinition, []uint64, experimental.StackIterator) {
+	if atomic.AddInt64(&f.remaining, -1) < 0 {
+		panic(errFuelExhausted)
+	}
+}
+
+func (f *sFuel) After(context.Context, api.Module, api.FunctionDefinition, []uint64) {}
+
+func (f *sFuel) Abort(context.Context, api.Module, api.FunctionDefinition, error) {}
@@ -0,0 +1,259 @@
+package yaegi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/worker/runner/yaegi/libs"
+	"github.com/xmapst/AutoExecFlow/pkg/wildcard"
+)
+
+const goroutineLabel = "aef_yaegi"
+
+var (
+	errGoroutineLimit = errors.New("goroutine limit exceeded")
+	errOutputLimit    = errors.New("output size limit exceeded")
+
+	goroutineHeader = regexp.MustCompile(`^(\d+) @`)
+)
+
+// sPolicy 脚本可导入的包及资源限制
+//
+// 允许列表分为服务端和步骤两层, 包需同时满足;
+// 禁止列表合并, 可以是包路径或 包路径.符号, 支持通配符*
+type sPolicy struct {
+	allow         [][]string
+	deny          []string
+	maxGoroutines int
+	maxOutput     int64
+}
+
+// newPolicy 服务端配置与步骤环境变量合并, 步骤只能收紧限制
+func (y *SYaegi) newPolicy() (*sPolicy, error) {
+	p := &sPolicy{
+		deny:          config.App.YaegiDeny,
+		maxGoroutines: config.App.YaegiMaxGoroutines,
+	}
+	if len(config.App.YaegiAllow) > 0 {
+		p.allow = append(p.allow, config.App.YaegiAllow)
+	}
+	maxOutput, err := parseBytes(config.App.YaegiMaxOutput)
+	if err != nil {
+		return nil, err
+	}
+	p.maxOutput = maxOutput
+
+	envs := y.envs()
+	if v := splitList(envs["YAEGI_ALLOW"]); len(v) > 0 {
+		p.allow = append(p.allow, v)
+	}
+	p.deny = append(p.deny, splitList(envs["YAEGI_DENY"])...)
+	if v := envs["YAEGI_MAX_GOROUTINES"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid YAEGI_MAX_GOROUTINES %s", v)
+		}
+		p.maxGoroutines = minLimit(p.maxGoroutines, n)
+	}
+	if v := envs["YAEGI_MAX_OUTPUT"]; v != "" {
+		n, err := parseBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		p.maxOutput = minLimit(p.maxOutput, n)
+	}
+	return p, nil
+}
+
+// allowed 包是否允许导入
+func (p *sPolicy) allowed(path string) bool {
+	if path == "aef" {
+		return true
+	}
+	for _, patterns := range p.allow {
+		if !match(patterns, path) {
+			return false
+		}
+	}
+	return !match(p.deny, path)
+}
+
+// denied 符号是否被禁止
+func (p *sPolicy) denied(path, name string) bool {
+	return match(p.deny, path+"."+name)
+}
+
+// symbols 按策略过滤后的符号表
+func (p *sPolicy) symbols() map[string]map[string]reflect.Value {
+	var res = make(map[string]map[string]reflect.Value)
+	for key, symbols := range libs.Symbols {
+		// key 格式为 导入路径/包名
+		path := key
+		if i := strings.LastIndex(key, "/"); i > 0 {
+			path = key[:i]
+		}
+		if !p.allowed(path) {
+			continue
+		}
+		var filtered = make(map[string]reflect.Value, len(symbols))
+		for name, value := range symbols {
+			if p.denied(path, name) {
+				continue
+			}
+			filtered[name] = value
+		}
+		res[key] = filtered
+	}
+	return res
+}
+
+// check 解析脚本, 返回所有违反策略的导入及符号引用
+func (p *sPolicy) check(content string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", content, parser.SkipObjectResolution)
+	if err != nil && !strings.HasPrefix(strings.TrimSpace(content), "package") {
+		// 脚本允许省略package声明
+		file, err = parser.ParseFile(fset, "main.go", "package main;"+content, parser.SkipObjectResolution)
+	}
+	if err != nil {
+		// 无法解析的脚本无法校验, 不交给解释器执行
+		return err
+	}
+
+	var violations []string
+	var imports = make(map[string]string)
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if !p.allowed(path) {
+			violations = append(violations, fmt.Sprintf("%s: import %q is not allowed by policy", fset.Position(imp.Pos()), path))
+			continue
+		}
+		name := packageName(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if path, ok := imports[ident.Name]; ok && p.denied(path, sel.Sel.Name) {
+			violations = append(violations, fmt.Sprintf("%s: %s.%s is not allowed by policy", fset.Position(sel.Pos()), path, sel.Sel.Name))
+		}
+		return true
+	})
+	if len(violations) > 0 {
+		return errors.New(strings.Join(violations, "\n"))
+	}
+	return nil
+}
+
+// watchGoroutines 定时统计带标签的goroutine数量, 超出上限后终止脚本
+func (y *SYaegi) watchGoroutines(ctx context.Context) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	needle := fmt.Sprintf("%q:%q", goroutineLabel, y.label())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 监控自身不计入
+			if countGoroutines(needle)-1 > y.policy.maxGoroutines {
+				y.cancel(fmt.Errorf("%w, max %d", errGoroutineLimit, y.policy.maxGoroutines))
+				return
+			}
+		}
+	}
+}
+
+func countGoroutines(needle string) (count int) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return 0
+	}
+	var current int
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := goroutineHeader.FindStringSubmatch(line); m != nil {
+			current, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if strings.HasPrefix(line, "# labels:") && strings.Contains(line, needle) {
+			count += current
+		}
+	}
+	return
+}
+
+// packageName 导入路径对应的包名
+func packageName(path string) string {
+	for key := range libs.Symbols {
+		if strings.HasPrefix(key, path+"/") && !strings.Contains(key[len(path)+1:], "/") {
+			return key[len(path)+1:]
+		}
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func match(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if wildcard.Match(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) (res []string) {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return
+}
+
+func parseBytes(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid output size %s", s)
+	}
+	return int64(n), nil
+}
+
+// minLimit 0为不限制
+func minLimit[T int | int64](a, b T) T {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	return min(a, b)
+}
@@ -0,0 +1,85 @@
+package yaegi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/traefik/yaegi/interp"
+
+	"github.com/xmapst/AutoExecFlow/internal/storage/models"
+)
+
+// sdkPath 脚本中通过 import "aef" 使用
+const sdkPath = "aef/aef"
+
+// sExit 由aef.Exit触发, 作为脚本上下文的取消原因, 在Run中恢复为退出码
+type sExit struct {
+	code int64
+}
+
+func (e *sExit) Error() string {
+	return fmt.Sprintf("exit with code %d", e.code)
+}
+
+// sdk 绑定当前步骤的辅助函数
+func (y *SYaegi) sdk() interp.Exports {
+	return interp.Exports{
+		sdkPath: {
+			"Log": reflect.ValueOf(func(a ...any) {
+				y.log(fmt.Sprint(a...))
+			}),
+			"Logf": reflect.ValueOf(func(format string, a ...any) {
+				y.log(fmt.Sprintf(format, a...))
+			}),
+			"Context": reflect.ValueOf(func() context.Context { return y.ctx }),
+			"Params":  reflect.ValueOf(func() gjson.Result { return y.params }),
+			"Env": reflect.ValueOf(func(name string) string {
+				return y.envs()[name]
+			}),
+			"Envs":      reflect.ValueOf(y.envs),
+			"Workspace": reflect.ValueOf(func() string { return y.workspace }),
+			"Workdir":   reflect.ValueOf(func() string { return y.workdir }),
+			"Args":      reflect.ValueOf(func() []string { return y.args }),
+			"TaskName":  reflect.ValueOf(y.storage.TaskName),
+			"StepName":  reflect.ValueOf(y.storage.Name),
+			"SetOutput": reflect.ValueOf(func(name string, value any) error {
+				return y.storage.Output().Insert(&models.SEnv{
+					Name:  name,
+					Value: fmt.Sprint(value),
+				})
+			}),
+			"Stdin": reflect.ValueOf(func() io.Reader {
+				if y.stdin == nil {
+					return strings.NewReader("")
+				}
+				return y.stdin
+			}),
+			// Exit 立即结束脚本并以指定退出码返回, 不使用panic以免被脚本recover或输出panic日志
+			"Exit": reflect.ValueOf(func(code int) {
+				y.cancel(&sExit{code: int64(code)})
+				runtime.Goexit()
+			}),
+		},
+	}
+}
+
+// envs 任务环境变量, 步骤环境变量优先
+func (y *SYaegi) envs() map[string]string {
+	var envs = make(map[string]string)
+	for _, env := range y.storage.GlobalEnv().List() {
+		envs[env.Name] = env.Value
+	}
+	for _, env := range y.storage.Env().List() {
+		envs[env.Name] = env.Value
+	}
+	envs["TASK_NAME"] = y.storage.TaskName()
+	envs["TASK_STEP_NAME"] = y.storage.Name()
+	envs["TASK_WORKSPACE"] = y.workspace
+	envs["TASK_WORKDIR"] = y.workdir
+	return envs
+}
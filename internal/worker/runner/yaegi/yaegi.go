@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"reflect"
 	"runtime/debug"
+	"runtime/pprof"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
@@ -14,13 +18,27 @@ import (
 
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/worker/common"
-	"github.com/xmapst/AutoExecFlow/internal/worker/runner/yaegi/libs"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 )
 
+// entrypoint 在脚本命名空间中调用入口函数, ctx及参数由sdk提供
+const entrypoint = `EvalCall(__aef.Context(), __aef.Params())`
+
 type SYaegi struct {
 	interp    *interp.Interpreter
 	storage   storage.IStep
 	workspace string
+	workdir   string
+	args      []string
+	stdin     io.ReadCloser
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	params gjson.Result
+	policy *sPolicy
+
+	outputSize int64
+	outputOnce sync.Once
 }
 
 func New(storage storage.IStep, workspace string) (*SYaegi, error) {
@@ -44,27 +62,109 @@ func (y *SYaegi) Run(ctx context.Context) (exit int64, err error) {
 		}
 	}()
 
-	params, err := y.getParams()
+	timeout, err := y.storage.Timeout()
+	if err != nil {
+		return common.CodeSystemErr, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, common.ErrTimeOut)
+		defer cancel()
+	}
+	y.ctx, y.cancel = context.WithCancelCause(ctx)
+	defer y.cancel(nil)
+
+	y.policy, err = y.newPolicy()
+	if err != nil {
+		y.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	if err = y.input(); err != nil {
+		y.storage.Log().Write(err.Error())
+		return common.CodeSystemErr, err
+	}
+
+	y.params, err = y.getParams()
 	if err != nil {
 		return common.CodeFailed, err
 	}
 
-	if err = y.createVM(ctx); err != nil {
+	if err = y.createVM(y.ctx); err != nil {
+		y.storage.Log().Write(err.Error())
 		return common.CodeSystemErr, err
 	}
 
-	evalFnval, err := y.interp.EvalWithContext(ctx, "EvalCall")
+	evalFnval, err := y.interp.EvalWithContext(y.ctx, "EvalCall")
 	if err != nil {
 		return common.CodeFailed, err
 	}
-	evalFn, ok := evalFnval.Interface().(func(ctx context.Context, params gjson.Result))
-	if !ok {
+	switch evalFnval.Interface().(type) {
+	case func(ctx context.Context, params gjson.Result):
+	case func(ctx context.Context, params gjson.Result) error:
+	default:
 		return common.CodeFailed, errors.New("not found EvalCall")
 	}
-	evalFn(ctx, params)
+
+	var res reflect.Value
+	// 脚本中启动的goroutine继承标签, 用于统计goroutine数量
+	pprof.Do(y.ctx, pprof.Labels(goroutineLabel, y.label()), func(ctx context.Context) {
+		if y.policy.maxGoroutines > 0 {
+			go y.watchGoroutines(ctx)
+		}
+		res, err = y.interp.EvalWithContext(ctx, entrypoint)
+	})
+	if y.ctx.Err() != nil {
+		return y.exitCode()
+	}
+	if err != nil {
+		y.storage.Log().Write(err.Error())
+		return common.CodeFailed, err
+	}
+	if res.IsValid() && res.CanInterface() {
+		if _err, ok := res.Interface().(error); ok && _err != nil {
+			y.storage.Log().Write(_err.Error())
+			return common.CodeFailed, _err
+		}
+	}
 	return common.CodeSuccess, nil
 }
 
+func (y *SYaegi) exitCode() (int64, error) {
+	cause := context.Cause(y.ctx)
+	var exit *sExit
+	switch {
+	case errors.As(cause, &exit):
+		return exit.code, nil
+	case errors.Is(cause, common.ErrTimeOut):
+		return common.CodeTimeout, common.ErrTimeOut
+	case errors.Is(cause, errGoroutineLimit), errors.Is(cause, errOutputLimit):
+		y.storage.Log().Write(cause.Error())
+		return common.CodeFailed, cause
+	default:
+		return common.CodeKilled, common.ErrManual
+	}
+}
+
+func (y *SYaegi) label() string {
+	return fmt.Sprintf("%s/%s", y.storage.TaskName(), y.storage.Name())
+}
+
+// input 工作目录, 参数及标准输入, 脚本在服务进程内执行, 工作目录仅通过sdk及环境变量提供
+func (y *SYaegi) input() error {
+	input, err := y.storage.Input()
+	if err != nil {
+		return err
+	}
+	roots := volume.Roots(y.storage.TaskName())
+	if y.workdir, err = common.Workdir(y.workspace, input.Workdir, roots...); err != nil {
+		return err
+	}
+	y.args = input.Args
+	y.stdin, err = common.Stdin(y.workspace, input.Stdin, input.StdinFile, roots...)
+	return err
+}
+
 func (y *SYaegi) getParams() (gjson.Result, error) {
 	var rawJSON string
 	var err error
@@ -86,47 +186,93 @@ func (y *SYaegi) getParams() (gjson.Result, error) {
 }
 
 func (y *SYaegi) createVM(ctx context.Context) (err error) {
+	content, err := y.storage.Content()
+	if err != nil {
+		return err
+	}
+	// 编译前按策略检查导入的包及符号
+	if err = y.policy.check(content); err != nil {
+		return err
+	}
+
+	// 未设置stdin或stdin_file时不继承服务进程的标准输入
+	var stdin io.Reader = strings.NewReader("")
+	if y.stdin != nil {
+		stdin = y.stdin
+	}
+	var stdout, stderr io.Writer = y.output(), y.output()
 	y.interp = interp.New(interp.Options{
 		Env: []string{
 			fmt.Sprintf("WORKSPACE=%s", y.workspace),
+			fmt.Sprintf("WORKDIR=%s", y.workdir),
 		},
-		Stdout: y.output(),
-		Stderr: y.output(),
+		// 不继承服务进程的参数
+		Args:   append([]string{y.storage.Name()}, y.args...),
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
 	})
-	if err = y.interp.Use(libs.Symbols); err != nil {
+	symbols := y.policy.symbols()
+	// 解释器只为*os.File设置os.Stdin/Stdout/Stderr, 此处替换为步骤的输入输出
+	if pkg, ok := symbols["os/os"]; ok {
+		for name, value := range map[string]reflect.Value{
+			"Stdin":  reflect.ValueOf(&stdin).Elem(),
+			"Stdout": reflect.ValueOf(&stdout).Elem(),
+			"Stderr": reflect.ValueOf(&stderr).Elem(),
+		} {
+			if !y.policy.denied("os", name) {
+				pkg[name] = value
+			}
+		}
+	}
+	if err = y.interp.Use(symbols); err != nil {
 		return err
 	}
-	y.interp.ImportUsed()
-
-	content, err := y.storage.Content()
-	if err != nil {
+	if err = y.interp.Use(y.sdk()); err != nil {
 		return err
 	}
+	y.interp.ImportUsed()
 
 	_, err = y.interp.EvalWithContext(ctx, content)
 	if err != nil {
 		return err
 	}
-
+	_, err = y.interp.EvalWithContext(ctx, `import __aef "aef"`)
 	return
 }
 
 func (y *SYaegi) Clear() error {
+	if y.stdin != nil {
+		return y.stdin.Close()
+	}
 	return nil
 }
 
+// log 写入步骤日志, 超出输出上限后丢弃并终止脚本
+func (y *SYaegi) log(content string) {
+	if y.policy != nil && y.policy.maxOutput > 0 {
+		if atomic.AddInt64(&y.outputSize, int64(len(content))) > y.policy.maxOutput {
+			y.outputOnce.Do(func() {
+				y.cancel(errOutputLimit)
+			})
+			return
+		}
+	}
+	y.storage.Log().Write(content)
+}
+
 type sYeagiOutput struct {
-	storage storage.IStep
+	y *SYaegi
 }
 
 func (s *sYeagiOutput) Write(p []byte) (n int, err error) {
 	n = len(p)
-	s.storage.Log().Write(string(p))
+	s.y.log(string(p))
 	return
 }
 
 func (y *SYaegi) output() io.Writer {
 	return &sYeagiOutput{
-		storage: y.storage,
+		y: y,
 	}
 }
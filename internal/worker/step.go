@@ -68,6 +68,14 @@ func (s *sStep) Execute(ctx context.Context, input map[string]any) (map[string]a
 		return nil, err
 	}
 
+	// 清理上次执行导出的变量及输出
+	if err = s.stg.Export().RemoveAll(); err != nil {
+		logx.Warnln(s.taskName, s.stepName, err)
+	}
+	if err = s.stg.Output().RemoveAll(); err != nil {
+		logx.Warnln(s.taskName, s.stepName, err)
+	}
+
 	// proc step
 	var res = new(models.SStepUpdate)
 	defer func() {
@@ -116,9 +124,26 @@ func (s *sStep) Execute(ctx context.Context, input map[string]any) (map[string]a
 			err = nil
 		}()
 	}
+	// 引用的密钥已删除或无法解密时不以空值执行
+	if err = s.stg.CheckSecrets(); err != nil {
+		logx.Errorln(s.taskName, s.stepName, err)
+		res.State = models.Pointer(models.StateFailed)
+		res.Message = err.Error()
+		res.Code = models.Pointer(common.CodeSystemErr)
+		return nil, err
+	}
+	var criteria *sCriteria
+	criteria, err = newCriteria(s.stg)
+	if err != nil {
+		logx.Errorln(s.taskName, s.stepName, err)
+		res.State = models.Pointer(models.StateFailed)
+		res.Message = err.Error()
+		res.Code = models.Pointer(common.CodeSystemErr)
+		return nil, err
+	}
 	var _runner runner.IRunner
 	_runner, err = runner.New(
-		s.stg,
+		criteria.wrap(s.stg),
 		s.workspace,
 		s.scriptDir,
 	)
@@ -141,17 +166,35 @@ func (s *sStep) Execute(ctx context.Context, input map[string]any) (map[string]a
 	_ctx, cancel := utils.MergerContext(ctx, s.lcCtx)
 	defer cancel()
 	code, err = _runner.Run(_ctx)
+	// 返回错误但退出码为0时视为失败
+	if err != nil && code == common.CodeSuccess {
+		code = common.CodeFailed
+	}
 	res.Code = models.Pointer(code)
-	res.State = models.Pointer(models.StateStopped)
-	if err != nil {
+	// 超时, 强杀, OOM及系统错误不参与判定, 其余结果均由判定规则决定
+	if err != nil && aborted(code) {
 		res.State = models.Pointer(models.StateFailed)
 		res.Message = err.Error()
+		res.Decision = decisionError
 		return nil, err
 	}
-	if code != 0 {
-		res.State = models.Pointer(models.StateFailed)
-		res.Message = fmt.Sprintf("execution failed with code: %d", code)
-		return nil, errors.New(res.Message)
+	var state models.State
+	state, res.Decision, res.Message = criteria.decide(code)
+	res.State = models.Pointer(state)
+	switch state {
+	case models.StateFailed:
+		if err == nil || res.Decision == decisionFailOnPattern {
+			err = errors.New(res.Message)
+		}
+		res.Message = err.Error()
+		return nil, err
+	case models.StateSkipped:
+		return nil, nil
+	}
+	if r, ok := _runner.(runner.IResult); ok {
+		if msg := r.Result(); msg != "" {
+			res.Message = msg
+		}
 	}
 	return nil, nil
 }
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -16,6 +17,7 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/utils"
 	"github.com/xmapst/AutoExecFlow/internal/worker/common"
 	"github.com/xmapst/AutoExecFlow/internal/worker/event"
+	"github.com/xmapst/AutoExecFlow/internal/worker/volume"
 	"github.com/xmapst/AutoExecFlow/pkg/dag"
 )
 
@@ -35,6 +37,13 @@ type sTask struct {
 	scriptDir string
 	dagTasks  map[string]dag.Task
 	state     int32 // 0: 正常, 1: 挂起
+	// 已挂载的流水线持久化卷, 任务结束时卸载并释放锁
+	mounts []*sMount
+}
+
+type sMount struct {
+	target  string
+	release func()
 }
 
 func newTask(taskName string) (*sTask, error) {
@@ -170,6 +179,12 @@ func (t *sTask) Execute() (err error) {
 	}
 	defer cancel()
 
+	defer t.unmountVolumes()
+	if err = t.mountVolumes(ctx); err != nil {
+		logx.Errorln(t.taskName, err)
+		return
+	}
+
 	_dag, err := dag.New(t.dagTasks)
 	if err != nil {
 		logx.Errorln(t.taskName, err)
@@ -246,6 +261,51 @@ func (t *sTask) initDir() error {
 	return nil
 }
 
+// mountVolumes 将所属流水线声明的持久化卷链接到工作目录, 并独占锁定直到任务结束
+func (t *sTask) mountVolumes(ctx context.Context) error {
+	pipeline, err := t.stg.Pipeline()
+	if err != nil || pipeline == "" {
+		return err
+	}
+	for _, v := range storage.Pipeline(pipeline).Volume().List() {
+		release, err := volume.Acquire(ctx, pipeline, v.Name)
+		if err != nil {
+			return fmt.Errorf("acquire volume %s: %w", v.Name, err)
+		}
+		mount := &sMount{
+			target:  filepath.Join(t.workspace, utils.PathEscape(v.Path)),
+			release: release,
+		}
+		t.mounts = append(t.mounts, mount)
+		source := volume.Dir(pipeline, v.Name)
+		if err = utils.EnsureDirExist(source); err != nil {
+			return err
+		}
+		if err = utils.EnsureDirExist(filepath.Dir(mount.target)); err != nil {
+			return err
+		}
+		if err = os.RemoveAll(mount.target); err != nil {
+			return err
+		}
+		if err = os.Symlink(source, mount.target); err != nil {
+			return fmt.Errorf("mount volume %s: %w", v.Name, err)
+		}
+		logx.Infoln(t.taskName, "mount volume", v.Name, source, mount.target)
+	}
+	return nil
+}
+
+func (t *sTask) unmountVolumes() {
+	for _, mount := range t.mounts {
+		// 只删除链接本身, 避免工作目录清理时误删卷内容
+		if err := os.Remove(mount.target); err != nil && !os.IsNotExist(err) {
+			logx.Warnln(t.taskName, err)
+		}
+		mount.release()
+	}
+	t.mounts = nil
+}
+
 func (t *sTask) clearDir() {
 	if err := os.RemoveAll(t.scriptDir); err != nil {
 		logx.Errorln(t.taskName, err)
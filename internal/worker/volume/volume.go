@@ -0,0 +1,236 @@
+package volume
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/xmapst/logx"
+
+	"github.com/xmapst/AutoExecFlow/internal/config"
+	"github.com/xmapst/AutoExecFlow/internal/storage"
+)
+
+var (
+	ErrInUse = errors.New("volume is in use")
+
+	locks sync.Map
+
+	// 配置redis时卷锁在节点间共享
+	client  redis.UniversalClient
+	mutexes *redsync.Redsync
+)
+
+const (
+	lockPrefix = "aef_volume_"
+	lockExpiry = 8 * time.Second
+	lockRetry  = time.Second
+)
+
+// Init 配置redis时使用分布式锁, 共享ROOT_DIR或数据库的多个节点不会同时使用同一个卷, 否则锁仅在当前进程内有效
+func Init(redisUrl string) error {
+	if redisUrl == "" {
+		return nil
+	}
+	opt, err := redis.ParseURL(redisUrl)
+	if err != nil {
+		return err
+	}
+	client = redis.NewClient(opt)
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		return err
+	}
+	mutexes = redsync.New(goredis.NewPool(client))
+	return nil
+}
+
+// Dir 卷在ROOT_DIR下的实际目录
+func Dir(pipeline, name string) string {
+	return filepath.Join(config.App.VolumeDir(), pipeline, name)
+}
+
+// Roots 任务所属流水线的卷目录, 卷以符号链接挂载到工作空间, 步骤的工作目录及输入文件可位于其中
+func Roots(taskName string) (res []string) {
+	pipeline, err := storage.Task(taskName).Pipeline()
+	if err != nil || pipeline == "" {
+		return nil
+	}
+	for _, v := range storage.Pipeline(pipeline).Volume().List() {
+		res = append(res, Dir(pipeline, v.Name))
+	}
+	return res
+}
+
+func lockFor(pipeline, name string) chan struct{} {
+	value, _ := locks.LoadOrStore(Dir(pipeline, name), make(chan struct{}, 1))
+	return value.(chan struct{})
+}
+
+// sLock 进程内锁及可选的分布式锁, 分布式锁在持有期间定期续期
+type sLock struct {
+	local  chan struct{}
+	mutex  *redsync.Mutex
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func newLock(pipeline, name string) *sLock {
+	l := &sLock{local: lockFor(pipeline, name)}
+	if mutexes != nil {
+		l.mutex = mutexes.NewMutex(lockPrefix+pipeline+"/"+name, redsync.WithExpiry(lockExpiry), redsync.WithTries(1))
+	}
+	return l
+}
+
+// lock wait为false时只尝试一次
+func (l *sLock) lock(ctx context.Context, wait bool) error {
+	if wait {
+		select {
+		case l.local <- struct{}{}:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	} else {
+		select {
+		case l.local <- struct{}{}:
+		default:
+			return ErrInUse
+		}
+	}
+	if l.mutex == nil {
+		return nil
+	}
+	for {
+		err := l.mutex.TryLockContext(ctx)
+		if err == nil {
+			break
+		}
+		if !wait || !taken(err) {
+			<-l.local
+			if taken(err) {
+				return ErrInUse
+			}
+			return err
+		}
+		select {
+		case <-time.After(lockRetry):
+		case <-ctx.Done():
+			<-l.local
+			return context.Cause(ctx)
+		}
+	}
+	var keepCtx context.Context
+	keepCtx, l.cancel = context.WithCancel(context.Background())
+	go l.keepAlive(keepCtx)
+	return nil
+}
+
+func (l *sLock) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(lockExpiry / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.mutex.ExtendContext(ctx); err != nil && ctx.Err() == nil {
+				logx.Warnln("extend volume lock", l.mutex.Name(), err)
+			}
+		}
+	}
+}
+
+func (l *sLock) release() {
+	l.once.Do(func() {
+		if l.mutex != nil {
+			l.cancel()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			if _, err := l.mutex.UnlockContext(ctx); err != nil {
+				logx.Warnln("unlock volume", l.mutex.Name(), err)
+			}
+			cancel()
+		}
+		<-l.local
+	})
+}
+
+// taken 锁已被其他节点持有
+func taken(err error) bool {
+	var errTaken *redsync.ErrTaken
+	var errNodeTaken *redsync.ErrNodeTaken
+	return errors.Is(err, redsync.ErrFailed) || errors.As(err, &errTaken) || errors.As(err, &errNodeTaken)
+}
+
+// Acquire 独占锁定卷, 直到ctx结束, 返回释放函数
+func Acquire(ctx context.Context, pipeline, name string) (func(), error) {
+	l := newLock(pipeline, name)
+	if err := l.lock(ctx, true); err != nil {
+		return nil, err
+	}
+	return l.release, nil
+}
+
+// TryAcquire 尝试锁定卷, 卷被占用时立即返回
+func TryAcquire(pipeline, name string) (func(), bool) {
+	l := newLock(pipeline, name)
+	if err := l.lock(context.Background(), false); err != nil {
+		if !errors.Is(err, ErrInUse) {
+			logx.Warnln("lock volume", pipeline, name, err)
+		}
+		return nil, false
+	}
+	return l.release, true
+}
+
+// IsLocked 卷是否被构建占用
+func IsLocked(pipeline, name string) bool {
+	if len(lockFor(pipeline, name)) > 0 {
+		return true
+	}
+	if client == nil {
+		return false
+	}
+	n, err := client.Exists(context.Background(), lockPrefix+pipeline+"/"+name).Result()
+	return err == nil && n > 0
+}
+
+// Usage 统计卷占用空间及最后修改时间(纳秒时间戳), 卷目录不存在时均为0
+func Usage(pipeline, name string) (size int64, modTime int64, err error) {
+	err = filepath.WalkDir(Dir(pipeline, name), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if t := info.ModTime().UnixNano(); t > modTime {
+			modTime = t
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// Purge 清空卷内容, 卷被占用时返回ErrInUse
+func Purge(pipeline, name string) error {
+	release, ok := TryAcquire(pipeline, name)
+	if !ok {
+		return ErrInUse
+	}
+	defer release()
+	return os.RemoveAll(Dir(pipeline, name))
+}
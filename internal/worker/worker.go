@@ -14,6 +14,7 @@ import (
 	"github.com/xmapst/AutoExecFlow/internal/storage"
 	"github.com/xmapst/AutoExecFlow/internal/storage/models"
 	"github.com/xmapst/AutoExecFlow/internal/utils"
+	"github.com/xmapst/AutoExecFlow/internal/worker/common"
 	"github.com/xmapst/AutoExecFlow/internal/worker/event"
 	"github.com/xmapst/AutoExecFlow/pkg/tunny"
 )
@@ -170,6 +171,8 @@ func managerStep(taskName, stepName, action, duration string) error {
 				Message:  "has been paused",
 			})
 		}
+	case "approve":
+		return common.Approve(taskName, stepName)
 	case "resume":
 		if atomic.CompareAndSwapInt32(&step.state, 1, 0) {
 			if step.ctrlCancel != nil {
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// SClient 服务端连接插件的客户端
+type SClient struct {
+	conn *grpc.ClientConn
+}
+
+// Dial 连接插件监听的unix socket, 连接在首次调用时建立
+func Dial(socket string) (*SClient, error) {
+	conn, err := grpc.NewClient("passthrough:///"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(Codec)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SClient{conn: conn}, nil
+}
+
+func (c *SClient) Info(ctx context.Context) (*SInfo, error) {
+	var res = new(SInfo)
+	if err := c.conn.Invoke(ctx, method("Info"), new(SEmpty), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Run 执行步骤, 通过返回的IEvents读取事件直到exit事件或出错
+func (c *SClient) Run(ctx context.Context, req *SRunRequest) (IEvents, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], method("Run"))
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &sEvents{stream}, nil
+}
+
+func (c *SClient) Clear(ctx context.Context, req *SClearRequest) error {
+	return c.conn.Invoke(ctx, method("Clear"), req, new(SEmpty))
+}
+
+func (c *SClient) Close() error {
+	return c.conn.Close()
+}
+
+type IEvents interface {
+	Recv() (*SEvent, error)
+}
+
+type sEvents struct {
+	grpc.ClientStream
+}
+
+func (e *sEvents) Recv() (*SEvent, error) {
+	var event = new(SEvent)
+	if err := e.RecvMsg(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
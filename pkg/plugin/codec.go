@@ -0,0 +1,29 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Codec 消息使用json编码, content-type为application/grpc+json, 其他语言的插件无需protoc即可实现
+const Codec = "json"
+
+type sCodec struct {
+}
+
+func (sCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (sCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (sCodec) Name() string {
+	return Codec
+}
+
+func init() {
+	encoding.RegisterCodec(sCodec{})
+}
@@ -0,0 +1,61 @@
+package plugin
+
+const (
+	// ServiceName 插件的grpc服务名
+	ServiceName = "autoexecflow.plugin.v1.Runner"
+	// EnvSocket 插件监听的unix socket路径, 由服务端启动插件时设置
+	EnvSocket = "AEF_PLUGIN_SOCKET"
+)
+
+// SInfo 插件信息, types为插件处理的步骤类型
+type SInfo struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Types       []string `json:"types"`
+}
+
+type SEnv struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SRunRequest 执行步骤的请求
+type SRunRequest struct {
+	Task      string  `json:"task"`
+	Step      string  `json:"step"`
+	Type      string  `json:"type"`
+	Content   string  `json:"content"`
+	Workspace string  `json:"workspace"`
+	Timeout   int64   `json:"timeout,omitempty"` // 超时时间, 毫秒, 0为不限制, 同时作为grpc的deadline
+	Action    string  `json:"action,omitempty"`
+	Rule      string  `json:"rule,omitempty"`
+	Env       []*SEnv `json:"env,omitempty"` // 任务与步骤的环境变量, 步骤变量在后
+}
+
+// SEvent 执行过程中插件返回的事件, 每个事件只设置其中一个字段, exit为最后一个事件
+type SEvent struct {
+	Log     *SLog   `json:"log,omitempty"`
+	Outputs []*SEnv `json:"outputs,omitempty"`
+	Result  string  `json:"result,omitempty"` // 执行结果描述, 如changed/unchanged
+	Exit    *SExit  `json:"exit,omitempty"`
+}
+
+type SLog struct {
+	Content string `json:"content"`
+	Stream  string `json:"stream,omitempty"` // stdout, stderr
+	Level   string `json:"level,omitempty"`  // 为空时由服务端解析
+}
+
+type SExit struct {
+	Code  int64  `json:"code"`
+	Error string `json:"error,omitempty"`
+}
+
+type SClearRequest struct {
+	Task string `json:"task"`
+	Step string `json:"step"`
+}
+
+type SEmpty struct {
+}
@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Serve 插件入口, 在EnvSocket指定的unix socket上提供服务, 收到退出信号或服务端退出后停止
+func Serve(runner IRunner) error {
+	socket := os.Getenv(EnvSocket)
+	if socket == "" {
+		return fmt.Errorf("%s is not set, plugins are started by the server", EnvSocket)
+	}
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socket)
+
+	server := grpc.NewServer()
+	server.RegisterService(&serviceDesc, runner)
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		// 服务端异常退出时插件被其他进程接管, 父进程号改变
+		ppid := os.Getppid()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-signals:
+				server.GracefulStop()
+				return
+			case <-ticker.C:
+				if os.Getppid() != ppid {
+					server.Stop()
+					return
+				}
+			}
+		}
+	}()
+	return server.Serve(listener)
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// IRunner 插件实现的接口
+type IRunner interface {
+	// Info 插件信息
+	Info(ctx context.Context) (*SInfo, error)
+	// Run 执行步骤, 日志与输出通过stream返回, ctx在步骤取消或超时后结束
+	Run(ctx context.Context, req *SRunRequest, stream IStream) (exit int64, err error)
+	// Clear 步骤结束后清理
+	Clear(ctx context.Context, req *SClearRequest) error
+}
+
+// IStream 执行过程中向服务端发送事件
+type IStream interface {
+	Log(content string) error
+	Logf(format string, args ...any) error
+	Output(name, value string) error
+	Result(result string) error
+	Send(event *SEvent) error
+}
+
+type sStream struct {
+	grpc.ServerStream
+}
+
+func (s *sStream) Log(content string) error {
+	return s.Send(&SEvent{Log: &SLog{Content: content}})
+}
+
+func (s *sStream) Logf(format string, args ...any) error {
+	return s.Log(fmt.Sprintf(format, args...))
+}
+
+func (s *sStream) Output(name, value string) error {
+	return s.Send(&SEvent{Outputs: []*SEnv{{Name: name, Value: value}}})
+}
+
+func (s *sStream) Result(result string) error {
+	return s.Send(&SEvent{Result: result})
+}
+
+func (s *sStream) Send(event *SEvent) error {
+	return s.SendMsg(event)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*IRunner)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				if err := dec(new(SEmpty)); err != nil {
+					return nil, err
+				}
+				return srv.(IRunner).Info(ctx)
+			},
+		},
+		{
+			MethodName: "Clear",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				var req = new(SClearRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return new(SEmpty), srv.(IRunner).Clear(ctx, req)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Run",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				var req = new(SRunRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				code, err := srv.(IRunner).Run(stream.Context(), req, &sStream{stream})
+				var exit = &SExit{Code: code}
+				if err != nil {
+					exit.Error = err.Error()
+				}
+				return stream.SendMsg(&SEvent{Exit: exit})
+			},
+		},
+	},
+}
+
+func method(name string) string {
+	return "/" + ServiceName + "/" + name
+}